@@ -0,0 +1,39 @@
+// Package aggs provides builders for elasticsearch aggregations and a typed
+// view over the aggregation results of a search response.
+package aggs
+
+// Aggregation is implemented by every aggregation builder
+type Aggregation interface {
+	// Source returns the aggregation in the form elasticsearch expects it to
+	// be serialized within a request body
+	Source() map[string]interface{}
+}
+
+// Sources converts named aggregations to the value of the "aggs" property
+// of a request body.
+func Sources(aggregations map[string]Aggregation) map[string]interface{} {
+	sources := make(map[string]interface{}, len(aggregations))
+
+	for name, aggregation := range aggregations {
+		sources[name] = aggregation.Source()
+	}
+
+	return sources
+}
+
+// attach sub-aggregations to the source of a bucket aggregation
+func withSubAggregations(source map[string]interface{}, subAggregations map[string]Aggregation) map[string]interface{} {
+	if len(subAggregations) > 0 {
+		source["aggs"] = Sources(subAggregations)
+	}
+
+	return source
+}
+
+func addSubAggregation(subAggregations *map[string]Aggregation, name string, aggregation Aggregation) {
+	if *subAggregations == nil {
+		*subAggregations = map[string]Aggregation{}
+	}
+
+	(*subAggregations)[name] = aggregation
+}
@@ -0,0 +1,114 @@
+package aggs_test
+
+import (
+	"encoding/json"
+	"github.com/b3ntly/elasticsearch/aggs"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func requireSource(t *testing.T, expected string, aggregation aggs.Aggregation) {
+	js, err := json.Marshal(aggregation.Source())
+	require.Nil(t, err)
+	require.JSONEq(t, expected, string(js))
+}
+
+func TestAggregations(t *testing.T) {
+	t.Run("Bucket aggregations nest their sub-aggregations", func(t *testing.T) {
+		requireSource(t, `{
+			"terms": {"field": "user", "size": 5, "order": {"avg_age": "desc"}},
+			"aggs": {"avg_age": {"avg": {"field": "age"}}}
+		}`, aggs.Terms("user").Size(5).Order("avg_age", false).SubAggregation("avg_age", aggs.Avg("age")))
+
+		requireSource(t, `{"date_histogram": {"field": "date", "fixed_interval": "90m", "format": "yyyy-MM-dd"}}`,
+			aggs.DateHistogram("date", "1d").FixedInterval("90m").Format("yyyy-MM-dd"))
+
+		requireSource(t, `{"histogram": {"field": "price", "interval": 50}}`, aggs.Histogram("price", 50))
+
+		requireSource(t, `{"range": {"field": "price", "ranges": [{"to": 100}, {"key": "expensive", "from": 100}]}}`,
+			aggs.Range("price").AddRange(nil, 100).AddKeyedRange("expensive", 100, nil))
+
+		requireSource(t, `{
+			"nested": {"path": "comments"},
+			"aggs": {"authors": {"terms": {"field": "comments.author"}}}
+		}`, aggs.Nested("comments").SubAggregation("authors", aggs.Terms("comments.author")))
+	})
+
+	t.Run("Metric aggregations serialize their parameters", func(t *testing.T) {
+		requireSource(t, `{"sum": {"field": "price", "missing": 0}}`, aggs.Sum("price").Missing(0))
+		requireSource(t, `{"min": {"field": "price"}}`, aggs.Min("price"))
+		requireSource(t, `{"max": {"field": "price"}}`, aggs.Max("price"))
+		requireSource(t, `{"cardinality": {"field": "user", "precision_threshold": 100}}`, aggs.Cardinality("user").PrecisionThreshold(100))
+		requireSource(t, `{"percentiles": {"field": "load", "percents": [95, 99]}}`, aggs.Percentiles("load", 95, 99))
+		requireSource(t, `{"top_hits": {"size": 1, "sort": [{"date": {"order": "desc"}}], "_source": {"includes": ["title"]}}}`,
+			aggs.TopHits(1).Sort("date", false).Includes("title"))
+	})
+}
+
+func TestResults(t *testing.T) {
+	results := aggs.Results{}
+	err := json.Unmarshal([]byte(`{
+		"users": {
+			"doc_count_error_upper_bound": 0,
+			"sum_other_doc_count": 3,
+			"buckets": [
+				{"key": "kimchy", "doc_count": 2, "avg_age": {"value": 30.5}, "latest": {"hits": {"total": 2, "hits": [{"_id": "1", "_source": {"title": "a"}}]}}},
+				{"key": "bob", "doc_count": 1, "avg_age": {"value": null}}
+			]
+		},
+		"prices": {"buckets": {"*-100.0": {"to": 100, "doc_count": 4}, "100.0-*": {"from": 100, "doc_count": 1}}},
+		"comments": {"doc_count": 7, "authors": {"value": 3}},
+		"load": {"values": {"95.0": 0.9, "99.0": 1.2}},
+		"total": {"value": 42}
+	}`), &results)
+	require.Nil(t, err)
+
+	t.Run("Buckets are iterable with their sub-aggregations", func(t *testing.T) {
+		users, ok := results.Buckets("users")
+		require.True(t, ok)
+		require.Equal(t, int64(3), users.SumOtherDocCount)
+		require.Len(t, users.Buckets, 2)
+		require.Equal(t, "kimchy", users.Buckets[0].Key)
+		require.Equal(t, int64(2), users.Buckets[0].DocCount)
+
+		avg, ok := users.Buckets[0].Aggregations.Metric("avg_age")
+		require.True(t, ok)
+		require.Equal(t, 30.5, *avg.Value)
+
+		latest, ok := users.Buckets[0].Aggregations.TopHits("latest")
+		require.True(t, ok)
+		require.Equal(t, "1", latest.Hits[0].ID)
+
+		empty, ok := users.Buckets[1].Aggregations.Metric("avg_age")
+		require.True(t, ok)
+		require.Nil(t, empty.Value)
+	})
+
+	t.Run("Keyed buckets are ordered by key", func(t *testing.T) {
+		prices, ok := results.Buckets("prices")
+		require.True(t, ok)
+		require.Len(t, prices.Buckets, 2)
+		require.Equal(t, "*-100.0", prices.Buckets[0].Key)
+		require.Equal(t, 100.0, *prices.Buckets[0].To)
+		require.Equal(t, 100.0, *prices.Buckets[1].From)
+	})
+
+	t.Run("Single bucket and metric results are decoded", func(t *testing.T) {
+		comments, ok := results.Single("comments")
+		require.True(t, ok)
+		require.Equal(t, int64(7), comments.DocCount)
+
+		authors, ok := comments.Aggregations.Metric("authors")
+		require.True(t, ok)
+		require.Equal(t, 3.0, *authors.Value)
+
+		load, ok := results.Percentiles("load")
+		require.True(t, ok)
+		value, ok := load.Value("99.0")
+		require.True(t, ok)
+		require.Equal(t, 1.2, value)
+
+		_, ok = results.Metric("missing")
+		require.False(t, ok)
+	})
+}
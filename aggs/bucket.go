@@ -0,0 +1,202 @@
+package aggs
+
+type (
+	// Groups documents by the unique values of a field
+	TermsAggregation struct {
+		params          map[string]interface{}
+		subAggregations map[string]Aggregation
+	}
+
+	// Groups documents by date intervals
+	DateHistogramAggregation struct {
+		params          map[string]interface{}
+		subAggregations map[string]Aggregation
+	}
+
+	// Groups documents by fixed numeric intervals
+	HistogramAggregation struct {
+		params          map[string]interface{}
+		subAggregations map[string]Aggregation
+	}
+
+	// Groups documents by user defined numeric ranges
+	RangeAggregation struct {
+		params          map[string]interface{}
+		ranges          []interface{}
+		subAggregations map[string]Aggregation
+	}
+
+	// Aggregates the nested objects at a path as if they were separate documents
+	NestedAggregation struct {
+		path            string
+		subAggregations map[string]Aggregation
+	}
+)
+
+// Terms creates a bucket per unique value of field.
+func Terms(field string) *TermsAggregation {
+	return &TermsAggregation{params: map[string]interface{}{"field": field}}
+}
+
+// Size sets the number of buckets returned, elasticsearch defaults to 10.
+func (a *TermsAggregation) Size(size int) *TermsAggregation {
+	a.params["size"] = size
+	return a
+}
+
+// Order sorts the buckets by a key such as "_count", "_key" or the name of a
+// metric sub-aggregation.
+func (a *TermsAggregation) Order(key string, ascending bool) *TermsAggregation {
+	order := "desc"
+
+	if ascending {
+		order = "asc"
+	}
+
+	a.params["order"] = map[string]interface{}{key: order}
+	return a
+}
+
+// MinDocCount omits buckets with fewer documents.
+func (a *TermsAggregation) MinDocCount(count int) *TermsAggregation {
+	a.params["min_doc_count"] = count
+	return a
+}
+
+// Missing sets the bucket documents without the field are placed in.
+func (a *TermsAggregation) Missing(missing interface{}) *TermsAggregation {
+	a.params["missing"] = missing
+	return a
+}
+
+// SubAggregation computes an aggregation within every bucket.
+func (a *TermsAggregation) SubAggregation(name string, aggregation Aggregation) *TermsAggregation {
+	addSubAggregation(&a.subAggregations, name, aggregation)
+	return a
+}
+
+func (a *TermsAggregation) Source() map[string]interface{} {
+	return withSubAggregations(map[string]interface{}{"terms": a.params}, a.subAggregations)
+}
+
+// DateHistogram creates a bucket per calendar interval (e.g. "1d", "month") of field.
+func DateHistogram(field string, calendarInterval string) *DateHistogramAggregation {
+	return &DateHistogramAggregation{params: map[string]interface{}{"field": field, "calendar_interval": calendarInterval}}
+}
+
+// FixedInterval replaces the calendar interval with a fixed one such as "90m".
+func (a *DateHistogramAggregation) FixedInterval(interval string) *DateHistogramAggregation {
+	delete(a.params, "calendar_interval")
+	a.params["fixed_interval"] = interval
+	return a
+}
+
+// Format sets the format of each bucket's key_as_string.
+func (a *DateHistogramAggregation) Format(format string) *DateHistogramAggregation {
+	a.params["format"] = format
+	return a
+}
+
+// TimeZone sets the time zone buckets are rounded in.
+func (a *DateHistogramAggregation) TimeZone(timeZone string) *DateHistogramAggregation {
+	a.params["time_zone"] = timeZone
+	return a
+}
+
+// MinDocCount omits buckets with fewer documents.
+func (a *DateHistogramAggregation) MinDocCount(count int) *DateHistogramAggregation {
+	a.params["min_doc_count"] = count
+	return a
+}
+
+// SubAggregation computes an aggregation within every bucket.
+func (a *DateHistogramAggregation) SubAggregation(name string, aggregation Aggregation) *DateHistogramAggregation {
+	addSubAggregation(&a.subAggregations, name, aggregation)
+	return a
+}
+
+func (a *DateHistogramAggregation) Source() map[string]interface{} {
+	return withSubAggregations(map[string]interface{}{"date_histogram": a.params}, a.subAggregations)
+}
+
+// Histogram creates a bucket per interval of the numeric field.
+func Histogram(field string, interval float64) *HistogramAggregation {
+	return &HistogramAggregation{params: map[string]interface{}{"field": field, "interval": interval}}
+}
+
+// MinDocCount omits buckets with fewer documents.
+func (a *HistogramAggregation) MinDocCount(count int) *HistogramAggregation {
+	a.params["min_doc_count"] = count
+	return a
+}
+
+// SubAggregation computes an aggregation within every bucket.
+func (a *HistogramAggregation) SubAggregation(name string, aggregation Aggregation) *HistogramAggregation {
+	addSubAggregation(&a.subAggregations, name, aggregation)
+	return a
+}
+
+func (a *HistogramAggregation) Source() map[string]interface{} {
+	return withSubAggregations(map[string]interface{}{"histogram": a.params}, a.subAggregations)
+}
+
+// Range creates a bucket per range added with AddRange.
+func Range(field string) *RangeAggregation {
+	return &RangeAggregation{params: map[string]interface{}{"field": field}}
+}
+
+// AddRange adds a bucket for values within [from, to), pass nil for an unbounded side.
+func (a *RangeAggregation) AddRange(from interface{}, to interface{}) *RangeAggregation {
+	return a.AddKeyedRange("", from, to)
+}
+
+// AddKeyedRange is like AddRange but names the bucket.
+func (a *RangeAggregation) AddKeyedRange(key string, from interface{}, to interface{}) *RangeAggregation {
+	r := map[string]interface{}{}
+
+	if key != "" {
+		r["key"] = key
+	}
+
+	if from != nil {
+		r["from"] = from
+	}
+
+	if to != nil {
+		r["to"] = to
+	}
+
+	a.ranges = append(a.ranges, r)
+	return a
+}
+
+// SubAggregation computes an aggregation within every bucket.
+func (a *RangeAggregation) SubAggregation(name string, aggregation Aggregation) *RangeAggregation {
+	addSubAggregation(&a.subAggregations, name, aggregation)
+	return a
+}
+
+func (a *RangeAggregation) Source() map[string]interface{} {
+	params := map[string]interface{}{"ranges": a.ranges}
+
+	for k, v := range a.params {
+		params[k] = v
+	}
+
+	return withSubAggregations(map[string]interface{}{"range": params}, a.subAggregations)
+}
+
+// Nested creates a single bucket holding the nested objects at path.
+func Nested(path string) *NestedAggregation {
+	return &NestedAggregation{path: path}
+}
+
+// SubAggregation computes an aggregation over the nested objects.
+func (a *NestedAggregation) SubAggregation(name string, aggregation Aggregation) *NestedAggregation {
+	addSubAggregation(&a.subAggregations, name, aggregation)
+	return a
+}
+
+func (a *NestedAggregation) Source() map[string]interface{} {
+	return withSubAggregations(map[string]interface{}{"nested": map[string]interface{}{"path": a.path}}, a.subAggregations)
+}
@@ -0,0 +1,122 @@
+package aggs
+
+type (
+	// Computes a single value (avg, sum, min or max) over a numeric field
+	MetricAggregation struct {
+		kind   string
+		params map[string]interface{}
+	}
+
+	// Approximates the number of distinct values of a field
+	CardinalityAggregation struct {
+		params map[string]interface{}
+	}
+
+	// Computes percentiles over a numeric field
+	PercentilesAggregation struct {
+		params map[string]interface{}
+	}
+
+	// Returns the most relevant documents of each bucket
+	TopHitsAggregation struct {
+		params map[string]interface{}
+	}
+)
+
+// Avg computes the average of field.
+func Avg(field string) *MetricAggregation {
+	return newMetric("avg", field)
+}
+
+// Sum computes the sum of field.
+func Sum(field string) *MetricAggregation {
+	return newMetric("sum", field)
+}
+
+// Min computes the minimum of field.
+func Min(field string) *MetricAggregation {
+	return newMetric("min", field)
+}
+
+// Max computes the maximum of field.
+func Max(field string) *MetricAggregation {
+	return newMetric("max", field)
+}
+
+// ValueCount counts the values of field.
+func ValueCount(field string) *MetricAggregation {
+	return newMetric("value_count", field)
+}
+
+func newMetric(kind string, field string) *MetricAggregation {
+	return &MetricAggregation{kind: kind, params: map[string]interface{}{"field": field}}
+}
+
+// Missing sets the value used for documents without the field.
+func (a *MetricAggregation) Missing(missing interface{}) *MetricAggregation {
+	a.params["missing"] = missing
+	return a
+}
+
+func (a *MetricAggregation) Source() map[string]interface{} {
+	return map[string]interface{}{a.kind: a.params}
+}
+
+// Cardinality approximates the number of distinct values of field.
+func Cardinality(field string) *CardinalityAggregation {
+	return &CardinalityAggregation{params: map[string]interface{}{"field": field}}
+}
+
+// PrecisionThreshold sets the count below which results are expected to be exact.
+func (a *CardinalityAggregation) PrecisionThreshold(threshold int) *CardinalityAggregation {
+	a.params["precision_threshold"] = threshold
+	return a
+}
+
+func (a *CardinalityAggregation) Source() map[string]interface{} {
+	return map[string]interface{}{"cardinality": a.params}
+}
+
+// Percentiles computes the given percents of field, elasticsearch defaults to
+// 1, 5, 25, 50, 75, 95 and 99 when none are passed.
+func Percentiles(field string, percents ...float64) *PercentilesAggregation {
+	params := map[string]interface{}{"field": field}
+
+	if len(percents) > 0 {
+		params["percents"] = percents
+	}
+
+	return &PercentilesAggregation{params: params}
+}
+
+func (a *PercentilesAggregation) Source() map[string]interface{} {
+	return map[string]interface{}{"percentiles": a.params}
+}
+
+// TopHits returns up to size documents per bucket.
+func TopHits(size int) *TopHitsAggregation {
+	return &TopHitsAggregation{params: map[string]interface{}{"size": size}}
+}
+
+// Sort orders the returned documents by field.
+func (a *TopHitsAggregation) Sort(field string, ascending bool) *TopHitsAggregation {
+	order := "desc"
+
+	if ascending {
+		order = "asc"
+	}
+
+	sorts, _ := a.params["sort"].([]interface{})
+	a.params["sort"] = append(sorts, map[string]interface{}{field: map[string]interface{}{"order": order}})
+	return a
+}
+
+// Includes limits the returned _source to the given fields.
+func (a *TopHitsAggregation) Includes(fields ...string) *TopHitsAggregation {
+	a.params["_source"] = map[string]interface{}{"includes": fields}
+	return a
+}
+
+func (a *TopHitsAggregation) Source() map[string]interface{} {
+	return map[string]interface{}{"top_hits": a.params}
+}
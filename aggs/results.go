@@ -0,0 +1,238 @@
+package aggs
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+type (
+	// Results holds the aggregations of a search response, or the
+	// sub-aggregations of a bucket, by name
+	Results map[string]json.RawMessage
+
+	// MetricResult is the result of a single value metric aggregation such as
+	// avg, sum, min, max, value_count or cardinality
+	MetricResult struct {
+		// nil when no document had a value for the field
+		Value         *float64 `json:"value"`
+		ValueAsString string   `json:"value_as_string"`
+	}
+
+	// BucketsResult is the result of a multi bucket aggregation such as terms,
+	// histogram, date_histogram or range
+	BucketsResult struct {
+		Buckets                 []*Bucket
+		DocCountErrorUpperBound int64
+		SumOtherDocCount        int64
+	}
+
+	// Bucket is a single group of documents within a BucketsResult
+	Bucket struct {
+		Key         interface{}
+		KeyAsString string
+		DocCount    int64
+		// bounds of a range bucket
+		From *float64
+		To   *float64
+		// sub-aggregations computed within the bucket
+		Aggregations Results
+	}
+
+	// SingleBucketResult is the result of an aggregation producing a single
+	// bucket, such as nested
+	SingleBucketResult struct {
+		DocCount     int64
+		Aggregations Results
+	}
+
+	// PercentilesResult maps each requested percent (e.g. "99.0") to its value
+	PercentilesResult struct {
+		Values map[string]*float64 `json:"values"`
+	}
+
+	// TopHitsResult holds the documents returned by a top_hits aggregation
+	TopHitsResult struct {
+		Hits []*TopHit
+	}
+
+	// TopHit is a single document returned by a top_hits aggregation
+	TopHit struct {
+		Index  string          `json:"_index"`
+		Type   string          `json:"_type"`
+		ID     string          `json:"_id"`
+		Score  *float64        `json:"_score"`
+		Source json.RawMessage `json:"_source"`
+	}
+)
+
+func (r Results) decode(name string, v interface{}) bool {
+	raw, exists := r[name]
+
+	if !exists {
+		return false
+	}
+
+	return json.Unmarshal(raw, v) == nil
+}
+
+// Metric returns the result of a single value metric aggregation.
+func (r Results) Metric(name string) (*MetricResult, bool) {
+	result := &MetricResult{}
+	return result, r.decode(name, result)
+}
+
+// Buckets returns the result of a multi bucket aggregation.
+func (r Results) Buckets(name string) (*BucketsResult, bool) {
+	result := &BucketsResult{}
+	return result, r.decode(name, result)
+}
+
+// Single returns the result of a single bucket aggregation.
+func (r Results) Single(name string) (*SingleBucketResult, bool) {
+	result := &SingleBucketResult{}
+	return result, r.decode(name, result)
+}
+
+// Percentiles returns the result of a percentiles aggregation.
+func (r Results) Percentiles(name string) (*PercentilesResult, bool) {
+	result := &PercentilesResult{}
+	return result, r.decode(name, result)
+}
+
+// TopHits returns the result of a top_hits aggregation.
+func (r Results) TopHits(name string) (*TopHitsResult, bool) {
+	result := &TopHitsResult{}
+	return result, r.decode(name, result)
+}
+
+// UnmarshalJSON accepts both the list and the keyed object form of buckets.
+func (b *BucketsResult) UnmarshalJSON(data []byte) error {
+	raw := &struct {
+		Buckets                 json.RawMessage `json:"buckets"`
+		DocCountErrorUpperBound int64           `json:"doc_count_error_upper_bound"`
+		SumOtherDocCount        int64           `json:"sum_other_doc_count"`
+	}{}
+
+	if err := json.Unmarshal(data, raw); err != nil {
+		return err
+	}
+
+	b.DocCountErrorUpperBound = raw.DocCountErrorUpperBound
+	b.SumOtherDocCount = raw.SumOtherDocCount
+
+	if len(raw.Buckets) == 0 {
+		return nil
+	}
+
+	if err := json.Unmarshal(raw.Buckets, &b.Buckets); err == nil {
+		return nil
+	}
+
+	keyed := map[string]*Bucket{}
+
+	if err := json.Unmarshal(raw.Buckets, &keyed); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(keyed))
+
+	for key := range keyed {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		bucket := keyed[key]
+
+		if bucket.Key == nil {
+			bucket.Key = key
+		}
+
+		b.Buckets = append(b.Buckets, bucket)
+	}
+
+	return nil
+}
+
+// UnmarshalJSON separates the properties of a bucket from its sub-aggregations.
+func (b *Bucket) UnmarshalJSON(data []byte) error {
+	properties := Results{}
+
+	if err := json.Unmarshal(data, &properties); err != nil {
+		return err
+	}
+
+	decoders := map[string]interface{}{
+		"key":           &b.Key,
+		"key_as_string": &b.KeyAsString,
+		"doc_count":     &b.DocCount,
+		"from":          &b.From,
+		"to":            &b.To,
+	}
+
+	for name, v := range decoders {
+		if raw, exists := properties[name]; exists {
+			if err := json.Unmarshal(raw, v); err != nil {
+				return err
+			}
+		}
+	}
+
+	b.Aggregations = subAggregations(properties, "from_as_string", "to_as_string", "doc_count_error_upper_bound")
+	return nil
+}
+
+// UnmarshalJSON separates the document count of a bucket from its sub-aggregations.
+func (b *SingleBucketResult) UnmarshalJSON(data []byte) error {
+	properties := Results{}
+
+	if err := json.Unmarshal(data, &properties); err != nil {
+		return err
+	}
+
+	if raw, exists := properties["doc_count"]; exists {
+		if err := json.Unmarshal(raw, &b.DocCount); err != nil {
+			return err
+		}
+	}
+
+	b.Aggregations = subAggregations(properties)
+	return nil
+}
+
+// UnmarshalJSON extracts the documents nested within the hits property.
+func (t *TopHitsResult) UnmarshalJSON(data []byte) error {
+	raw := &struct {
+		Hits struct {
+			Hits []*TopHit `json:"hits"`
+		} `json:"hits"`
+	}{}
+
+	if err := json.Unmarshal(data, raw); err != nil {
+		return err
+	}
+
+	t.Hits = raw.Hits.Hits
+	return nil
+}
+
+// Value returns the value of the given percent.
+func (p *PercentilesResult) Value(percent string) (float64, bool) {
+	value, exists := p.Values[percent]
+
+	if !exists || value == nil {
+		return 0, false
+	}
+
+	return *value, true
+}
+
+// the remaining properties of a bucket, which are its named sub-aggregations
+func subAggregations(properties Results, ignored ...string) Results {
+	for _, name := range append(ignored, "key", "key_as_string", "doc_count", "from", "to") {
+		delete(properties, name)
+	}
+
+	return properties
+}
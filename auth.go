@@ -0,0 +1,93 @@
+package elasticsearch
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// credentials and headers sent with every request
+type auth struct {
+	username string
+	password string
+	apiKey   string
+	headers  http.Header
+	refresh  func(ctx context.Context) (string, error)
+
+	// the bearer token changes when it is refreshed
+	mu    sync.RWMutex
+	token string
+}
+
+var errConflictingAuth = errors.New("Only one of basic auth, an API key or a bearer token may be configured.")
+
+// EncodeAPIKey encodes the id and key returned by the elasticsearch Create
+// API key API into the form expected by Options.APIKey.
+func EncodeAPIKey(ID string, key string) string {
+	return base64.StdEncoding.EncodeToString([]byte(ID + ":" + key))
+}
+
+func newAuth(options *Options) *auth {
+	return &auth{
+		username: options.Username,
+		password: options.Password,
+		apiKey:   options.APIKey,
+		headers:  options.Headers,
+		refresh:  options.RefreshToken,
+		token:    options.BearerToken,
+	}
+}
+
+// set the headers and credentials on req, returns the bearer token which was
+// used so that a rejected one can be refreshed
+func (a *auth) apply(req *http.Request) string {
+	for key, values := range a.headers {
+		req.Header.Del(key)
+
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+
+	a.mu.RLock()
+	token := a.token
+	a.mu.RUnlock()
+
+	switch {
+	case a.username != "" || a.password != "":
+		req.SetBasicAuth(a.username, a.password)
+	case a.apiKey != "":
+		req.Header.Set("Authorization", "ApiKey "+a.apiKey)
+	case token != "":
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return token
+}
+
+// whether a request rejected with err may succeed with a fresh bearer token
+func (a *auth) canRefresh(err error) bool {
+	return a.refresh != nil && IsUnauthorized(err)
+}
+
+// replace the bearer token by calling the refresh callback, unless another
+// request already replaced the stale one
+func (a *auth) refreshToken(ctx context.Context, stale string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.token != stale {
+		return nil
+	}
+
+	token, err := a.refresh(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	a.token = token
+	return nil
+}
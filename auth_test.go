@@ -0,0 +1,106 @@
+package elasticsearch_test
+
+import (
+	"context"
+	"github.com/b3ntly/elasticsearch"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestAuth(t *testing.T) {
+	var mu sync.Mutex
+	var received []http.Header
+	valid := "Bearer fresh"
+
+	// accepts basic auth, API keys and the current bearer token
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		received = append(received, req.Header)
+		authorization := req.Header.Get("Authorization")
+
+		if authorization == "" || authorization == "Bearer stale" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": {"type": "security_exception"}, "status": 401}`))
+			return
+		}
+
+		w.Write([]byte(`{"acknowledged": true, "items": [{"delete": {"_id": "1", "status": 200}}]}`))
+	}))
+	defer server.Close()
+
+	newClient := func(t *testing.T, options *elasticsearch.Options) *elasticsearch.Client {
+		mu.Lock()
+		received = nil
+		mu.Unlock()
+
+		options.URI = server.URL
+		client, err := elasticsearch.New(options)
+		require.Nil(t, err)
+		return client
+	}
+
+	t.Run("Basic auth and default headers are sent with every request", func(t *testing.T) {
+		client := newClient(t, &elasticsearch.Options{
+			Username: "elastic",
+			Password: "changeme",
+			Headers:  http.Header{"X-Opaque-Id": []string{"test"}},
+		})
+
+		require.Nil(t, client.I("test").Drop())
+		_, err := client.I("test").T("test").BulkDelete("1")
+		require.Nil(t, err)
+
+		require.Len(t, received, 2)
+
+		for _, header := range received {
+			require.Equal(t, "Basic ZWxhc3RpYzpjaGFuZ2VtZQ==", header.Get("Authorization"))
+			require.Equal(t, "test", header.Get("X-Opaque-Id"))
+			require.Equal(t, "application/json", header.Get("Content-Type"))
+		}
+	})
+
+	t.Run("API keys are sent as id:key encoded in base64", func(t *testing.T) {
+		client := newClient(t, &elasticsearch.Options{APIKey: elasticsearch.EncodeAPIKey("id", "key")})
+
+		require.Nil(t, client.I("test").Drop())
+		require.Equal(t, "ApiKey aWQ6a2V5", received[0].Get("Authorization"))
+	})
+
+	t.Run("Rejected bearer tokens are refreshed once", func(t *testing.T) {
+		refreshes := 0
+		client := newClient(t, &elasticsearch.Options{
+			BearerToken: "stale",
+			RefreshToken: func(ctx context.Context) (string, error) {
+				refreshes++
+				return "fresh", nil
+			},
+		})
+
+		require.Nil(t, client.I("test").Drop())
+		require.Nil(t, client.I("test").Drop())
+		require.Equal(t, 1, refreshes)
+
+		require.Len(t, received, 3)
+		require.Equal(t, "Bearer stale", received[0].Get("Authorization"))
+		require.Equal(t, valid, received[1].Get("Authorization"))
+		require.Equal(t, valid, received[2].Get("Authorization"))
+
+		// a token which is rejected after refreshing is not refreshed again
+		client = newClient(t, &elasticsearch.Options{
+			RefreshToken: func(ctx context.Context) (string, error) { return "stale", nil },
+		})
+
+		require.True(t, elasticsearch.IsUnauthorized(client.I("test").Drop()))
+		require.Len(t, received, 2)
+	})
+
+	t.Run("Only one kind of credentials may be configured", func(t *testing.T) {
+		_, err := elasticsearch.New(&elasticsearch.Options{Username: "elastic", APIKey: "aWQ6a2V5"})
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,449 @@
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/b3ntly/elasticsearch/mock"
+)
+
+type (
+	// BulkableRequest is implemented by every action which may be sent to
+	// the Bulk API
+	BulkableRequest interface {
+		// Source returns the NDJSON lines of the action, its metadata followed
+		// by its payload if the action has one
+		Source() ([][]byte, error)
+	}
+
+	// implemented by the actions of this package, which encode their lines
+	// with the Codec of the client they are sent by
+	codecSource interface {
+		sourceWith(codec Codec) ([][]byte, error)
+	}
+
+	// Indexes a document, replacing it if one exists with the same ID
+	BulkIndexRequest struct {
+		action string
+		meta   *mock.BulkMetadata
+		doc    []byte
+	}
+
+	// Partially updates a document, optionally creating it if it does not exist
+	BulkUpdateRequest struct {
+		meta           *mock.BulkMetadata
+		doc            []byte
+		upsert         []byte
+		docAsUpsert    bool
+		script         *Script
+		scriptedUpsert bool
+	}
+
+	// Deletes a document
+	BulkDeleteRequest struct {
+		meta *mock.BulkMetadata
+	}
+
+	// BulkRequest combines any number of actions, across indices and types,
+	// into a single call to the Bulk API. Create one with Client.Bulk,
+	// Index.Bulk or Type.Bulk and chain its setters.
+	BulkRequest struct {
+		rest     *rest
+		index    string
+		_type    string
+		requests []BulkableRequest
+		refresh  string
+	}
+
+	// BulkResponse is the decoded result of a call to the Bulk API. Items
+	// are in the same order as the actions of the request.
+	BulkResponse struct {
+		Took int
+		// true if any of the actions failed
+		Errors bool
+		Items  []*BulkResponseItem
+	}
+
+	// BulkResponseItem is the result of a single bulk action
+	BulkResponseItem struct {
+		// the action which was performed, one of index, create, update or delete
+		Action  string
+		Index   string
+		Type    string
+		ID      string
+		Status  int
+		Version int64
+		// created, updated, deleted, noop or not_found
+		Result string
+		// set if the action failed
+		Error *ElasticsearchError
+	}
+)
+
+var (
+	errNoBulkActions     = errors.New("A bulk request requires at least one action.")
+	errBulkUpdatePayload = errors.New("A bulk update action requires a partial document or a script.")
+)
+
+// Bulk creates an empty bulk request, every action must name its index and type.
+func (c *Client) Bulk() *BulkRequest {
+	return &BulkRequest{rest: c.REST}
+}
+
+// Bulk creates an empty bulk request whose actions default to this index.
+func (idx *Index) Bulk() *BulkRequest {
+	return &BulkRequest{rest: idx.Client.REST, index: idx.Name}
+}
+
+// Bulk creates an empty bulk request whose actions default to this index-type.
+func (t *Type) Bulk() *BulkRequest {
+	return &BulkRequest{rest: t.Index.Client.REST, index: t.Index.Name, _type: t.Name}
+}
+
+// Add appends actions to the request.
+func (b *BulkRequest) Add(requests ...BulkableRequest) *BulkRequest {
+	b.requests = append(b.requests, requests...)
+	return b
+}
+
+// Refresh sets when the changes become visible to search, either "true",
+// "false" or "wait_for".
+func (b *BulkRequest) Refresh(refresh string) *BulkRequest {
+	b.refresh = refresh
+	return b
+}
+
+// NumberOfActions returns the number of actions added so far.
+func (b *BulkRequest) NumberOfActions() int {
+	return len(b.requests)
+}
+
+// Execute sends every action in a single call to the Bulk API. An error is
+// only returned if the request itself failed, the result of every action is
+// listed by the response.
+func (b *BulkRequest) Execute() (*BulkResponse, error) {
+	return b.ExecuteContext(context.Background())
+}
+
+// ExecuteContext is like Execute but the request is bound to ctx.
+func (b *BulkRequest) ExecuteContext(ctx context.Context) (*BulkResponse, error) {
+	if len(b.requests) == 0 {
+		return nil, errNoBulkActions
+	}
+
+	queryMap := map[string]string{}
+
+	if b.refresh != "" {
+		queryMap["refresh"] = b.refresh
+	}
+
+	return b.rest.bulkRequests(ctx, b.index, b._type, b.requests, queryMap)
+}
+
+// NewBulkIndexRequest creates an action indexing doc, replacing any document
+// with the same ID.
+func NewBulkIndexRequest(doc []byte) *BulkIndexRequest {
+	return &BulkIndexRequest{action: "index", meta: &mock.BulkMetadata{}, doc: doc}
+}
+
+// NewBulkCreateRequest creates an action indexing doc which fails if a
+// document with the same ID exists.
+func NewBulkCreateRequest(doc []byte) *BulkIndexRequest {
+	return &BulkIndexRequest{action: "create", meta: &mock.BulkMetadata{}, doc: doc}
+}
+
+// Index sets the index of the document.
+func (r *BulkIndexRequest) Index(index string) *BulkIndexRequest {
+	r.meta.Index = index
+	return r
+}
+
+// Type sets the type of the document.
+func (r *BulkIndexRequest) Type(_type string) *BulkIndexRequest {
+	r.meta.Type = _type
+	return r
+}
+
+// ID sets the ID of the document, one is generated if it is not set.
+func (r *BulkIndexRequest) ID(ID string) *BulkIndexRequest {
+	r.meta.ID = ID
+	return r
+}
+
+// Routing sets the value routing the document to a shard, which defaults to its ID.
+func (r *BulkIndexRequest) Routing(routing string) *BulkIndexRequest {
+	r.meta.Routing = routing
+	return r
+}
+
+// Version performs the action only if it matches the version of the document,
+// see VersionType.
+func (r *BulkIndexRequest) Version(version int64) *BulkIndexRequest {
+	r.meta.Version = &version
+	return r
+}
+
+// VersionType sets how Version is compared, either "internal", "external" or
+// "external_gte".
+func (r *BulkIndexRequest) VersionType(versionType string) *BulkIndexRequest {
+	r.meta.VersionType = versionType
+	return r
+}
+
+// IfSeqNo performs the action only if the document was last changed by the
+// operation with the given sequence number, see IfPrimaryTerm.
+func (r *BulkIndexRequest) IfSeqNo(seqNo int64) *BulkIndexRequest {
+	r.meta.IfSeqNo = &seqNo
+	return r
+}
+
+// IfPrimaryTerm performs the action only if the document was last changed
+// under the given primary term, see IfSeqNo.
+func (r *BulkIndexRequest) IfPrimaryTerm(primaryTerm int64) *BulkIndexRequest {
+	r.meta.IfPrimaryTerm = &primaryTerm
+	return r
+}
+
+func (r *BulkIndexRequest) Source() ([][]byte, error) {
+	return r.sourceWith(DefaultCodec)
+}
+
+func (r *BulkIndexRequest) sourceWith(codec Codec) ([][]byte, error) {
+	return bulkSource(codec, r.action, r.meta, r.doc)
+}
+
+// NewBulkUpdateRequest creates an action updating the document with the given ID.
+func NewBulkUpdateRequest(ID string) *BulkUpdateRequest {
+	return &BulkUpdateRequest{meta: &mock.BulkMetadata{ID: ID}}
+}
+
+// NewBulkUpsertRequest creates an action merging doc into the document with
+// the given ID, indexing doc as is if no such document exists.
+func NewBulkUpsertRequest(ID string, doc []byte) *BulkUpdateRequest {
+	return NewBulkUpdateRequest(ID).Doc(doc).DocAsUpsert(true)
+}
+
+// Index sets the index of the document.
+func (r *BulkUpdateRequest) Index(index string) *BulkUpdateRequest {
+	r.meta.Index = index
+	return r
+}
+
+// Type sets the type of the document.
+func (r *BulkUpdateRequest) Type(_type string) *BulkUpdateRequest {
+	r.meta.Type = _type
+	return r
+}
+
+// Routing sets the value routing the document to a shard, which defaults to its ID.
+func (r *BulkUpdateRequest) Routing(routing string) *BulkUpdateRequest {
+	r.meta.Routing = routing
+	return r
+}
+
+// IfSeqNo performs the action only if the document was last changed by the
+// operation with the given sequence number, see IfPrimaryTerm.
+func (r *BulkUpdateRequest) IfSeqNo(seqNo int64) *BulkUpdateRequest {
+	r.meta.IfSeqNo = &seqNo
+	return r
+}
+
+// IfPrimaryTerm performs the action only if the document was last changed
+// under the given primary term, see IfSeqNo.
+func (r *BulkUpdateRequest) IfPrimaryTerm(primaryTerm int64) *BulkUpdateRequest {
+	r.meta.IfPrimaryTerm = &primaryTerm
+	return r
+}
+
+// RetryOnConflict retries the update this many times if the document is
+// changed concurrently.
+func (r *BulkUpdateRequest) RetryOnConflict(retries int) *BulkUpdateRequest {
+	r.meta.RetryOnConflict = retries
+	return r
+}
+
+// Doc sets the partial document merged into the existing one.
+func (r *BulkUpdateRequest) Doc(doc []byte) *BulkUpdateRequest {
+	r.doc = doc
+	return r
+}
+
+// Upsert sets the document which is indexed if none exists with the ID.
+func (r *BulkUpdateRequest) Upsert(doc []byte) *BulkUpdateRequest {
+	r.upsert = doc
+	return r
+}
+
+// DocAsUpsert indexes the partial document if none exists with the ID.
+func (r *BulkUpdateRequest) DocAsUpsert(docAsUpsert bool) *BulkUpdateRequest {
+	r.docAsUpsert = docAsUpsert
+	return r
+}
+
+// Script updates the document with a script rather than a partial document.
+func (r *BulkUpdateRequest) Script(script *Script) *BulkUpdateRequest {
+	r.script = script
+	return r
+}
+
+// ScriptedUpsert runs the script against the Upsert document as well if none
+// exists with the ID.
+func (r *BulkUpdateRequest) ScriptedUpsert(scriptedUpsert bool) *BulkUpdateRequest {
+	r.scriptedUpsert = scriptedUpsert
+	return r
+}
+
+func (r *BulkUpdateRequest) Source() ([][]byte, error) {
+	return r.sourceWith(DefaultCodec)
+}
+
+func (r *BulkUpdateRequest) sourceWith(codec Codec) ([][]byte, error) {
+	if r.doc == nil && r.script == nil {
+		return nil, errBulkUpdatePayload
+	}
+
+	body := &mock.BulkUpdatePayload{Doc: r.doc, Upsert: r.upsert, DocAsUpsert: r.docAsUpsert, ScriptedUpsert: r.scriptedUpsert}
+
+	if r.script != nil {
+		body.Script = r.script.body()
+	}
+
+	payload, err := codec.Marshal(body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return bulkSource(codec, "update", r.meta, payload)
+}
+
+// NewBulkDeleteRequest creates an action deleting the document with the given ID.
+func NewBulkDeleteRequest(ID string) *BulkDeleteRequest {
+	return &BulkDeleteRequest{meta: &mock.BulkMetadata{ID: ID}}
+}
+
+// Index sets the index of the document.
+func (r *BulkDeleteRequest) Index(index string) *BulkDeleteRequest {
+	r.meta.Index = index
+	return r
+}
+
+// Type sets the type of the document.
+func (r *BulkDeleteRequest) Type(_type string) *BulkDeleteRequest {
+	r.meta.Type = _type
+	return r
+}
+
+// Routing sets the value routing the document to a shard, which defaults to its ID.
+func (r *BulkDeleteRequest) Routing(routing string) *BulkDeleteRequest {
+	r.meta.Routing = routing
+	return r
+}
+
+// Version performs the action only if it matches the version of the document,
+// see VersionType.
+func (r *BulkDeleteRequest) Version(version int64) *BulkDeleteRequest {
+	r.meta.Version = &version
+	return r
+}
+
+// VersionType sets how Version is compared, either "internal", "external" or
+// "external_gte".
+func (r *BulkDeleteRequest) VersionType(versionType string) *BulkDeleteRequest {
+	r.meta.VersionType = versionType
+	return r
+}
+
+// IfSeqNo performs the action only if the document was last changed by the
+// operation with the given sequence number, see IfPrimaryTerm.
+func (r *BulkDeleteRequest) IfSeqNo(seqNo int64) *BulkDeleteRequest {
+	r.meta.IfSeqNo = &seqNo
+	return r
+}
+
+// IfPrimaryTerm performs the action only if the document was last changed
+// under the given primary term, see IfSeqNo.
+func (r *BulkDeleteRequest) IfPrimaryTerm(primaryTerm int64) *BulkDeleteRequest {
+	r.meta.IfPrimaryTerm = &primaryTerm
+	return r
+}
+
+func (r *BulkDeleteRequest) Source() ([][]byte, error) {
+	return r.sourceWith(DefaultCodec)
+}
+
+func (r *BulkDeleteRequest) sourceWith(codec Codec) ([][]byte, error) {
+	return bulkSource(codec, "delete", r.meta, nil)
+}
+
+// the lines of request, encoded with codec unless it is implemented outside this package
+func encodeBulkRequest(codec Codec, request BulkableRequest) ([][]byte, error) {
+	if source, ok := request.(codecSource); ok {
+		return source.sourceWith(codec)
+	}
+
+	return request.Source()
+}
+
+// the metadata line of an action followed by its payload. Only delete
+// actions have none, a missing payload would shift every following line.
+func bulkSource(codec Codec, action string, meta interface{}, payload []byte) ([][]byte, error) {
+	if payload == nil && action != "delete" {
+		return nil, fmt.Errorf("A bulk %v action requires a document.", action)
+	}
+
+	line, err := codec.Marshal(map[string]interface{}{action: meta})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if payload == nil {
+		return [][]byte{line}, nil
+	}
+
+	return [][]byte{line, payload}, nil
+}
+
+// Failed reports whether the action was rejected. Deleting a document which
+// does not exist is not a failure, its Result is "not_found".
+func (i *BulkResponseItem) Failed() bool {
+	return i.Error != nil
+}
+
+// Failed returns the items whose action was rejected.
+func (r *BulkResponse) Failed() []*BulkResponseItem {
+	failed := []*BulkResponseItem{}
+
+	for _, item := range r.Items {
+		if item.Failed() {
+			failed = append(failed, item)
+		}
+	}
+
+	return failed
+}
+
+// Succeeded returns the items whose action was performed.
+func (r *BulkResponse) Succeeded() []*BulkResponseItem {
+	succeeded := []*BulkResponseItem{}
+
+	for _, item := range r.Items {
+		if !item.Failed() {
+			succeeded = append(succeeded, item)
+		}
+	}
+
+	return succeeded
+}
+
+// IDs returns the ID of the document of every item, in the order of the actions.
+func (r *BulkResponse) IDs() []string {
+	IDs := make([]string, len(r.Items))
+
+	for i, item := range r.Items {
+		IDs[i] = item.ID
+	}
+
+	return IDs
+}
@@ -0,0 +1,436 @@
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type (
+	// BulkProcessorOptions configures when a BulkProcessor flushes its
+	// pending actions and how rejected actions are retried. Zero values are
+	// replaced with defaults, negative values disable a threshold.
+	BulkProcessorOptions struct {
+		// number of bulk requests which may be in flight at once, defaults to 1
+		Workers int
+		// flush once this many actions are pending, defaults to 1000
+		BulkActions int
+		// flush once the pending payload reaches this many bytes, defaults to 5MB
+		BulkSize int
+		// flush pending actions periodically, disabled when zero
+		FlushInterval time.Duration
+		// times an action rejected with 429 Too Many Requests is retried, defaults to 3
+		MaxRetries int
+		// delay before the first retry, doubled for every following one, defaults to 100ms
+		InitialBackoff time.Duration
+		// upper bound of the delay between retries, defaults to 10s
+		MaxBackoff time.Duration
+		// called by a worker before a batch of actions is sent
+		Before func(requests []BulkableRequest)
+		// called by a worker once a batch of actions is committed, response
+		// lists the final result of every action in the order they were added.
+		// Before and After may call Add but not Flush or Close, which would
+		// wait for the batch being committed.
+		After func(requests []BulkableRequest, response *BulkResponse, err error)
+	}
+
+	// BulkProcessorStats are the counters of a BulkProcessor since it was created
+	BulkProcessorStats struct {
+		// batches of actions committed
+		Flushed int64
+		// requests sent to the Bulk API, including retries
+		Committed int64
+		// actions which were performed
+		Succeeded int64
+		// actions which failed, including those of batches whose request failed
+		Failed int64
+		// actions which were sent again after being rejected
+		Retried int64
+	}
+
+	// BulkProcessor batches actions added from any number of goroutines and
+	// sends them to the Bulk API in the background. Call Close once done to
+	// send the remaining actions.
+	//
+	//	processor := client.BulkProcessor(&elasticsearch.BulkProcessorOptions{Workers: 4})
+	//	defer processor.Close()
+	//
+	//	processor.Add(elasticsearch.NewBulkIndexRequest(doc).Index("users").Type("user"))
+	BulkProcessor struct {
+		ctx     context.Context
+		rest    *rest
+		options BulkProcessorOptions
+
+		// guards the pending batch and the batches waiting for a worker
+		mu      sync.Mutex
+		pending []*bulkEntry
+		size    int
+		closed  bool
+		queue   []*bulkBatch
+		queued  uint64
+		ready   *sync.Cond
+		workers sync.WaitGroup
+		stop    chan struct{}
+
+		// guards the progress of the workers. Every batch up to committed is
+		// committed, done holds the batches committed ahead of those.
+		progressMu sync.Mutex
+		committed  uint64
+		done       map[uint64]bool
+		progress   *sync.Cond
+
+		statsMu sync.Mutex
+		stats   BulkProcessorStats
+	}
+
+	// an action alongside its NDJSON lines
+	bulkEntry struct {
+		request BulkableRequest
+		lines   [][]byte
+	}
+
+	// actions flushed together, numbered from 1 in the order they are queued
+	bulkBatch struct {
+		seq     uint64
+		entries []*bulkEntry
+	}
+)
+
+var (
+	DefaultBulkActions    = 1000
+	DefaultBulkSize       = 5 << 20
+	DefaultMaxRetries     = 3
+	DefaultInitialBackoff = 100 * time.Millisecond
+	DefaultMaxBackoff     = 10 * time.Second
+
+	// ErrProcessorClosed is returned when using a BulkProcessor after Close
+	ErrProcessorClosed = errors.New("The bulk processor is closed.")
+
+	errBulkItemMismatch = errors.New("The bulk response does not list a result for every action.")
+)
+
+// BulkProcessor creates a processor which sends the actions added to it to
+// the Bulk API in the background.
+func (c *Client) BulkProcessor(options *BulkProcessorOptions) *BulkProcessor {
+	return c.BulkProcessorContext(context.Background(), options)
+}
+
+// BulkProcessorContext is like BulkProcessor but every request is bound to ctx.
+func (c *Client) BulkProcessorContext(ctx context.Context, options *BulkProcessorOptions) *BulkProcessor {
+	p := &BulkProcessor{ctx: ctx, rest: c.REST, stop: make(chan struct{})}
+
+	if options != nil {
+		p.options = *options
+	}
+
+	p.options.init()
+	p.done = map[uint64]bool{}
+	p.progress = sync.NewCond(&p.progressMu)
+	p.ready = sync.NewCond(&p.mu)
+
+	for i := 0; i < p.options.Workers; i++ {
+		p.workers.Add(1)
+		go p.worker()
+	}
+
+	if p.options.FlushInterval > 0 {
+		go p.tick()
+	}
+
+	return p
+}
+
+func (opts *BulkProcessorOptions) init() {
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+
+	if opts.BulkActions == 0 {
+		opts.BulkActions = DefaultBulkActions
+	}
+
+	if opts.BulkSize == 0 {
+		opts.BulkSize = DefaultBulkSize
+	}
+
+	if opts.MaxRetries == 0 {
+		opts.MaxRetries = DefaultMaxRetries
+	}
+
+	if opts.InitialBackoff <= 0 {
+		opts.InitialBackoff = DefaultInitialBackoff
+	}
+
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = DefaultMaxBackoff
+	}
+}
+
+// Add queues actions, flushing the pending batch if it reaches one of the
+// thresholds. Flushed batches wait for a free worker in the background, Add
+// never blocks on the workers.
+func (p *BulkProcessor) Add(requests ...BulkableRequest) error {
+	entries := make([]*bulkEntry, len(requests))
+
+	for i, request := range requests {
+		lines, err := encodeBulkRequest(p.rest.codec, request)
+
+		if err != nil {
+			return err
+		}
+
+		entries[i] = &bulkEntry{request: request, lines: lines}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return ErrProcessorClosed
+	}
+
+	for _, entry := range entries {
+		p.pending = append(p.pending, entry)
+
+		for _, line := range entry.lines {
+			p.size += len(line) + 1
+		}
+
+		if (p.options.BulkActions > 0 && len(p.pending) >= p.options.BulkActions) ||
+			(p.options.BulkSize > 0 && p.size >= p.options.BulkSize) {
+			p.dispatch()
+		}
+	}
+
+	return nil
+}
+
+// Flush sends the pending actions and waits until every batch queued so far
+// is committed. Batches queued by calls to Add while it waits are not waited
+// for.
+func (p *BulkProcessor) Flush() error {
+	p.mu.Lock()
+
+	if p.closed {
+		p.mu.Unlock()
+		return ErrProcessorClosed
+	}
+
+	p.dispatch()
+	last := p.queued
+	p.mu.Unlock()
+
+	p.progressMu.Lock()
+	defer p.progressMu.Unlock()
+
+	for p.committed < last {
+		p.progress.Wait()
+	}
+
+	return nil
+}
+
+// Close sends the pending actions, waits for every batch to be committed and
+// stops the workers. It is safe to call more than once.
+func (p *BulkProcessor) Close() error {
+	p.mu.Lock()
+
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+
+	p.closed = true
+	p.dispatch()
+	p.ready.Broadcast()
+	close(p.stop)
+	p.mu.Unlock()
+
+	p.workers.Wait()
+	return nil
+}
+
+// Stats returns a snapshot of the counters of the processor.
+func (p *BulkProcessor) Stats() BulkProcessorStats {
+	p.statsMu.Lock()
+	defer p.statsMu.Unlock()
+	return p.stats
+}
+
+// queue the pending batch for the next free worker, p.mu must be held
+func (p *BulkProcessor) dispatch() {
+	if len(p.pending) == 0 {
+		return
+	}
+
+	p.queued++
+	p.queue = append(p.queue, &bulkBatch{seq: p.queued, entries: p.pending})
+	p.pending, p.size = nil, 0
+	p.ready.Signal()
+}
+
+func (p *BulkProcessor) tick() {
+	ticker := time.NewTicker(p.options.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.mu.Lock()
+
+			if !p.closed {
+				p.dispatch()
+			}
+
+			p.mu.Unlock()
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+func (p *BulkProcessor) worker() {
+	defer p.workers.Done()
+
+	for {
+		p.mu.Lock()
+
+		for len(p.queue) == 0 && !p.closed {
+			p.ready.Wait()
+		}
+
+		// the queue is only drained once closed
+		if len(p.queue) == 0 {
+			p.mu.Unlock()
+			return
+		}
+
+		batch := p.queue[0]
+		p.queue[0] = nil
+		p.queue = p.queue[1:]
+		p.mu.Unlock()
+
+		p.commit(batch.entries)
+
+		p.progressMu.Lock()
+		p.done[batch.seq] = true
+
+		for p.done[p.committed+1] {
+			delete(p.done, p.committed+1)
+			p.committed++
+		}
+
+		p.progress.Broadcast()
+		p.progressMu.Unlock()
+	}
+}
+
+func (p *BulkProcessor) commit(batch []*bulkEntry) {
+	requests := make([]BulkableRequest, len(batch))
+
+	for i, entry := range batch {
+		requests[i] = entry.request
+	}
+
+	if p.options.Before != nil {
+		p.options.Before(requests)
+	}
+
+	response, err := p.execute(batch)
+
+	p.statsMu.Lock()
+	p.stats.Flushed++
+
+	if err != nil {
+		p.stats.Failed += int64(len(batch))
+	} else {
+		for _, item := range response.Items {
+			if item.Failed() {
+				p.stats.Failed++
+			} else {
+				p.stats.Succeeded++
+			}
+		}
+	}
+
+	p.statsMu.Unlock()
+
+	if p.options.After != nil {
+		p.options.After(requests, response, err)
+	}
+}
+
+// send a batch, resending the actions rejected with 429 Too Many Requests
+// until they are accepted or the retries are exhausted
+func (p *BulkProcessor) execute(batch []*bulkEntry) (*BulkResponse, error) {
+	result := &BulkResponse{Items: make([]*BulkResponseItem, len(batch))}
+	remaining := make([]int, len(batch))
+	backoff := p.options.InitialBackoff
+
+	for i := range remaining {
+		remaining[i] = i
+	}
+
+	for attempt := 0; ; attempt++ {
+		lines := [][]byte{}
+
+		for _, i := range remaining {
+			lines = append(lines, batch[i].lines...)
+		}
+
+		p.statsMu.Lock()
+		p.stats.Committed++
+		p.statsMu.Unlock()
+
+		response, err := p.rest.bulk(p.ctx, "", "", lines, nil)
+		canRetry := attempt < p.options.MaxRetries
+
+		if err != nil && !(canRetry && IsRetryable(err)) {
+			return nil, err
+		}
+
+		retry := []int{}
+
+		if err != nil {
+			retry = remaining
+		} else if len(response.Items) != len(remaining) {
+			return nil, errBulkItemMismatch
+		} else {
+			result.Took += response.Took
+
+			for k, item := range response.Items {
+				result.Items[remaining[k]] = item
+
+				if item.Status == http.StatusTooManyRequests && canRetry {
+					retry = append(retry, remaining[k])
+				}
+			}
+		}
+
+		if len(retry) == 0 {
+			break
+		}
+
+		p.statsMu.Lock()
+		p.stats.Retried += int64(len(retry))
+		p.statsMu.Unlock()
+
+		if err := sleepContext(p.ctx, backoff); err != nil {
+			return nil, err
+		}
+
+		remaining = retry
+
+		if backoff *= 2; backoff > p.options.MaxBackoff {
+			backoff = p.options.MaxBackoff
+		}
+	}
+
+	for _, item := range result.Items {
+		result.Errors = result.Errors || item.Failed()
+	}
+
+	return result, nil
+}
@@ -0,0 +1,275 @@
+package elasticsearch_test
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"github.com/b3ntly/elasticsearch"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBulkProcessor(t *testing.T) {
+	client, err := getClient("http://127.0.0.1:9201")
+	require.Nil(t, err)
+
+	t.Run("Flushes mixed actions in batches of BulkActions", func(t *testing.T) {
+		var mu sync.Mutex
+		batches := []int{}
+		items := []int{}
+		errs := []error{}
+
+		// callbacks run on the workers, results are checked once flushed
+		processor := client.BulkProcessor(&elasticsearch.BulkProcessorOptions{
+			Workers:     2,
+			BulkActions: 10,
+			After: func(requests []elasticsearch.BulkableRequest, response *elasticsearch.BulkResponse, err error) {
+				mu.Lock()
+				defer mu.Unlock()
+
+				batches = append(batches, len(requests))
+				errs = append(errs, err)
+
+				if response != nil {
+					items = append(items, len(response.Items))
+				}
+			},
+		})
+
+		for i := 0; i < 20; i++ {
+			doc := []byte(fmt.Sprintf(`{"rank": %d}`, i))
+			require.Nil(t, processor.Add(elasticsearch.NewBulkIndexRequest(doc).Index(testIndex).Type(testType).ID(fmt.Sprintf("bp-%02d", i))))
+		}
+
+		require.Nil(t, processor.Add(
+			elasticsearch.NewBulkUpdateRequest("bp-00").Index(testIndex).Type(testType).Doc([]byte(`{"rank": 100}`)),
+			elasticsearch.NewBulkUpsertRequest("bp-upsert", []byte(`{"rank": 200}`)).Index(testIndex).Type(testType),
+			elasticsearch.NewBulkDeleteRequest("bp-01").Index(testIndex).Type(testType),
+			elasticsearch.NewBulkCreateRequest([]byte(`{"rank": 300}`)).Index(testIndex).Type(testType).ID("bp-02"),
+		))
+
+		require.Nil(t, processor.Flush())
+		require.Nil(t, processor.Close())
+		require.Equal(t, elasticsearch.BulkProcessorStats{Flushed: 3, Committed: 3, Succeeded: 23, Failed: 1}, processor.Stats())
+		require.Equal(t, []error{nil, nil, nil}, errs)
+		sort.Ints(batches)
+		sort.Ints(items)
+		require.Equal(t, []int{4, 10, 10}, batches)
+		require.Equal(t, batches, items)
+
+		collection := client.I(testIndex).T(testType)
+
+		doc, err := collection.FindById("bp-00")
+		require.Nil(t, err)
+		require.Contains(t, string(doc), `"rank":100`)
+
+		_, err = collection.FindById("bp-upsert")
+		require.Nil(t, err)
+
+		_, err = collection.FindById("bp-01")
+		require.True(t, elasticsearch.IsNotFound(err))
+
+		require.Equal(t, elasticsearch.ErrProcessorClosed, processor.Add(elasticsearch.NewBulkDeleteRequest("bp-03")))
+	})
+
+	t.Run("Flushes pending actions every FlushInterval", func(t *testing.T) {
+		flushed := make(chan *elasticsearch.BulkResponse, 1)
+
+		processor := client.BulkProcessor(&elasticsearch.BulkProcessorOptions{
+			FlushInterval: 10 * time.Millisecond,
+			After: func(requests []elasticsearch.BulkableRequest, response *elasticsearch.BulkResponse, err error) {
+				flushed <- response
+			},
+		})
+		defer processor.Close()
+
+		require.Nil(t, processor.Add(elasticsearch.NewBulkIndexRequest([]byte(`{"rank": 1}`)).Index(testIndex).Type(testType)))
+
+		select {
+		case response := <-flushed:
+			require.Len(t, response.Items, 1)
+			require.False(t, response.Errors)
+		case <-time.After(time.Second):
+			t.Fatal("the pending action was never flushed")
+		}
+	})
+
+	t.Run("Actions may be added from the callbacks of a single worker", func(t *testing.T) {
+		var mu sync.Mutex
+		committed := 0
+		errs := []error{}
+		var processor *elasticsearch.BulkProcessor
+
+		record := func(err error) {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+		}
+
+		processor = client.BulkProcessor(&elasticsearch.BulkProcessorOptions{
+			Workers:     1,
+			BulkActions: 1,
+			After: func(requests []elasticsearch.BulkableRequest, response *elasticsearch.BulkResponse, err error) {
+				record(err)
+
+				mu.Lock()
+				committed++
+				followUp := committed <= 3
+				mu.Unlock()
+
+				// fills a batch while the only worker is busy with this one
+				if followUp {
+					record(processor.Add(elasticsearch.NewBulkIndexRequest([]byte(`{"rank": 2}`)).Index(testIndex).Type(testType)))
+				}
+			},
+		})
+
+		done := make(chan struct{})
+
+		go func() {
+			defer close(done)
+
+			for i := 0; i < 3; i++ {
+				record(processor.Add(elasticsearch.NewBulkIndexRequest([]byte(`{"rank": 1}`)).Index(testIndex).Type(testType)))
+			}
+
+			record(processor.Flush())
+			record(processor.Close())
+		}()
+
+		select {
+		case <-done:
+			require.Equal(t, int64(6), processor.Stats().Flushed)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			for _, err := range errs {
+				require.Nil(t, err)
+			}
+
+			require.Len(t, errs, 14)
+		case <-time.After(5 * time.Second):
+			t.Fatal("the processor deadlocked")
+		}
+	})
+
+	clean(client)
+}
+
+func TestBulkProcessor_Retry(t *testing.T) {
+	var mu sync.Mutex
+	actions := []int{}
+
+	// rejects the second action of the first request only
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		lines := 0
+
+		for scanner := bufio.NewScanner(bytes.NewReader(body)); scanner.Scan(); {
+			lines++
+		}
+
+		mu.Lock()
+		actions = append(actions, lines/2)
+		attempt := len(actions)
+		mu.Unlock()
+
+		if attempt == 1 {
+			w.Write([]byte(`{"took": 1, "errors": true, "items": [
+				{"index": {"_id": "1", "status": 201, "result": "created"}},
+				{"index": {"_id": "2", "status": 429, "error": {"type": "es_rejected_execution_exception", "reason": "queue is full"}}}
+			]}`))
+			return
+		}
+
+		w.Write([]byte(`{"took": 1, "errors": false, "items": [{"index": {"_id": "2", "status": 201, "result": "created"}}]}`))
+	}))
+	defer server.Close()
+
+	client, err := elasticsearch.New(&elasticsearch.Options{URI: server.URL})
+	require.Nil(t, err)
+
+	var result *elasticsearch.BulkResponse
+	var resultErr error
+
+	// Close waits for the worker, which is when result is checked
+	processor := client.BulkProcessor(&elasticsearch.BulkProcessorOptions{
+		InitialBackoff: time.Millisecond,
+		After: func(requests []elasticsearch.BulkableRequest, response *elasticsearch.BulkResponse, err error) {
+			result, resultErr = response, err
+		},
+	})
+
+	require.Nil(t, processor.Add(
+		elasticsearch.NewBulkIndexRequest([]byte(`{}`)).Index("test").Type("test").ID("1"),
+		elasticsearch.NewBulkIndexRequest([]byte(`{}`)).Index("test").Type("test").ID("2"),
+	))
+
+	require.Nil(t, processor.Close())
+	require.Equal(t, []int{2, 1}, actions)
+	require.Nil(t, resultErr)
+	require.False(t, result.Errors)
+	require.Equal(t, "1", result.Items[0].ID)
+	require.Equal(t, "2", result.Items[1].ID)
+	require.Equal(t, elasticsearch.BulkProcessorStats{Flushed: 1, Committed: 2, Succeeded: 2, Retried: 1}, processor.Stats())
+}
+
+func TestBulkProcessor_Flush(t *testing.T) {
+	received := make(chan string, 2)
+	proceed := make(chan struct{})
+	release := make(chan struct{})
+
+	// holds every request until the test lets it through
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+
+		if bytes.Contains(body, []byte("slow")) {
+			received <- "slow"
+			<-release
+		} else {
+			received <- "fast"
+			<-proceed
+		}
+
+		w.Write([]byte(`{"took": 1, "errors": false, "items": [{"index": {"_id": "1", "status": 201, "result": "created"}}]}`))
+	}))
+	defer server.Close()
+
+	client, err := elasticsearch.New(&elasticsearch.Options{URI: server.URL})
+	require.Nil(t, err)
+
+	processor := client.BulkProcessor(&elasticsearch.BulkProcessorOptions{Workers: 2})
+	defer processor.Close()
+	defer close(release)
+
+	require.Nil(t, processor.Add(elasticsearch.NewBulkIndexRequest([]byte(`{"speed": "fast"}`)).Index("test").Type("test")))
+
+	flushed := make(chan error, 1)
+
+	go func() {
+		flushed <- processor.Flush()
+	}()
+
+	require.Equal(t, "fast", <-received)
+
+	// queued while Flush waits and never committed before it returns
+	require.Nil(t, processor.Add(elasticsearch.NewBulkIndexRequest([]byte(`{"speed": "slow"}`)).Index("test").Type("test")))
+	go processor.Flush()
+	require.Equal(t, "slow", <-received)
+
+	close(proceed)
+
+	select {
+	case err := <-flushed:
+		require.Nil(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Flush waited for a batch queued after it was called")
+	}
+}
@@ -0,0 +1,158 @@
+package elasticsearch_test
+
+import (
+	"encoding/json"
+	"github.com/b3ntly/elasticsearch"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestBulkableRequest_Source(t *testing.T) {
+	source := func(t *testing.T, request elasticsearch.BulkableRequest) []string {
+		lines, err := request.Source()
+		require.Nil(t, err)
+
+		result := make([]string, len(lines))
+
+		for i, line := range lines {
+			result[i] = string(line)
+		}
+
+		return result
+	}
+
+	t.Run("Index and create actions carry their metadata and document", func(t *testing.T) {
+		request := elasticsearch.NewBulkIndexRequest([]byte(`{"a":1}`)).Index("i").Type("t").ID("1").Routing("user").Version(3).VersionType("external")
+		require.Equal(t, []string{
+			`{"index":{"_index":"i","_type":"t","_id":"1","routing":"user","version":3,"version_type":"external"}}`,
+			`{"a":1}`,
+		}, source(t, request))
+
+		request = elasticsearch.NewBulkCreateRequest([]byte(`{"a":1}`)).Index("i").IfSeqNo(0).IfPrimaryTerm(1)
+		require.Equal(t, []string{`{"create":{"_index":"i","if_seq_no":0,"if_primary_term":1}}`, `{"a":1}`}, source(t, request))
+	})
+
+	t.Run("Update actions carry a partial document or a script", func(t *testing.T) {
+		request := elasticsearch.NewBulkUpsertRequest("1", []byte(`{"a":1}`)).RetryOnConflict(2)
+		require.Equal(t, []string{`{"update":{"_id":"1","retry_on_conflict":2}}`, `{"doc":{"a":1},"doc_as_upsert":true}`}, source(t, request))
+
+		request = elasticsearch.NewBulkUpdateRequest("1").
+			Script(elasticsearch.NewScript("ctx._source.a += params.n").Param("n", 2)).
+			Upsert([]byte(`{"a":0}`)).
+			ScriptedUpsert(true)
+
+		require.Equal(t, []string{
+			`{"update":{"_id":"1"}}`,
+			`{"upsert":{"a":0},"script":{"source":"ctx._source.a += params.n","params":{"n":2}},"scripted_upsert":true}`,
+		}, source(t, request))
+	})
+
+	t.Run("Actions other than delete require a payload", func(t *testing.T) {
+		for _, request := range []elasticsearch.BulkableRequest{
+			elasticsearch.NewBulkIndexRequest(nil).Index("i"),
+			elasticsearch.NewBulkCreateRequest(nil).Index("i"),
+			elasticsearch.NewBulkUpdateRequest("1").Upsert([]byte(`{"a":0}`)),
+		} {
+			_, err := request.Source()
+			require.Error(t, err)
+		}
+	})
+
+	t.Run("Delete actions have no payload", func(t *testing.T) {
+		request := elasticsearch.NewBulkDeleteRequest("1").Index("i").Type("t").Routing("user")
+		require.Equal(t, []string{`{"delete":{"_index":"i","_type":"t","_id":"1","routing":"user"}}`}, source(t, request))
+	})
+}
+
+func TestBulk(t *testing.T) {
+	client, err := getClient("http://127.0.0.1:9201")
+	require.Nil(t, err)
+
+	const otherIndex = "test-bulk"
+	defer client.I(otherIndex).Drop()
+
+	collection := client.I(testIndex).T(testType)
+
+	t.Run("Client.Bulk combines actions across indices", func(t *testing.T) {
+		response, err := client.Bulk().
+			Add(
+				elasticsearch.NewBulkIndexRequest([]byte(`{"count": 1}`)).Index(testIndex).Type(testType).ID("1"),
+				elasticsearch.NewBulkIndexRequest([]byte(`{"count": 1}`)).Index(otherIndex).Type(testType).ID("1"),
+				elasticsearch.NewBulkCreateRequest([]byte(`{"count": 1}`)).Index(testIndex).Type(testType).ID("1"),
+				elasticsearch.NewBulkUpdateRequest("1").Index(testIndex).Type(testType).Script(elasticsearch.NewScript("ctx._source.count += params.n").Param("n", 4)),
+				elasticsearch.NewBulkDeleteRequest("1").Index(otherIndex).Type(testType),
+			).
+			Refresh("true").
+			Execute()
+
+		require.Nil(t, err)
+		require.True(t, response.Errors)
+		require.Len(t, response.Items, 5)
+
+		actions := []string{}
+
+		for _, item := range response.Items {
+			actions = append(actions, item.Action)
+		}
+
+		require.Equal(t, []string{"index", "index", "create", "update", "delete"}, actions)
+		require.Equal(t, otherIndex, response.Items[1].Index)
+		require.Len(t, response.Failed(), 1)
+		require.True(t, elasticsearch.IsVersionConflict(response.Failed()[0].Error))
+
+		doc, err := collection.FindById("1")
+		require.Nil(t, err)
+
+		fields := map[string]interface{}{}
+		require.Nil(t, json.Unmarshal(doc, &fields))
+		require.Equal(t, float64(5), fields["count"])
+
+		_, err = client.I(otherIndex).T(testType).FindById("1")
+		require.True(t, elasticsearch.IsNotFound(err))
+	})
+
+	t.Run("Type.Bulk defaults the index and type of its actions", func(t *testing.T) {
+		bulk := collection.Bulk().Add(
+			elasticsearch.NewBulkIndexRequest([]byte(`{"count": 1}`)).ID("2"),
+			elasticsearch.NewBulkUpsertRequest("3", []byte(`{"count": 3}`)),
+			elasticsearch.NewBulkUpdateRequest("2").Script(elasticsearch.NewScript("ctx._source.remove('count'); ctx._source.name = 'two'")),
+		)
+
+		require.Equal(t, 3, bulk.NumberOfActions())
+
+		response, err := bulk.Execute()
+		require.Nil(t, err)
+		require.False(t, response.Errors)
+
+		for _, item := range response.Items {
+			require.Equal(t, testIndex, item.Index)
+			require.Equal(t, testType, item.Type)
+		}
+
+		doc, err := collection.FindById("2")
+		require.Nil(t, err)
+		require.NotContains(t, string(doc), "count")
+		require.Contains(t, string(doc), `"name":"two"`)
+
+		_, err = collection.FindById("3")
+		require.Nil(t, err)
+	})
+
+	t.Run("Unsupported scripts fail their action only", func(t *testing.T) {
+		response, err := collection.Bulk().Add(
+			elasticsearch.NewBulkUpdateRequest("2").Script(elasticsearch.NewScript("ctx.op = 'delete'")),
+			elasticsearch.NewBulkDeleteRequest("2"),
+		).Execute()
+
+		require.Nil(t, err)
+		require.Equal(t, "script_exception", response.Items[0].Error.Type)
+		require.Equal(t, "deleted", response.Items[1].Result)
+	})
+
+	t.Run("Requests without actions are rejected", func(t *testing.T) {
+		_, err := client.Bulk().Execute()
+		require.Error(t, err)
+	})
+
+	clean(client)
+}
@@ -1,6 +1,12 @@
 package elasticsearch
 
-import "github.com/b3ntly/elasticsearch/mock"
+import (
+	"context"
+	"github.com/b3ntly/elasticsearch/mock"
+	"github.com/b3ntly/elasticsearch/query"
+	"sync"
+	"time"
+)
 
 type (
 	// Client interface for this library
@@ -8,6 +14,10 @@ type (
 		Options *Options
 		// Convenience wrapper for a rest interface with the elasticsearch server
 		REST *rest
+
+		// stops the background sniffer
+		stop      chan struct{}
+		closeOnce sync.Once
 	}
 
 	// Reference to an elasticsearch index
@@ -27,8 +37,51 @@ type (
 // replace zero-values with default values where desired.
 func New(options *Options) (*Client, error) {
 	err := options.Init()
-	r := &rest{BaseURI: options.URI, HTTPClient: options.HTTPClient}
-	return &Client{Options: options, REST: r}, err
+
+	if err != nil {
+		return &Client{Options: options, REST: &rest{BaseURI: options.URI, HTTPClient: options.HTTPClient}}, err
+	}
+
+	r, err := newREST(options)
+	client := &Client{Options: options, REST: r, stop: make(chan struct{})}
+
+	if err != nil || !options.Sniff {
+		return client, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), options.SnifferTimeout)
+	defer cancel()
+
+	if err := client.SniffContext(ctx); err != nil {
+		return client, err
+	}
+
+	if options.SnifferInterval > 0 {
+		go client.sniffer(options.SnifferInterval)
+	}
+
+	return client, nil
+}
+
+// Close stops the background work of the client, such as sniffing. It is
+// safe to call more than once.
+func (c *Client) Close() error {
+	c.closeOnce.Do(func() {
+		if c.stop != nil {
+			close(c.stop)
+		}
+	})
+
+	return nil
+}
+
+// Nodes returns the state of every node of the connection pool.
+func (c *Client) Nodes() []Node {
+	if c.REST.pool == nil {
+		return nil
+	}
+
+	return c.REST.pool.state()
 }
 
 // Index creates a reference to an elasticsearch index.
@@ -49,67 +102,267 @@ func (idx *Index) T(name string) *Type {
 // Perform a basic elasticsearch query on an index that will return exact matches
 // on the passed querystring.
 func (idx *Index) Search(querystring string) ([][]byte, error) {
-	return idx.Client.REST.searchIndex(idx.Name, querystring)
+	return idx.SearchContext(context.Background(), querystring)
+}
+
+// SearchContext is like Search but the request is bound to ctx.
+func (idx *Index) SearchContext(ctx context.Context, querystring string) ([][]byte, error) {
+	return idx.Client.REST.searchIndex(ctx, idx.Name, querystring)
+}
+
+// Query performs a search on an index described by a query DSL builder.
+func (idx *Index) Query(q query.Query) ([][]byte, error) {
+	return idx.QueryContext(context.Background(), q)
+}
+
+// QueryContext is like Query but the request is bound to ctx.
+func (idx *Index) QueryContext(ctx context.Context, q query.Query) ([][]byte, error) {
+	return idx.Client.REST.query(ctx, idx.Name, "", q)
+}
+
+// Execute performs a search on an index described by a SearchRequest and
+// returns the hits alongside the metadata of the response.
+func (idx *Index) Execute(search *SearchRequest) (*SearchResponse, error) {
+	return idx.ExecuteContext(context.Background(), search)
+}
+
+// ExecuteContext is like Execute but the request is bound to ctx.
+func (idx *Index) ExecuteContext(ctx context.Context, search *SearchRequest) (*SearchResponse, error) {
+	return idx.Client.REST.search(ctx, idx.Name, "", search, nil)
+}
+
+// Scan iterates over every hit of a search on an index using the scroll API,
+// keeping the scroll alive for keepAlive between pages.
+func (idx *Index) Scan(search *SearchRequest, keepAlive time.Duration) *Scroller {
+	return idx.ScanContext(context.Background(), search, keepAlive)
+}
+
+// ScanContext is like Scan but every request of the iteration is bound to ctx.
+func (idx *Index) ScanContext(ctx context.Context, search *SearchRequest, keepAlive time.Duration) *Scroller {
+	return newScroller(ctx, idx.Client.REST, idx.Name, "", search, keepAlive)
+}
+
+// SearchAfter iterates over every hit of a search on an index by paginating
+// with search_after. If keepAlive is positive the pages are read from a point
+// in time which is kept alive for keepAlive between pages. Hits are ordered
+// by the sort of the search, which should be unique per document. Without a
+// point in time the search must be sorted, last by a field unique per
+// document such as an ID stored in its source, or the cursor fails.
+func (idx *Index) SearchAfter(search *SearchRequest, keepAlive time.Duration) *Cursor {
+	return idx.SearchAfterContext(context.Background(), search, keepAlive)
+}
+
+// SearchAfterContext is like SearchAfter but every request of the iteration is bound to ctx.
+func (idx *Index) SearchAfterContext(ctx context.Context, search *SearchRequest, keepAlive time.Duration) *Cursor {
+	return newCursor(ctx, idx.Client.REST, idx.Name, "", search, keepAlive)
 }
 
 // Delete an index.
 func (idx *Index) Drop() error {
-	return idx.Client.REST.deleteIndex(idx.Name)
+	return idx.DropContext(context.Background())
 }
 
-//
+// DropContext is like Drop but the request is bound to ctx.
+func (idx *Index) DropContext(ctx context.Context) error {
+	return idx.Client.REST.deleteIndex(ctx, idx.Name)
+}
+
+// Perform a search on a given index-type described by an SQL statement.
 func (t *Type) SearchSQL(sql string) ([][]byte, error) {
-	return t.Index.Client.REST.searchSQL(t.Index.Name, t.Name, sql)
+	return t.SearchSQLContext(context.Background(), sql)
+}
+
+// SearchSQLContext is like SearchSQL but the request is bound to ctx.
+func (t *Type) SearchSQLContext(ctx context.Context, sql string) ([][]byte, error) {
+	return t.Index.Client.REST.searchSQL(ctx, t.Index.Name, t.Name, sql)
+}
+
+// ExecuteSQL is like SearchSQL but returns the full search response including
+// the aggregations produced by GROUP BY clauses and aggregate functions.
+func (t *Type) ExecuteSQL(sql string) (*SearchResponse, error) {
+	return t.ExecuteSQLContext(context.Background(), sql)
+}
+
+// ExecuteSQLContext is like ExecuteSQL but the request is bound to ctx.
+func (t *Type) ExecuteSQLContext(ctx context.Context, sql string) (*SearchResponse, error) {
+	return t.Index.Client.REST.executeSQL(ctx, t.Index.Name, t.Name, sql)
 }
 
 // Perform a basic elasticsearch on a given index-type that will return
 // exact string matches on the passed querystring.
 func (t *Type) Search(querystring string) ([][]byte, error) {
-	return t.Index.Client.REST.searchType(t.Index.Name, t.Name, querystring)
+	return t.SearchContext(context.Background(), querystring)
+}
+
+// SearchContext is like Search but the request is bound to ctx.
+func (t *Type) SearchContext(ctx context.Context, querystring string) ([][]byte, error) {
+	return t.Index.Client.REST.searchType(ctx, t.Index.Name, t.Name, querystring)
+}
+
+// Query performs a search on a given index-type described by a query DSL builder.
+func (t *Type) Query(q query.Query) ([][]byte, error) {
+	return t.QueryContext(context.Background(), q)
+}
+
+// QueryContext is like Query but the request is bound to ctx.
+func (t *Type) QueryContext(ctx context.Context, q query.Query) ([][]byte, error) {
+	return t.Index.Client.REST.query(ctx, t.Index.Name, t.Name, q)
+}
+
+// Execute performs a search on a given index-type described by a SearchRequest
+// and returns the hits alongside the metadata of the response.
+func (t *Type) Execute(search *SearchRequest) (*SearchResponse, error) {
+	return t.ExecuteContext(context.Background(), search)
+}
+
+// ExecuteContext is like Execute but the request is bound to ctx.
+func (t *Type) ExecuteContext(ctx context.Context, search *SearchRequest) (*SearchResponse, error) {
+	return t.Index.Client.REST.search(ctx, t.Index.Name, t.Name, search, nil)
+}
+
+// Scan iterates over every hit of a search on a given index-type using the
+// scroll API, keeping the scroll alive for keepAlive between pages.
+func (t *Type) Scan(search *SearchRequest, keepAlive time.Duration) *Scroller {
+	return t.ScanContext(context.Background(), search, keepAlive)
+}
+
+// ScanContext is like Scan but every request of the iteration is bound to ctx.
+func (t *Type) ScanContext(ctx context.Context, search *SearchRequest, keepAlive time.Duration) *Scroller {
+	return newScroller(ctx, t.Index.Client.REST, t.Index.Name, t.Name, search, keepAlive)
+}
+
+// SearchAfter iterates over every hit of a search on a given index-type by
+// paginating with search_after. Points in time span whole indices, use
+// Index.SearchAfter to read consistent pages from one. The search must be
+// sorted, last by a field unique per document, see Index.SearchAfter.
+func (t *Type) SearchAfter(search *SearchRequest) *Cursor {
+	return t.SearchAfterContext(context.Background(), search)
+}
+
+// SearchAfterContext is like SearchAfter but every request of the iteration is bound to ctx.
+func (t *Type) SearchAfterContext(ctx context.Context, search *SearchRequest) *Cursor {
+	return newCursor(ctx, t.Index.Client.REST, t.Index.Name, t.Name, search, 0)
 }
 
 // Insert a document into a given type namespace
 func (t *Type) Insert(doc []byte) (string, error) {
-	return t.Index.Client.REST.insertDocument(t.Index.Name, t.Name, doc)
+	return t.InsertContext(context.Background(), doc)
 }
 
-// Insert multiple documents into a given type namespace, not all documents may be inserted
-// an error will be returned if any of the operations fail
-func (t *Type) BulkInsert(docs [][]byte) ([]string, error) {
-	return t.Index.Client.REST.bulkInsertDocuments(t.Index.Name, t.Name, docs)
+// InsertContext is like Insert but the request is bound to ctx.
+func (t *Type) InsertContext(ctx context.Context, doc []byte) (string, error) {
+	return t.Index.Client.REST.insertDocument(ctx, t.Index.Name, t.Name, doc)
+}
+
+// Insert multiple documents into a given type namespace, not all documents may be inserted.
+// The response lists the result of every document in order, an error is only returned
+// if the request itself failed.
+func (t *Type) BulkInsert(docs [][]byte) (*BulkResponse, error) {
+	return t.BulkInsertContext(context.Background(), docs)
+}
+
+// BulkInsertContext is like BulkInsert but the request is bound to ctx.
+func (t *Type) BulkInsertContext(ctx context.Context, docs [][]byte) (*BulkResponse, error) {
+	return t.Index.Client.REST.bulkInsertDocuments(ctx, t.Index.Name, t.Name, docs)
 }
 
 // Find multiple documents in a given type namespace that match
 // key:value pairs in the passed queryString.
 func (t *Type) Find(querystring string) ([][]byte, error) {
-	return t.Index.Client.REST.searchType(t.Index.Name, t.Name, querystring)
+	return t.FindContext(context.Background(), querystring)
+}
+
+// FindContext is like Find but the request is bound to ctx.
+func (t *Type) FindContext(ctx context.Context, querystring string) ([][]byte, error) {
+	return t.Index.Client.REST.searchType(ctx, t.Index.Name, t.Name, querystring)
 }
 
 // Return a single document by its ID. If the document is not found
 // it will return an error.
 func (t *Type) FindById(ID string) ([]byte, error) {
-	return t.Index.Client.REST.getDocument(t.Index.Name, t.Name, ID)
+	return t.FindByIdContext(context.Background(), ID)
+}
+
+// FindByIdContext is like FindById but the request is bound to ctx.
+func (t *Type) FindByIdContext(ctx context.Context, ID string) ([]byte, error) {
+	return t.Index.Client.REST.getDocument(ctx, t.Index.Name, t.Name, ID)
+}
+
+// FindByIdVersioned is like FindById but also returns the version of the
+// document, which may be passed to IfMatch to update it only if it was not
+// changed since.
+func (t *Type) FindByIdVersioned(ID string) (*VersionedDocument, error) {
+	return t.FindByIdVersionedContext(context.Background(), ID)
+}
+
+// FindByIdVersionedContext is like FindByIdVersioned but the request is bound to ctx.
+func (t *Type) FindByIdVersionedContext(ctx context.Context, ID string) (*VersionedDocument, error) {
+	return t.Index.Client.REST.getVersionedDocument(ctx, t.Index.Name, t.Name, ID)
 }
 
 // Update a document by its ID. If it is not found it will return an error.
 func (t *Type) UpdateById(ID string, doc []byte) error {
-	return t.Index.Client.REST.updateDocument(t.Index.Name, t.Name, ID, doc)
+	return t.UpdateByIdContext(context.Background(), ID, doc)
+}
+
+// UpdateByIdContext is like UpdateById but the request is bound to ctx.
+func (t *Type) UpdateByIdContext(ctx context.Context, ID string, doc []byte) error {
+	return t.Index.Client.REST.updateDocument(ctx, t.Index.Name, t.Name, ID, doc)
+}
+
+// UpdateByIdIf is like UpdateById but the document is only updated if the
+// precondition holds, otherwise a *VersionConflictError is returned. Returns
+// the version of the updated document.
+func (t *Type) UpdateByIdIf(ID string, doc []byte, precondition *Precondition) (DocumentVersion, error) {
+	return t.UpdateByIdIfContext(context.Background(), ID, doc, precondition)
+}
+
+// UpdateByIdIfContext is like UpdateByIdIf but the request is bound to ctx.
+func (t *Type) UpdateByIdIfContext(ctx context.Context, ID string, doc []byte, precondition *Precondition) (DocumentVersion, error) {
+	return t.Index.Client.REST.updateDocumentIf(ctx, t.Index.Name, t.Name, ID, doc, precondition)
+}
 
+// Update multiple documents of a given type namespace, not all updates may be completed.
+// The response lists the result of every update in order, an error is only returned
+// if the request itself failed.
+func (t *Type) BulkUpdate(docs []*mock.GenericDocument) (*BulkResponse, error) {
+	return t.BulkUpdateContext(context.Background(), docs)
 }
 
-// Insert multiple documents into a given type namespace, not all updates may be completed
-// an error will be returned if any of the operations fail
-func (t *Type) BulkUpdate(docs []*mock.GenericDocument) ([]string, error) {
-	return t.Index.Client.REST.bulkUpdateDocuments(t.Index.Name, t.Name, docs)
+// BulkUpdateContext is like BulkUpdate but the request is bound to ctx.
+func (t *Type) BulkUpdateContext(ctx context.Context, docs []*mock.GenericDocument) (*BulkResponse, error) {
+	return t.Index.Client.REST.bulkUpdateDocuments(ctx, t.Index.Name, t.Name, docs)
 }
 
 // Delete a document by its ID. If it is not found it will return an error.
 func (t *Type) DeleteById(ID string) error {
-	return t.Index.Client.REST.deleteDocument(t.Index.Name, t.Name, ID)
+	return t.DeleteByIdContext(context.Background(), ID)
+}
+
+// DeleteByIdContext is like DeleteById but the request is bound to ctx.
+func (t *Type) DeleteByIdContext(ctx context.Context, ID string) error {
+	return t.Index.Client.REST.deleteDocument(ctx, t.Index.Name, t.Name, ID)
+}
+
+// DeleteByIdIf is like DeleteById but the document is only deleted if the
+// precondition holds, otherwise a *VersionConflictError is returned.
+func (t *Type) DeleteByIdIf(ID string, precondition *Precondition) error {
+	return t.DeleteByIdIfContext(context.Background(), ID, precondition)
+}
+
+// DeleteByIdIfContext is like DeleteByIdIf but the request is bound to ctx.
+func (t *Type) DeleteByIdIfContext(ctx context.Context, ID string, precondition *Precondition) error {
+	return t.Index.Client.REST.deleteDocumentIf(ctx, t.Index.Name, t.Name, ID, precondition)
+}
+
+// delete a list of documents, not all documents may be deleted. The response lists
+// the result of every deletion in order, an error is only returned if the request
+// itself failed.
+func (t *Type) BulkDelete(IDs ...string) (*BulkResponse, error) {
+	return t.BulkDeleteContext(context.Background(), IDs...)
 }
 
-// delete a list of documents, not all documents may be deleted
-// an error will be returned if any of the operations fail
-func (t *Type) BulkDelete(IDs ...string) ([]string, error) {
-	return t.Index.Client.REST.bulkDeleteDocuments(t.Index.Name, t.Name, IDs)
+// BulkDeleteContext is like BulkDelete but the request is bound to ctx.
+func (t *Type) BulkDeleteContext(ctx context.Context, IDs ...string) (*BulkResponse, error) {
+	return t.Index.Client.REST.bulkDeleteDocuments(ctx, t.Index.Name, t.Name, IDs)
 }
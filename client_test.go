@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"github.com/b3ntly/elasticsearch"
 	"github.com/b3ntly/elasticsearch/mock"
+	"github.com/b3ntly/elasticsearch/query"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"log"
@@ -95,11 +96,12 @@ func TestClient(t *testing.T) {
 					inputs[i] = doc
 				}
 
-				docs, err := collection.BulkInsert(inputs)
+				response, err := collection.BulkInsert(inputs)
 				require.Nil(t, err)
-				require.Equal(t, bulkOperations, len(docs))
+				require.Equal(t, bulkOperations, len(response.Succeeded()))
+				require.Empty(t, response.Failed())
 
-				for _, ID := range docs {
+				for _, ID := range response.IDs() {
 					require.NotEqual(t, "", ID)
 				}
 
@@ -189,8 +191,9 @@ func TestClient(t *testing.T) {
 					inputs[i] = doc
 				}
 
-				IDs, err := collection.BulkInsert(inputs)
+				inserted, err := collection.BulkInsert(inputs)
 				require.Nil(t, err)
+				IDs := inserted.IDs()
 				require.Equal(t, bulkOperations, len(IDs))
 
 				for _, ID := range IDs {
@@ -211,12 +214,22 @@ func TestClient(t *testing.T) {
 				updated, err := collection.BulkUpdate(updates)
 
 				require.Nil(t, err)
-				require.Equal(t, bulkOperations, len(updated))
+				require.Equal(t, bulkOperations, len(updated.Succeeded()))
 
-				for _, ID := range updated {
-					require.NotEqual(t, "", ID)
+				for i, item := range updated.Items {
+					require.Equal(t, IDs[i], item.ID)
+					require.Equal(t, "update", item.Action)
 				}
 
+				// updating a document which does not exist is reported per item
+				missing, err := collection.BulkUpdate([]*mock.GenericDocument{{ID: "hunter2", Body: updatedBody}, updates[0]})
+				require.Nil(t, err)
+				require.True(t, missing.Errors)
+				require.Len(t, missing.Failed(), 1)
+				require.Equal(t, "hunter2", missing.Failed()[0].ID)
+				require.True(t, elasticsearch.IsNotFound(missing.Failed()[0].Error))
+				require.Equal(t, IDs[0], missing.Succeeded()[0].ID)
+
 				clean(client)
 			})
 		})
@@ -240,6 +253,7 @@ func TestClient(t *testing.T) {
 
 				// FindById will return an error because the document was deleted
 				require.Error(t, err)
+				require.True(t, elasticsearch.IsNotFound(err))
 				clean(client)
 			})
 		})
@@ -257,8 +271,9 @@ func TestClient(t *testing.T) {
 				inputs[i] = doc
 			}
 
-			IDs, err := collection.BulkInsert(inputs)
+			inserted, err := collection.BulkInsert(inputs)
 			require.Nil(t, err)
+			IDs := inserted.IDs()
 			require.Equal(t, bulkOperations, len(IDs))
 
 			for _, ID := range IDs {
@@ -267,7 +282,11 @@ func TestClient(t *testing.T) {
 
 			deleted, err := collection.BulkDelete(IDs...)
 			require.Nil(t, err)
-			require.Equal(t, len(IDs), len(deleted))
+			require.Equal(t, len(IDs), len(deleted.Succeeded()))
+
+			for _, item := range deleted.Items {
+				require.Equal(t, "deleted", item.Result)
+			}
 
 			// verify none of the deleted ids still exist
 			// todo: bulk findById would be good here
@@ -281,7 +300,7 @@ func TestClient(t *testing.T) {
 			require.Nil(t, err)
 
 			for _, finalDoc := range finalDocs {
-				for _, id := range deleted {
+				for _, id := range deleted.IDs() {
 					doc := &mock.GenericDocument{}
 					err := json.Unmarshal(finalDoc, doc)
 					require.Nil(t, err)
@@ -313,6 +332,7 @@ func TestClient(t *testing.T) {
 				collection := mockClient.I("hunter2")
 				_, err := collection.Search("*:*")
 				require.NotNil(t, err)
+				require.True(t, elasticsearch.IsIndexNotFound(err))
 				clean(client)
 			})
 		})
@@ -372,7 +392,7 @@ func TestClient(t *testing.T) {
 
 				docs, err := collection.BulkInsert(inputs)
 				require.Nil(t, err)
-				require.Equal(t, bulkOperations, len(docs))
+				require.Equal(t, bulkOperations, len(docs.Items))
 
 				sql := `SELECT Message FROM test WHERE Message = 'eureka' LIMIT 14`
 				results, err := collection.SearchSQL(sql)
@@ -382,3 +402,63 @@ func TestClient(t *testing.T) {
 		})
 	}
 }
+
+// Integration tests which rely on the mock server only
+func TestQuery(t *testing.T) {
+	client, err := getClient("http://127.0.0.1:9201")
+	require.Nil(t, err)
+
+	collection := client.I(testIndex).T(testType)
+	body, err := json.Marshal(sampleDocument)
+	require.Nil(t, err)
+	_, err = collection.Insert(body)
+	require.Nil(t, err)
+
+	t.Run("Type.Query returns matching documents", func(t *testing.T) {
+		docs, err := collection.Query(query.Bool().Must(query.Match("message", testMessage)))
+		require.Nil(t, err)
+		assert.NotEqual(t, 0, len(docs))
+	})
+
+	t.Run("Index.Query returns matching documents", func(t *testing.T) {
+		docs, err := client.I(testIndex).Query(query.Term("message", testMessage))
+		require.Nil(t, err)
+		assert.NotEqual(t, 0, len(docs))
+	})
+
+	clean(client)
+}
+
+func TestExecute(t *testing.T) {
+	client, err := getClient("http://127.0.0.1:9201")
+	require.Nil(t, err)
+
+	collection := client.I(testIndex).T(testType)
+	for _, doc := range []string{`{"rank": 2, "message": "b"}`, `{"rank": 3, "message": "c"}`, `{"rank": 1, "message": "a"}`} {
+		_, err = collection.Insert([]byte(doc))
+		require.Nil(t, err)
+	}
+
+	t.Run("Returns a sorted page of hits alongside the total", func(t *testing.T) {
+		search := elasticsearch.NewSearchRequest().
+			Sort(elasticsearch.NewSort("rank").Desc()).
+			Size(2).
+			Includes("rank")
+
+		response, err := collection.Execute(search)
+		require.Nil(t, err)
+		require.Equal(t, 3, response.TotalHits)
+		require.Len(t, response.Hits, 2)
+		require.JSONEq(t, `{"rank": 3}`, string(response.Hits[0].Source))
+		require.JSONEq(t, `{"rank": 2}`, string(response.Hits[1].Source))
+	})
+
+	t.Run("Index.Execute pages through hits with From", func(t *testing.T) {
+		response, err := client.I(testIndex).Execute(elasticsearch.NewSearchRequest().Sort(elasticsearch.NewSort("rank")).From(2))
+		require.Nil(t, err)
+		require.Equal(t, 3, response.TotalHits)
+		require.Len(t, response.Documents(), 1)
+	})
+
+	clean(client)
+}
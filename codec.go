@@ -0,0 +1,47 @@
+package elasticsearch
+
+import (
+	"encoding/json"
+	"io"
+)
+
+type (
+	// Codec encodes request bodies and decodes response bodies. Set
+	// Options.Codec to replace encoding/json with a faster implementation,
+	// which must produce and accept the same JSON. Aggregation results are
+	// always decoded with encoding/json by the aggs package.
+	Codec interface {
+		Marshal(v interface{}) ([]byte, error)
+		Unmarshal(data []byte, v interface{}) error
+		// NewDecoder reads a stream of JSON values from r
+		NewDecoder(r io.Reader) Decoder
+	}
+
+	// Decoder reads JSON values from a stream, as *json.Decoder does
+	Decoder interface {
+		// Decode reads the next value into v
+		Decode(v interface{}) error
+		// Token returns the next token, delimiters of objects and arrays
+		// are returned as json.Delim
+		Token() (json.Token, error)
+		// More reports whether the current object or array has another element
+		More() bool
+	}
+
+	jsonCodec struct{}
+)
+
+// DefaultCodec is the Codec used unless Options.Codec is set, it is backed by encoding/json.
+var DefaultCodec Codec = jsonCodec{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) NewDecoder(r io.Reader) Decoder {
+	return json.NewDecoder(r)
+}
@@ -0,0 +1,108 @@
+package elasticsearch_test
+
+import (
+	"github.com/b3ntly/elasticsearch"
+	"github.com/b3ntly/elasticsearch/query"
+	"github.com/stretchr/testify/require"
+	"io"
+	"sync"
+	"testing"
+)
+
+// counts the calls to the default codec
+type countingCodec struct {
+	mu        sync.Mutex
+	marshal   int
+	unmarshal int
+}
+
+func (c *countingCodec) reset() {
+	c.mu.Lock()
+	c.marshal, c.unmarshal = 0, 0
+	c.mu.Unlock()
+}
+
+func (c *countingCodec) Marshal(v interface{}) ([]byte, error) {
+	c.mu.Lock()
+	c.marshal++
+	c.mu.Unlock()
+	return elasticsearch.DefaultCodec.Marshal(v)
+}
+
+func (c *countingCodec) Unmarshal(data []byte, v interface{}) error {
+	c.mu.Lock()
+	c.unmarshal++
+	c.mu.Unlock()
+	return elasticsearch.DefaultCodec.Unmarshal(data, v)
+}
+
+func (c *countingCodec) NewDecoder(r io.Reader) elasticsearch.Decoder {
+	return elasticsearch.DefaultCodec.NewDecoder(r)
+}
+
+func TestCodec(t *testing.T) {
+	codec := &countingCodec{}
+	client, err := elasticsearch.New(&elasticsearch.Options{URI: "http://127.0.0.1:9201", Codec: codec})
+	require.Nil(t, err)
+
+	_ = client.I("codec").Drop()
+	defer client.I("codec").Drop()
+
+	t.Run("Decodes every response with the configured codec", func(t *testing.T) {
+		codec.reset()
+		collection := client.I("codec").T("codec")
+
+		ID, err := collection.Insert([]byte(`{"message": "hello"}`))
+		require.Nil(t, err)
+		require.Equal(t, 1, codec.unmarshal)
+
+		response, err := collection.BulkInsert([][]byte{[]byte(`{"message": "a"}`), []byte(`{"message": "b"}`)})
+		require.Nil(t, err)
+		require.Len(t, response.IDs(), 2)
+		require.Equal(t, 2, codec.unmarshal)
+
+		_, err = collection.FindById(ID)
+		require.Nil(t, err)
+		require.Equal(t, 3, codec.unmarshal)
+
+		// errors are decoded with the codec as well
+		_, err = collection.FindById("missing")
+		require.True(t, elasticsearch.IsNotFound(err))
+		require.Equal(t, 4, codec.unmarshal)
+	})
+
+	t.Run("Encodes request bodies with the configured codec", func(t *testing.T) {
+		codec.reset()
+
+		_, err := client.I("codec").Execute(elasticsearch.NewSearchRequest().Size(1))
+		require.Nil(t, err)
+		require.Equal(t, 1, codec.marshal)
+
+		_, err = client.I("codec").Query(query.MatchAll())
+		require.Nil(t, err)
+		require.Equal(t, 2, codec.marshal)
+
+		type message struct {
+			Message string `json:"message"`
+		}
+
+		_, err = elasticsearch.Typed[message](client.I("codec").T("codec")).Insert(message{Message: "typed"})
+		require.Nil(t, err)
+		require.Equal(t, 3, codec.marshal)
+
+		// the metadata line and payload of bulk actions
+		_, err = client.I("codec").T("codec").Bulk().Add(
+			elasticsearch.NewBulkIndexRequest([]byte(`{"message": "bulk"}`)),
+			elasticsearch.NewBulkUpsertRequest("upserted", []byte(`{"message": "bulk"}`)),
+		).Execute()
+
+		require.Nil(t, err)
+		require.Equal(t, 6, codec.marshal)
+	})
+
+	t.Run("Defaults to encoding/json", func(t *testing.T) {
+		options := &elasticsearch.Options{}
+		require.Nil(t, options.Init())
+		require.Equal(t, elasticsearch.DefaultCodec, options.Codec)
+	})
+}
@@ -1,32 +1,108 @@
 package elasticsearch
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/b3ntly/elasticsearch/aggs"
 	"github.com/b3ntly/elasticsearch/mock"
 	"github.com/b3ntly/insertjson"
+	"sort"
+	"strings"
 )
 
-func errorResponseToError(HTTPResponseBody []byte) error {
-	response := &mock.Generic{}
-	err := json.Unmarshal(HTTPResponseBody, response)
+// errorResponseToError converts the body of a non 2xx response into an
+// *ElasticsearchError. Bodies which are not elasticsearch errors (e.g. from a
+// proxy in front of the cluster) are preserved as the error's reason.
+func errorResponseToError(codec Codec, status int, HTTPResponseBody []byte) error {
+	esErr := &ElasticsearchError{Status: status}
+	response := &mock.ErrorResponse{}
 
-	if err != nil {
-		return err
+	if err := codec.Unmarshal(HTTPResponseBody, response); err != nil {
+		esErr.Reason = strings.TrimSpace(string(HTTPResponseBody))
+		return esErr
 	}
 
-	reason := ""
-	for _, r := range response.Error.RootCause {
-		reason += "," + r.Reason
+	if response.Error == nil {
+		return esErr
 	}
 
-	return errors.New(reason)
+	return asVersionConflict(toElasticsearchError(status, response.Error))
 }
 
-func indexResponseToDocument(HTTPResponseBody []byte) (string, error) {
-	response := &mock.Generic{}
-	err := json.Unmarshal(HTTPResponseBody, response)
+func toElasticsearchError(status int, description *mock.ElasticsearchError) *ElasticsearchError {
+	esErr := &ElasticsearchError{
+		Status:   status,
+		Type:     description.Type,
+		Reason:   description.Reason,
+		Index:    description.Index,
+		CausedBy: toErrorCause(description.CausedBy),
+	}
+
+	for i := range description.RootCause {
+		esErr.RootCause = append(esErr.RootCause, toErrorCause(&description.RootCause[i]))
+	}
+
+	for _, failure := range description.FailedShards {
+		esErr.ShardFailures = append(esErr.ShardFailures, &ShardFailure{
+			Shard:  failure.Shard,
+			Index:  failure.Index,
+			Node:   failure.Node,
+			Reason: toErrorCause(failure.Reason),
+		})
+	}
+
+	return esErr
+}
+
+func toErrorCause(description *mock.ErrorDescription) *ErrorCause {
+	if description == nil {
+		return nil
+	}
+
+	return &ErrorCause{
+		Type:     description.Type,
+		Reason:   description.Reason,
+		Index:    description.Index,
+		CausedBy: toErrorCause(description.CausedBy),
+	}
+}
+
+type (
+	// the fields of an index response the client reads
+	indexResult struct {
+		ID      string `json:"_id"`
+		Created bool   `json:"created"`
+	}
+
+	// the fields of a bulk response the client reads. Decoding into
+	// mock.Generic would allocate all of its fields for every item.
+	bulkResult struct {
+		Took   int  `json:"took"`
+		Errors bool `json:"errors"`
+		Items  []struct {
+			Index  *bulkItemResult `json:"index"`
+			Create *bulkItemResult `json:"create"`
+			Update *bulkItemResult `json:"update"`
+			Delete *bulkItemResult `json:"delete"`
+		} `json:"items"`
+	}
+
+	bulkItemResult struct {
+		Index   string                   `json:"_index"`
+		Type    string                   `json:"_type"`
+		ID      string                   `json:"_id"`
+		Status  int                      `json:"status"`
+		Version int64                    `json:"_version"`
+		Result  string                   `json:"result"`
+		Error   *mock.ElasticsearchError `json:"error"`
+	}
+)
+
+func indexResponseToDocument(codec Codec, HTTPResponseBody []byte) (string, error) {
+	response := &indexResult{}
+	err := codec.Unmarshal(HTTPResponseBody, response)
 
 	if err != nil {
 		return "", err
@@ -39,10 +115,10 @@ func indexResponseToDocument(HTTPResponseBody []byte) (string, error) {
 	return response.ID, err
 }
 
-func deleteIndexResponseToDocument(HTTPResponseBody []byte) error {
+func deleteIndexResponseToDocument(codec Codec, HTTPResponseBody []byte) error {
 	response := &mock.Generic{}
 
-	err := json.Unmarshal(HTTPResponseBody, response)
+	err := codec.Unmarshal(HTTPResponseBody, response)
 	if err != nil {
 		return err
 	}
@@ -54,10 +130,10 @@ func deleteIndexResponseToDocument(HTTPResponseBody []byte) error {
 	return nil
 }
 
-func getDocumentResponseToDocument(HTTPResponseBody []byte) ([]byte, error) {
+func getDocumentResponseToDocument(codec Codec, HTTPResponseBody []byte) ([]byte, error) {
 	//fmt.Println(string(HTTPResponseBody))
 	response := &mock.Generic{}
-	err := json.Unmarshal(HTTPResponseBody, response)
+	err := codec.Unmarshal(HTTPResponseBody, response)
 
 	if err != nil {
 		return nil, err
@@ -67,12 +143,12 @@ func getDocumentResponseToDocument(HTTPResponseBody []byte) ([]byte, error) {
 		return nil, errors.New(fmt.Sprintf("Failed to get document with id: %v", response.ID))
 	}
 
-	return insertjson.Property("_id", response.ID, response.Source), err
+	return withID(response.ID, response.Source), err
 }
 
-func searchResponseToDocument(HTTPResponseBody []byte) ([][]byte, error) {
+func searchResponseToDocument(codec Codec, HTTPResponseBody []byte) ([][]byte, error) {
 	response := &mock.Generic{}
-	err := json.Unmarshal(HTTPResponseBody, response)
+	err := codec.Unmarshal(HTTPResponseBody, response)
 
 	if err != nil {
 		return nil, err
@@ -80,15 +156,84 @@ func searchResponseToDocument(HTTPResponseBody []byte) ([][]byte, error) {
 
 	documents := make([][]byte, len(response.Hits.Hits))
 	for i, val := range response.Hits.Hits {
-		documents[i] = insertjson.Property("_id", val.ID, val.Source)
+		documents[i] = withID(val.ID, val.Source)
 	}
 
 	return documents, err
 }
 
-func deleteDocumentResponseToDocument(HTTPResponseBody []byte) error {
+func searchResponseToResponse(codec Codec, HTTPResponseBody []byte) (*SearchResponse, error) {
 	response := &mock.Generic{}
-	err := json.Unmarshal(HTTPResponseBody, response)
+	err := codec.Unmarshal(HTTPResponseBody, response)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return toSearchResponse(response), nil
+}
+
+func toSearchResponse(response *mock.Generic) *SearchResponse {
+	result := &SearchResponse{
+		Took:              response.Took,
+		TimedOut:          response.TimedOut,
+		TotalHits:         response.Hits.Total.Value,
+		TotalHitsRelation: response.Hits.Total.Relation,
+		MaxScore:          response.Hits.MaxScore,
+		Hits:              make([]*SearchHit, len(response.Hits.Hits)),
+		Aggregations:      aggs.Results(response.Aggregations),
+		ScrollID:          response.ScrollID,
+		PitID:             response.PitID,
+	}
+
+	for i, hit := range response.Hits.Hits {
+		result.Hits[i] = toSearchHit(hit)
+	}
+
+	return result
+}
+
+func toSearchHit(hit *mock.SearchHit) *SearchHit {
+	return &SearchHit{
+		Index:  hit.Index,
+		Type:   hit.Type,
+		ID:     hit.ID,
+		Score:  hit.Score,
+		Source: hit.Source,
+		Fields: hit.Fields,
+		Sort:   hit.Sort,
+	}
+}
+
+func pointInTimeResponseToID(codec Codec, HTTPResponseBody []byte) (string, error) {
+	response := &mock.PointInTime{}
+	err := codec.Unmarshal(HTTPResponseBody, response)
+
+	if err != nil {
+		return "", err
+	}
+
+	if response.ID == "" {
+		return "", errors.New("Failed to open point in time.")
+	}
+
+	return response.ID, nil
+}
+
+// inject the _id of a document into its source, sources which were not
+// fetched or are empty objects are replaced with an object holding only the _id
+func withID(ID string, source []byte) []byte {
+	if len(bytes.TrimSpace(source)) == 0 || bytes.Equal(bytes.Join(bytes.Fields(source), nil), []byte("{}")) {
+		js, _ := json.Marshal(map[string]string{"_id": ID})
+		return js
+	}
+
+	return insertjson.Property("_id", ID, source)
+}
+
+func deleteDocumentResponseToDocument(codec Codec, HTTPResponseBody []byte) error {
+	response := &mock.Generic{}
+	err := codec.Unmarshal(HTTPResponseBody, response)
 
 	if err != nil {
 		return err
@@ -101,74 +246,180 @@ func deleteDocumentResponseToDocument(HTTPResponseBody []byte) error {
 	return nil
 }
 
-func updateDocumentResponseToDocument(HTTPResponseBody []byte) error {
+func updateDocumentResponseToDocument(codec Codec, HTTPResponseBody []byte) error {
+	_, err := updateDocumentResponseToVersion(codec, HTTPResponseBody)
+	return err
+}
+
+func updateDocumentResponseToVersion(codec Codec, HTTPResponseBody []byte) (DocumentVersion, error) {
 	response := &mock.Generic{}
-	err := json.Unmarshal(HTTPResponseBody, response)
+	err := codec.Unmarshal(HTTPResponseBody, response)
 
 	if err != nil {
-		return errors.New("Failed to unmarshal response")
+		return DocumentVersion{}, errors.New("Failed to unmarshal response")
 	}
 
 	if response.Created == true {
-		return errors.New("Accidentally upserted document...")
+		return DocumentVersion{}, errors.New("Accidentally upserted document...")
 	}
 
-	return nil
+	return responseToVersion(response), nil
 }
 
-func bulkInsertResponseToIDs(HTTPResponseBody []byte) ([]string, error) {
+func updateResponseToResult(codec Codec, HTTPResponseBody []byte) (*UpdateResult, error) {
 	response := &mock.Generic{}
-	err := json.Unmarshal(HTTPResponseBody, response)
+	err := codec.Unmarshal(HTTPResponseBody, response)
 
 	if err != nil {
 		return nil, err
 	}
 
-	inserted := make([]string, len(response.Items))
-	for idx, item := range response.Items {
-		if item.Index.Created == false {
-			err = errors.New("Some documents were not inserted.")
-		}
-		inserted[idx] = item.Index.ID
+	return &UpdateResult{ID: response.ID, Result: response.Result, Version: responseToVersion(response)}, nil
+}
+
+func getDocumentResponseToVersionedDocument(codec Codec, HTTPResponseBody []byte) (*VersionedDocument, error) {
+	response, err := getDocumentResponseToResponse(codec, HTTPResponseBody)
+
+	if err != nil {
+		return nil, err
 	}
 
-	return inserted, err
+	return &VersionedDocument{
+		ID:      response.ID,
+		Source:  withID(response.ID, response.Source),
+		Version: responseToVersion(response),
+	}, nil
 }
 
-func bulkUpdateResponseToIDs(HTTPResponseBody []byte) ([]string, error) {
+// decode a response of the Document API, keeping the source as it was stored
+func getDocumentResponseToResponse(codec Codec, HTTPResponseBody []byte) (*mock.Generic, error) {
 	response := &mock.Generic{}
-	err := json.Unmarshal(HTTPResponseBody, response)
+	err := codec.Unmarshal(HTTPResponseBody, response)
 
 	if err != nil {
 		return nil, err
 	}
 
-	updated := make([]string, len(response.Items))
+	if response.Found == false {
+		return nil, errors.New(fmt.Sprintf("Failed to get document with id: %v", response.ID))
+	}
+
+	return response, nil
+}
+
+func responseToVersion(response *mock.Generic) DocumentVersion {
+	version := DocumentVersion{PrimaryTerm: response.PrimaryTerm, Version: response.Version}
 
-	for idx, item := range response.Items {
-		updated[idx] = item.Update.ID
+	if response.SeqNo != nil {
+		version.SeqNo = *response.SeqNo
 	}
 
-	return updated, err
+	return version
 }
 
-func bulkDeleteResponseToIDs(HTTPResponseBody []byte) ([]string, error) {
-	//fmt.Println(string(HTTPResponseBody))
-	response := &mock.Generic{}
-	err := json.Unmarshal(HTTPResponseBody, response)
+func bulkResponseToResponse(codec Codec, HTTPResponseBody []byte) (*BulkResponse, error) {
+	response := &bulkResult{}
+	err := codec.Unmarshal(HTTPResponseBody, response)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BulkResponse{Took: response.Took, Errors: response.Errors, Items: make([]*BulkResponseItem, len(response.Items))}
+
+	for idx, operation := range response.Items {
+		action, item := "index", operation.Index
+
+		switch {
+		case operation.Create != nil:
+			action, item = "create", operation.Create
+		case operation.Update != nil:
+			action, item = "update", operation.Update
+		case operation.Delete != nil:
+			action, item = "delete", operation.Delete
+		case item == nil:
+			return nil, errors.New("Unknown bulk action in response.")
+		}
+
+		result.Items[idx] = &BulkResponseItem{
+			Action:  action,
+			Index:   item.Index,
+			Type:    item.Type,
+			ID:      item.ID,
+			Status:  item.Status,
+			Version: item.Version,
+			Result:  item.Result,
+		}
+
+		if item.Error != nil && item.Error.Type != "" {
+			result.Items[idx].Error = toElasticsearchError(item.Status, item.Error)
+		}
+	}
+
+	return result, nil
+}
+
+func indexMetadataResponseToMetadata(codec Codec, HTTPResponseBody []byte, index string, field string) ([]byte, error) {
+	response := map[string]map[string]json.RawMessage{}
+	err := codec.Unmarshal(HTTPResponseBody, &response)
 
 	if err != nil {
 		return nil, err
 	}
 
-	deleted := make([]string, len(response.Items))
+	metadata, ok := response[index][field]
+
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("The response does not contain the %v of index %v.", field, index))
+	}
 
-	for idx, item := range response.Items {
-		if item.Delete.Found == false {
-			err = errors.New("Some documents were not found and thus not deleted.")
+	return metadata, nil
+}
+
+// the parameters found at the root of a mapping which is not keyed by type
+var mappingParameters = map[string]bool{
+	"properties":        true,
+	"dynamic":           true,
+	"dynamic_templates": true,
+	"date_detection":    true,
+	"numeric_detection": true,
+	"enabled":           true,
+	"runtime":           true,
+	"_source":           true,
+	"_routing":          true,
+	"_meta":             true,
+	"_all":              true,
+	"_field_names":      true,
+	"_size":             true,
+}
+
+// unwrap the mapping of the single type of an index from the mappings
+// reported by clusters before 7.0, {"<type>": {"properties": {...}}}.
+// Mappings which are not keyed by type are returned as is.
+func typeMappingsToMapping(codec Codec, mappings []byte) ([]byte, error) {
+	keyed := map[string]json.RawMessage{}
+
+	if err := codec.Unmarshal(mappings, &keyed); err != nil {
+		return nil, err
+	}
+
+	types := []string{}
+
+	for key := range keyed {
+		if mappingParameters[key] {
+			return mappings, nil
 		}
-		deleted[idx] = item.Delete.ID
+
+		types = append(types, key)
+	}
+
+	switch len(types) {
+	case 0:
+		return mappings, nil
+	case 1:
+		return keyed[types[0]], nil
 	}
 
-	return deleted, err
+	sort.Strings(types)
+	return nil, fmt.Errorf("The index has mappings for several types: %v.", strings.Join(types, ", "))
 }
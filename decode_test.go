@@ -17,48 +17,89 @@ func Test_decodingError(t *testing.T) {
 
 	t.Run("Will return error if it cannot unmarshal to mock.Generic", func(t *testing.T) {
 		// run it against all decoding functions
-		_, err := indexResponseToDocument(malformedJSON)
+		_, err := indexResponseToDocument(DefaultCodec, malformedJSON)
 		require.Error(t, err)
 
-		require.Error(t, deleteDocumentResponseToDocument(malformedJSON))
+		require.Error(t, deleteDocumentResponseToDocument(DefaultCodec, malformedJSON))
 
-		_, err = getDocumentResponseToDocument(malformedJSON)
+		_, err = getDocumentResponseToDocument(DefaultCodec, malformedJSON)
 		require.Error(t, err)
 
-		_, err = searchResponseToDocument(malformedJSON)
+		_, err = searchResponseToDocument(DefaultCodec, malformedJSON)
 		require.Error(t, err)
 
-		require.Error(t, deleteDocumentResponseToDocument(malformedJSON))
+		require.Error(t, deleteDocumentResponseToDocument(DefaultCodec, malformedJSON))
 
-		require.Error(t, updateDocumentResponseToDocument(malformedJSON))
+		require.Error(t, updateDocumentResponseToDocument(DefaultCodec, malformedJSON))
 
-		_, err = bulkInsertResponseToIDs(malformedJSON)
-		require.Error(t, err)
-
-		_, err = bulkUpdateResponseToIDs(malformedJSON)
-		require.Error(t, err)
-
-		_, err = bulkDeleteResponseToIDs(malformedJSON)
+		_, err = bulkResponseToResponse(DefaultCodec, malformedJSON)
 		require.Error(t, err)
 	})
 
 	t.Run("Will return error if state requirement is not met", func(t *testing.T) {
-		_, err := indexResponseToDocument(errorJSON)
+		_, err := indexResponseToDocument(DefaultCodec, errorJSON)
 		require.Error(t, err)
 
-		require.Error(t, deleteDocumentResponseToDocument(errorJSON))
+		require.Error(t, deleteDocumentResponseToDocument(DefaultCodec, errorJSON))
 
-		_, err = getDocumentResponseToDocument(errorJSON)
+		_, err = getDocumentResponseToDocument(DefaultCodec, errorJSON)
 		require.Error(t, err)
 
-		require.Error(t, deleteIndexResponseToDocument(errorJSON))
+		require.Error(t, deleteIndexResponseToDocument(DefaultCodec, errorJSON))
 
-		require.Error(t, updateDocumentResponseToDocument(errorJSON))
+		require.Error(t, updateDocumentResponseToDocument(DefaultCodec, errorJSON))
 
-		_, err = bulkInsertResponseToIDs(bulkErrorJSON)
+		_, err = bulkResponseToResponse(DefaultCodec, bulkErrorJSON)
 		require.Error(t, err)
+	})
+}
+
+func Test_bulkResponseToResponse(t *testing.T) {
+	body := []byte(`{"took": 3, "errors": true, "items": [
+		{"index": {"_index": "test", "_type": "test", "_id": "1", "_version": 1, "result": "created", "status": 201}},
+		{"create": {"_index": "test", "_type": "test", "_id": "2", "status": 409, "error": {"type": "version_conflict_engine_exception", "reason": "[2]: version conflict, document already exists"}}},
+		{"delete": {"_index": "test", "_type": "test", "_id": "3", "result": "not_found", "status": 404}}
+	]}`)
+
+	response, err := bulkResponseToResponse(DefaultCodec, body)
+	require.Nil(t, err)
+	require.Equal(t, 3, response.Took)
+	require.True(t, response.Errors)
+	require.Equal(t, []string{"1", "2", "3"}, response.IDs())
+
+	require.Equal(t, &BulkResponseItem{Action: "index", Index: "test", Type: "test", ID: "1", Status: 201, Version: 1, Result: "created"}, response.Items[0])
+
+	// deleting a missing document is not a failure
+	require.Equal(t, []*BulkResponseItem{response.Items[0], response.Items[2]}, response.Succeeded())
+	require.Equal(t, []*BulkResponseItem{response.Items[1]}, response.Failed())
+
+	failure := response.Failed()[0].Error
+	require.Equal(t, "create", response.Failed()[0].Action)
+	require.True(t, IsVersionConflict(failure))
+	require.Equal(t, "elasticsearch: 409 version_conflict_engine_exception: [2]: version conflict, document already exists", failure.Error())
+}
+
+func Test_typeMappingsToMapping(t *testing.T) {
+	t.Run("Unwraps the mapping of the single type of an index", func(t *testing.T) {
+		mapping, err := typeMappingsToMapping(DefaultCodec, []byte(`{"user": {"properties": {"name": {"type": "keyword"}}}}`))
+		require.Nil(t, err)
+		require.JSONEq(t, `{"properties": {"name": {"type": "keyword"}}}`, string(mapping))
+
+		mapping, err = typeMappingsToMapping(DefaultCodec, []byte(`{"_doc": {"dynamic": "strict", "properties": {}}}`))
+		require.Nil(t, err)
+		require.JSONEq(t, `{"dynamic": "strict", "properties": {}}`, string(mapping))
+	})
+
+	t.Run("Returns mappings which are not keyed by type as is", func(t *testing.T) {
+		for _, typeless := range []string{`{}`, `{"properties": {"name": {"type": "keyword"}}}`, `{"_source": {"enabled": false}}`} {
+			mapping, err := typeMappingsToMapping(DefaultCodec, []byte(typeless))
+			require.Nil(t, err)
+			require.JSONEq(t, typeless, string(mapping))
+		}
+	})
 
-		_, err = bulkDeleteResponseToIDs(bulkErrorJSON)
+	t.Run("Fails on indices with several types", func(t *testing.T) {
+		_, err := typeMappingsToMapping(DefaultCodec, []byte(`{"user": {"properties": {}}, "post": {"properties": {}}}`))
 		require.Error(t, err)
 	})
 }
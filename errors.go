@@ -0,0 +1,136 @@
+package elasticsearch
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+type (
+	// ElasticsearchError is returned whenever elasticsearch answers a request
+	// with a non 2xx status code. Use errors.As to inspect it or one of the
+	// Is* helpers to branch on common failures.
+	ElasticsearchError struct {
+		// HTTP status code of the response
+		Status int
+		// elasticsearch exception type, e.g. index_not_found_exception
+		Type   string
+		Reason string
+		// the index the error refers to, if any
+		Index         string
+		RootCause     []*ErrorCause
+		CausedBy      *ErrorCause
+		ShardFailures []*ShardFailure
+	}
+
+	// ErrorCause is a single link in the chain of causes elasticsearch reports
+	ErrorCause struct {
+		Type     string
+		Reason   string
+		Index    string
+		CausedBy *ErrorCause
+	}
+
+	// ShardFailure describes why an individual shard failed to execute a request
+	ShardFailure struct {
+		Shard  int
+		Index  string
+		Node   string
+		Reason *ErrorCause
+	}
+)
+
+// Error implements the error interface.
+func (e *ElasticsearchError) Error() string {
+	reason := e.Reason
+
+	if reason == "" && len(e.RootCause) > 0 {
+		reason = e.RootCause[0].Reason
+	}
+
+	if reason == "" {
+		reason = http.StatusText(e.Status)
+	}
+
+	if e.Type == "" {
+		return fmt.Sprintf("elasticsearch: %d: %s", e.Status, reason)
+	}
+
+	return fmt.Sprintf("elasticsearch: %d %s: %s", e.Status, e.Type, reason)
+}
+
+// hasType reports whether the error or any of its causes is of the
+// given elasticsearch exception type.
+func (e *ElasticsearchError) hasType(errorType string) bool {
+	if e.Type == errorType {
+		return true
+	}
+
+	for _, cause := range e.RootCause {
+		if cause.Type == errorType {
+			return true
+		}
+	}
+
+	for cause := e.CausedBy; cause != nil; cause = cause.CausedBy {
+		if cause.Type == errorType {
+			return true
+		}
+	}
+
+	return false
+}
+
+func asElasticsearchError(err error) (*ElasticsearchError, bool) {
+	var esErr *ElasticsearchError
+
+	if errors.As(err, &esErr) {
+		return esErr, true
+	}
+
+	return nil, false
+}
+
+// IsNotFound reports whether err is an elasticsearch 404, which is returned
+// for missing documents as well as missing indices.
+func IsNotFound(err error) bool {
+	esErr, ok := asElasticsearchError(err)
+	return ok && esErr.Status == http.StatusNotFound
+}
+
+// IsIndexNotFound reports whether err was caused by a reference to an index
+// which does not exist.
+func IsIndexNotFound(err error) bool {
+	esErr, ok := asElasticsearchError(err)
+	return ok && esErr.hasType("index_not_found_exception")
+}
+
+// IsVersionConflict reports whether err was caused by a write that conflicted
+// with the current version of a document.
+func IsVersionConflict(err error) bool {
+	esErr, ok := asElasticsearchError(err)
+	return ok && (esErr.Status == http.StatusConflict || esErr.hasType("version_conflict_engine_exception"))
+}
+
+// IsRetryable reports whether err represents a transient condition on the
+// server side after which the same request may succeed.
+func IsRetryable(err error) bool {
+	esErr, ok := asElasticsearchError(err)
+
+	if !ok {
+		return false
+	}
+
+	switch esErr.Status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return esErr.hasType("es_rejected_execution_exception")
+}
+
+// IsUnauthorized reports whether err was caused by missing or rejected credentials.
+func IsUnauthorized(err error) bool {
+	esErr, ok := asElasticsearchError(err)
+	return ok && esErr.Status == http.StatusUnauthorized
+}
@@ -0,0 +1,75 @@
+package elasticsearch
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func Test_errorResponseToError(t *testing.T) {
+	t.Run("Will decode the full error model", func(t *testing.T) {
+		body := []byte(`{
+			"error": {
+				"root_cause": [{"type": "index_not_found_exception", "reason": "no such index", "index": "hunter2"}],
+				"type": "search_phase_execution_exception",
+				"reason": "all shards failed",
+				"index": "hunter2",
+				"caused_by": {"type": "illegal_argument_exception", "reason": "bad", "caused_by": {"type": "number_format_exception", "reason": "NaN"}},
+				"failed_shards": [{"shard": 0, "index": "hunter2", "node": "n1", "reason": {"type": "query_shard_exception", "reason": "failed"}}]
+			},
+			"status": 400
+		}`)
+
+		err := errorResponseToError(DefaultCodec, 400, body)
+
+		var esErr *ElasticsearchError
+		require.True(t, errors.As(err, &esErr))
+		require.Equal(t, 400, esErr.Status)
+		require.Equal(t, "search_phase_execution_exception", esErr.Type)
+		require.Equal(t, "all shards failed", esErr.Reason)
+		require.Equal(t, "hunter2", esErr.Index)
+		require.Len(t, esErr.RootCause, 1)
+		require.Equal(t, "number_format_exception", esErr.CausedBy.CausedBy.Type)
+		require.Len(t, esErr.ShardFailures, 1)
+		require.Equal(t, "query_shard_exception", esErr.ShardFailures[0].Reason.Type)
+		require.True(t, IsIndexNotFound(err))
+		require.False(t, IsNotFound(err))
+	})
+
+	t.Run("Will preserve bodies which are not elasticsearch errors", func(t *testing.T) {
+		err := errorResponseToError(DefaultCodec, 502, []byte("Bad Gateway\n"))
+
+		var esErr *ElasticsearchError
+		require.True(t, errors.As(err, &esErr))
+		require.Equal(t, "Bad Gateway", esErr.Reason)
+		require.True(t, IsRetryable(err))
+	})
+
+	t.Run("Will return version conflicts as a *VersionConflictError", func(t *testing.T) {
+		err := errorResponseToError(DefaultCodec, 409, []byte(`{"error": {"type": "version_conflict_engine_exception", "reason": "[1]: version conflict, required seqNo [0]"}, "status": 409}`))
+
+		var conflict *VersionConflictError
+		require.True(t, errors.As(err, &conflict))
+		require.Equal(t, "1", conflict.ID)
+		require.True(t, IsVersionConflict(err))
+		require.Equal(t, "elasticsearch: 409 version_conflict_engine_exception: [1]: version conflict, required seqNo [0]", err.Error())
+	})
+
+	t.Run("Will report a missing document as not found", func(t *testing.T) {
+		err := errorResponseToError(DefaultCodec, 404, []byte(`{"_index": "test", "_id": "1", "found": false}`))
+		require.True(t, IsNotFound(err))
+		require.False(t, IsIndexNotFound(err))
+	})
+}
+
+func Test_errorHelpers(t *testing.T) {
+	conflict := fmt.Errorf("wrapped: %w", &ElasticsearchError{Status: 409, Type: "version_conflict_engine_exception"})
+	rejected := &ElasticsearchError{Status: 429, Type: "es_rejected_execution_exception"}
+
+	require.True(t, IsVersionConflict(conflict))
+	require.False(t, IsRetryable(conflict))
+	require.True(t, IsRetryable(rejected))
+	require.False(t, IsNotFound(errors.New("plain")))
+	require.Equal(t, "elasticsearch: 429 es_rejected_execution_exception: Too Many Requests", rejected.Error())
+}
@@ -0,0 +1,56 @@
+package elasticsearch_test
+
+import (
+	"github.com/b3ntly/elasticsearch"
+	"github.com/b3ntly/elasticsearch/mock"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGzip(t *testing.T) {
+	t.Run("Request bodies are compressed and responses decompressed", func(t *testing.T) {
+		var encoding, accepted string
+		var body []byte
+
+		decoded := mock.Gzip(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			body, _ = ioutil.ReadAll(req.Body)
+			w.Write([]byte(`{"took": 1, "items": [{"index": {"_id": "1", "status": 201}}]}`))
+		}))
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			encoding, accepted = req.Header.Get("Content-Encoding"), req.Header.Get("Accept-Encoding")
+			decoded.ServeHTTP(w, req)
+		}))
+		defer server.Close()
+
+		client, err := elasticsearch.New(&elasticsearch.Options{URI: server.URL, Gzip: true})
+		require.Nil(t, err)
+
+		response, err := client.I("test").T("test").BulkInsert([][]byte{[]byte(`{"message":"hello"}`)})
+		require.Nil(t, err)
+		require.Equal(t, []string{"1"}, response.IDs())
+
+		require.Equal(t, "gzip", encoding)
+		require.Equal(t, "gzip", accepted)
+		require.Equal(t, "{\"index\":{\"_index\":\"test\",\"_type\":\"test\"}}\n{\"message\":\"hello\"}\n", string(body))
+	})
+
+	t.Run("The mock server decodes compressed requests", func(t *testing.T) {
+		client, err := elasticsearch.New(&elasticsearch.Options{URI: "http://127.0.0.1:9201", Gzip: true})
+		require.Nil(t, err)
+
+		_ = client.I("gzip").Drop()
+		defer client.I("gzip").Drop()
+
+		response, err := client.I("gzip").T("gzip").BulkInsert([][]byte{[]byte(`{"message":"hello"}`), []byte(`{"message":"world"}`)})
+		require.Nil(t, err)
+		require.Len(t, response.Succeeded(), 2)
+
+		doc, err := client.I("gzip").T("gzip").FindById(response.IDs()[0])
+		require.Nil(t, err)
+		require.Contains(t, string(doc), "hello")
+	})
+}
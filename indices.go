@@ -0,0 +1,208 @@
+package elasticsearch
+
+import (
+	"context"
+	"github.com/b3ntly/elasticsearch/mock"
+	"strconv"
+)
+
+// Create creates the index with the given settings and mappings, either of
+// which may be nil. Fails if the index already exists.
+func (idx *Index) Create(settings []byte, mappings []byte) error {
+	return idx.CreateContext(context.Background(), settings, mappings)
+}
+
+// CreateContext is like Create but the request is bound to ctx.
+func (idx *Index) CreateContext(ctx context.Context, settings []byte, mappings []byte) error {
+	return idx.Client.REST.createIndex(ctx, idx.Name, settings, mappings)
+}
+
+// Exists reports whether the index exists.
+func (idx *Index) Exists() (bool, error) {
+	return idx.ExistsContext(context.Background())
+}
+
+// ExistsContext is like Exists but the request is bound to ctx.
+func (idx *Index) ExistsContext(ctx context.Context) (bool, error) {
+	return idx.Client.REST.indexExists(ctx, idx.Name)
+}
+
+// Close closes the index, it keeps its data but can no longer be read from
+// or written to until it is opened again.
+func (idx *Index) Close() error {
+	return idx.CloseContext(context.Background())
+}
+
+// CloseContext is like Close but the request is bound to ctx.
+func (idx *Index) CloseContext(ctx context.Context) error {
+	return idx.Client.REST.indexAction(ctx, "POST", idx.Name, "_close", nil, nil)
+}
+
+// Open opens a closed index.
+func (idx *Index) Open() error {
+	return idx.OpenContext(context.Background())
+}
+
+// OpenContext is like Open but the request is bound to ctx.
+func (idx *Index) OpenContext(ctx context.Context) error {
+	return idx.Client.REST.indexAction(ctx, "POST", idx.Name, "_open", nil, nil)
+}
+
+// GetSettings returns the settings of the index, as elasticsearch reports
+// them every value is a string: {"index": {"number_of_shards": "1", ...}}
+func (idx *Index) GetSettings() ([]byte, error) {
+	return idx.GetSettingsContext(context.Background())
+}
+
+// GetSettingsContext is like GetSettings but the request is bound to ctx.
+func (idx *Index) GetSettingsContext(ctx context.Context) ([]byte, error) {
+	return idx.Client.REST.indexMetadata(ctx, idx.Name, "_settings", "settings")
+}
+
+// PutSettings updates the settings of the index. Static settings such as the
+// number of shards may only be changed while the index is closed.
+func (idx *Index) PutSettings(settings []byte) error {
+	return idx.PutSettingsContext(context.Background(), settings)
+}
+
+// PutSettingsContext is like PutSettings but the request is bound to ctx.
+func (idx *Index) PutSettingsContext(ctx context.Context, settings []byte) error {
+	return idx.Client.REST.indexAction(ctx, "PUT", idx.Name, "_settings", settings, nil)
+}
+
+// GetMapping returns the mappings of the index: {"properties": {...}}. The
+// mappings of clusters before 7.0 are keyed by type, the mapping of the only
+// type of the index is returned from those. Indices with several types are
+// reported as an error.
+func (idx *Index) GetMapping() ([]byte, error) {
+	return idx.GetMappingContext(context.Background())
+}
+
+// GetMappingContext is like GetMapping but the request is bound to ctx.
+func (idx *Index) GetMappingContext(ctx context.Context) ([]byte, error) {
+	return idx.Client.REST.getMapping(ctx, idx.Name)
+}
+
+// PutMapping adds fields to the mappings of the index. The type of an
+// existing field cannot be changed.
+func (idx *Index) PutMapping(mapping []byte) error {
+	return idx.PutMappingContext(context.Background(), mapping)
+}
+
+// PutMappingContext is like PutMapping but the request is bound to ctx.
+func (idx *Index) PutMappingContext(ctx context.Context, mapping []byte) error {
+	return idx.Client.REST.indexAction(ctx, "PUT", idx.Name, "_mapping", mapping, nil)
+}
+
+// Refresh makes every change to the index visible to searches.
+func (idx *Index) Refresh() error {
+	return idx.RefreshContext(context.Background())
+}
+
+// RefreshContext is like Refresh but the request is bound to ctx.
+func (idx *Index) RefreshContext(ctx context.Context) error {
+	return idx.Client.REST.indexAction(ctx, "POST", idx.Name, "_refresh", nil, nil)
+}
+
+// Flush persists every change to the index to disk.
+func (idx *Index) Flush() error {
+	return idx.FlushContext(context.Background())
+}
+
+// FlushContext is like Flush but the request is bound to ctx.
+func (idx *Index) FlushContext(ctx context.Context) error {
+	return idx.Client.REST.indexAction(ctx, "POST", idx.Name, "_flush", nil, nil)
+}
+
+// ForceMerge merges the segments of the index down to maxNumSegments, or as
+// far as elasticsearch sees fit if maxNumSegments is 0.
+func (idx *Index) ForceMerge(maxNumSegments int) error {
+	return idx.ForceMergeContext(context.Background(), maxNumSegments)
+}
+
+// ForceMergeContext is like ForceMerge but the request is bound to ctx.
+func (idx *Index) ForceMergeContext(ctx context.Context, maxNumSegments int) error {
+	var queryMap map[string]string
+
+	if maxNumSegments > 0 {
+		queryMap = map[string]string{"max_num_segments": strconv.Itoa(maxNumSegments)}
+	}
+
+	return idx.Client.REST.indexAction(ctx, "POST", idx.Name, "_forcemerge", nil, queryMap)
+}
+
+// Call the elasticsearch Create Index API
+func (r *rest) createIndex(ctx context.Context, index string, settings []byte, mappings []byte) error {
+	URL, err := buildURI(r.BaseURI, map[string]string{"index": index}, nil)
+
+	if err != nil {
+		return err
+	}
+
+	payload, err := r.codec.Marshal(&mock.CreateIndexRequest{Settings: settings, Mappings: mappings})
+
+	if err != nil {
+		return err
+	}
+
+	_, err = r.request(ctx, "PUT", URL, payload)
+	return err
+}
+
+// Call the elasticsearch Index Exists API
+func (r *rest) indexExists(ctx context.Context, index string) (bool, error) {
+	URL, err := buildURI(r.BaseURI, map[string]string{"index": index}, nil)
+
+	if err != nil {
+		return false, err
+	}
+
+	_, err = r.request(ctx, "HEAD", URL, nil)
+
+	if IsNotFound(err) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// Call an elasticsearch index API such as /{index}/_close, ignoring its response
+func (r *rest) indexAction(ctx context.Context, method string, index string, action string, body []byte, queryMap map[string]string) error {
+	URL, err := buildURI(r.BaseURI, map[string]string{"index": index, "suffix": action}, queryMap)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = r.request(ctx, method, URL, body)
+	return err
+}
+
+// Call the elasticsearch Get Mapping API, unwrapping the mapping of the type of the index
+func (r *rest) getMapping(ctx context.Context, index string) ([]byte, error) {
+	mappings, err := r.indexMetadata(ctx, index, "_mapping", "mappings")
+
+	if err != nil {
+		return nil, err
+	}
+
+	return typeMappingsToMapping(r.codec, mappings)
+}
+
+// Call an elasticsearch API returning metadata of an index such as its
+// settings, which are reported as {"index": {"settings": {...}}}
+func (r *rest) indexMetadata(ctx context.Context, index string, action string, field string) ([]byte, error) {
+	URL, err := buildURI(r.BaseURI, map[string]string{"index": index, "suffix": action}, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := r.request(ctx, "GET", URL, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return indexMetadataResponseToMetadata(r.codec, body, index, field)
+}
@@ -0,0 +1,109 @@
+package elasticsearch_test
+
+import (
+	"github.com/b3ntly/elasticsearch"
+	"github.com/b3ntly/elasticsearch/mapping"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIndices(t *testing.T) {
+	client, err := elasticsearch.New(&elasticsearch.Options{URI: "http://127.0.0.1:9201"})
+	require.Nil(t, err)
+
+	index := client.I("indices")
+	_ = index.Drop()
+	defer index.Drop()
+
+	t.Run("Create creates an index once", func(t *testing.T) {
+		exists, err := index.Exists()
+		require.Nil(t, err)
+		require.False(t, exists)
+
+		err = index.Create([]byte(`{"number_of_shards": 2}`), []byte(`{"properties": {"name": {"type": "keyword"}}}`))
+		require.Nil(t, err)
+
+		exists, err = index.Exists()
+		require.Nil(t, err)
+		require.True(t, exists)
+
+		err = index.Create(nil, nil)
+		require.Error(t, err)
+	})
+
+	t.Run("Settings are reported as strings below index", func(t *testing.T) {
+		settings, err := index.GetSettings()
+		require.Nil(t, err)
+		require.JSONEq(t, `{"index": {"number_of_shards": "2", "number_of_replicas": "1"}}`, string(settings))
+
+		require.Nil(t, index.PutSettings([]byte(`{"index": {"number_of_replicas": 0}}`)))
+
+		settings, err = index.GetSettings()
+		require.Nil(t, err)
+		require.JSONEq(t, `{"index": {"number_of_shards": "2", "number_of_replicas": "0"}}`, string(settings))
+
+		// static settings can only be changed while the index is closed
+		require.Error(t, index.PutSettings([]byte(`{"index.number_of_shards": 3}`)))
+	})
+
+	t.Run("Mappings may gain fields but not change their type", func(t *testing.T) {
+		require.Nil(t, index.PutMapping([]byte(`{"properties": {"age": {"type": "integer"}}}`)))
+
+		mapping, err := index.GetMapping()
+		require.Nil(t, err)
+		require.JSONEq(t, `{"properties": {"name": {"type": "keyword"}, "age": {"type": "integer"}}}`, string(mapping))
+
+		require.Error(t, index.PutMapping([]byte(`{"properties": {"age": {"type": "text"}}}`)))
+	})
+
+	t.Run("Closed indices reject reads and writes until they are opened", func(t *testing.T) {
+		collection := index.T("indices")
+		_, err := collection.Replace("1", []byte(`{"name": "a"}`))
+		require.Nil(t, err)
+
+		require.Nil(t, index.Close())
+
+		_, err = collection.FindById("1")
+		require.Error(t, err)
+		require.False(t, elasticsearch.IsNotFound(err))
+
+		_, err = collection.Insert([]byte(`{"name": "b"}`))
+		require.Error(t, err)
+
+		require.Error(t, index.Refresh())
+
+		require.Nil(t, index.Open())
+
+		_, err = collection.FindById("1")
+		require.Nil(t, err)
+	})
+
+	t.Run("Refresh, Flush and ForceMerge", func(t *testing.T) {
+		require.Nil(t, index.Refresh())
+		require.Nil(t, index.Flush())
+		require.Nil(t, index.ForceMerge(1))
+		require.Nil(t, index.ForceMerge(0))
+
+		require.True(t, elasticsearch.IsNotFound(client.I("indices-missing").Refresh()))
+	})
+}
+
+func TestIndex_GetMapping(t *testing.T) {
+	// clusters before 7.0 key the mappings of an index by type
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`{"users": {"mappings": {"user": {"properties": {"name": {"type": "keyword"}}}}}}`))
+	}))
+	defer server.Close()
+
+	client, err := elasticsearch.New(&elasticsearch.Options{URI: server.URL})
+	require.Nil(t, err)
+
+	data, err := client.I("users").GetMapping()
+	require.Nil(t, err)
+
+	live, err := mapping.Parse(data)
+	require.Nil(t, err)
+	require.Equal(t, "keyword", live.Properties["name"].Type)
+}
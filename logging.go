@@ -0,0 +1,151 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Logger receives a record of every request sent to a node. Arguments are
+// alternating keys and values, as accepted by *slog.Logger which implements it.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+const redacted = "[REDACTED]"
+
+// RedactFields returns an Options.Redact function which replaces the values
+// of the given fields of JSON and NDJSON bodies, at any depth.
+func RedactFields(fields ...string) func(body []byte) []byte {
+	names := map[string]bool{}
+
+	for _, field := range fields {
+		names[field] = true
+	}
+
+	return func(body []byte) []byte {
+		lines := bytes.Split(body, []byte("\n"))
+
+		for i, line := range lines {
+			var value interface{}
+
+			if json.Unmarshal(line, &value) != nil {
+				continue
+			}
+
+			if js, err := json.Marshal(redact(value, names)); err == nil {
+				lines[i] = js
+			}
+		}
+
+		return bytes.Join(lines, []byte("\n"))
+	}
+}
+
+func redact(value interface{}, names map[string]bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if names[key] {
+				v[key] = redacted
+			} else {
+				v[key] = redact(child, names)
+			}
+		}
+	case []interface{}:
+		for i, child := range v {
+			v[i] = redact(child, names)
+		}
+	}
+
+	return value
+}
+
+// whether a request is a search whose duration is compared against the slow query threshold
+func isSearch(req *http.Request) bool {
+	return strings.HasSuffix(req.URL.Path, "/_search") || strings.HasSuffix(req.URL.Path, "/_search/scroll")
+}
+
+// the innermost middleware, logs every request as it is sent
+func (r *rest) logged(next RoundTripFunc) RoundTripFunc {
+	return func(req *http.Request) (*Response, error) {
+		start := time.Now()
+		response, err := next(req)
+		duration := time.Since(start)
+
+		args := []interface{}{"method", req.Method, "node", req.URL.Host, "path", req.URL.Path, "duration", duration}
+
+		if response != nil {
+			args = append(args, "status", response.StatusCode)
+		}
+
+		if r.logBodies {
+			args = append(args, "body", string(r.redactBody(requestBody(req))))
+
+			if response != nil {
+				args = append(args, "response", string(r.redactBody(response.Body)))
+			}
+		}
+
+		switch {
+		case err != nil:
+			r.logger.Error("elasticsearch request failed", append(args, "error", err.Error())...)
+		case response.StatusCode >= 299:
+			r.logger.Warn("elasticsearch request failed", args...)
+		default:
+			r.logger.Debug("elasticsearch request", args...)
+		}
+
+		if r.slowQuery > 0 && duration >= r.slowQuery && isSearch(req) {
+			r.logger.Warn("elasticsearch slow query", "method", req.Method, "node", req.URL.Host, "path", req.URL.Path,
+				"duration", duration, "query", string(r.redactBody(requestBody(req))))
+		}
+
+		return response, err
+	}
+}
+
+func (r *rest) redactBody(body []byte) []byte {
+	if r.redact == nil || len(body) == 0 {
+		return body
+	}
+
+	return r.redact(body)
+}
+
+// a copy of the body of req, decompressed if it was gzipped
+func requestBody(req *http.Request) []byte {
+	if req.GetBody == nil {
+		return nil
+	}
+
+	body, err := req.GetBody()
+
+	if err != nil {
+		return nil
+	}
+
+	defer body.Close()
+
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		decompressed, err := gzip.NewReader(body)
+
+		if err != nil {
+			return nil
+		}
+
+		defer decompressed.Close()
+		contents, _ := ioutil.ReadAll(decompressed)
+		return contents
+	}
+
+	contents, _ := ioutil.ReadAll(body)
+	return contents
+}
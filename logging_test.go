@@ -0,0 +1,107 @@
+package elasticsearch_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/b3ntly/elasticsearch"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogging(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if strings.HasSuffix(req.URL.Path, "/_search") {
+			time.Sleep(20 * time.Millisecond)
+			w.Write([]byte(`{"hits": {"total": 0, "hits": []}}`))
+			return
+		}
+
+		if req.Method == "DELETE" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+
+		w.Write([]byte(`{"_id": "1", "created": true}`))
+	}))
+	defer server.Close()
+
+	// decoded records of a JSON slog handler
+	newClient := func(t *testing.T, options *elasticsearch.Options) (*elasticsearch.Client, func() []map[string]interface{}) {
+		buffer := new(bytes.Buffer)
+		options.URI = server.URL
+		options.Logger = slog.New(slog.NewJSONHandler(buffer, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+		client, err := elasticsearch.New(options)
+		require.Nil(t, err)
+
+		return client, func() []map[string]interface{} {
+			records := []map[string]interface{}{}
+			decoder := json.NewDecoder(buffer)
+
+			for decoder.More() {
+				record := map[string]interface{}{}
+				require.Nil(t, decoder.Decode(&record))
+				records = append(records, record)
+			}
+
+			return records
+		}
+	}
+
+	t.Run("Every request is logged with its method, path, status and duration", func(t *testing.T) {
+		client, records := newClient(t, &elasticsearch.Options{})
+
+		_, err := client.I("test").T("test").Insert([]byte(`{"message": "hello"}`))
+		require.Nil(t, err)
+		require.True(t, elasticsearch.IsNotFound(client.I("test").Drop()))
+
+		logged := records()
+		require.Len(t, logged, 2)
+
+		require.Equal(t, "DEBUG", logged[0]["level"])
+		require.Equal(t, "POST", logged[0]["method"])
+		require.Equal(t, "/test/test", logged[0]["path"])
+		require.Equal(t, float64(200), logged[0]["status"])
+		require.Contains(t, logged[0], "duration")
+		require.NotContains(t, logged[0], "body")
+
+		require.Equal(t, "WARN", logged[1]["level"])
+		require.Equal(t, float64(404), logged[1]["status"])
+	})
+
+	t.Run("Bodies are logged on request and redacted", func(t *testing.T) {
+		client, records := newClient(t, &elasticsearch.Options{LogBodies: true, Redact: elasticsearch.RedactFields("password")})
+
+		_, err := client.I("test").T("test").Insert([]byte(`{"user": {"name": "elastic", "password": "changeme"}}`))
+		require.Nil(t, err)
+
+		logged := records()
+		require.Len(t, logged, 1)
+		require.Equal(t, `{"user":{"name":"elastic","password":"[REDACTED]"}}`, logged[0]["body"])
+		require.Equal(t, `{"_id":"1","created":true}`, logged[0]["response"])
+	})
+
+	t.Run("Searches exceeding the threshold are logged with their query", func(t *testing.T) {
+		client, records := newClient(t, &elasticsearch.Options{SlowQueryThreshold: 10 * time.Millisecond})
+
+		_, err := client.I("test").Execute(elasticsearch.NewSearchRequest().Size(1))
+		require.Nil(t, err)
+
+		logged := records()
+		require.Len(t, logged, 2)
+		require.Equal(t, "WARN", logged[1]["level"])
+		require.Equal(t, "elasticsearch slow query", logged[1]["msg"])
+		require.Equal(t, `{"size":1}`, logged[1]["query"])
+
+		// requests other than searches are never slow queries
+		client, records = newClient(t, &elasticsearch.Options{SlowQueryThreshold: time.Nanosecond})
+
+		_, err = client.I("test").T("test").Insert([]byte(`{}`))
+		require.Nil(t, err)
+		require.Len(t, records(), 1)
+	})
+}
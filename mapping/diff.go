@@ -0,0 +1,108 @@
+package mapping
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+type (
+	// Difference describes a field whose mapping differs between two mappings
+	Difference struct {
+		// the dotted path of the field, e.g. comments.author
+		Path string
+		// the mapping of the field on either side, nil where it is missing
+		Expected *Field
+		Actual   *Field
+	}
+)
+
+func (d Difference) String() string {
+	switch {
+	case d.Actual == nil:
+		return fmt.Sprintf("%v is missing", d.Path)
+	case d.Expected == nil:
+		return fmt.Sprintf("%v is not expected", d.Path)
+	}
+
+	expected, _ := json.Marshal(d.Expected.withoutProperties())
+	actual, _ := json.Marshal(d.Actual.withoutProperties())
+	return fmt.Sprintf("%v is mapped as %s instead of %s", d.Path, actual, expected)
+}
+
+// Diff compares the expected mapping, e.g. derived by For, with the actual
+// mapping of an index and returns the fields which differ, sorted by path.
+// Parameters elasticsearch adds to the actual mapping are ignored unless
+// they are set on the expected mapping, as is the type object.
+func Diff(expected *Mapping, actual *Mapping) []Difference {
+	return diffProperties("", expected.Properties, actual.Properties)
+}
+
+func diffProperties(prefix string, expected map[string]*Field, actual map[string]*Field) []Difference {
+	differences := []Difference{}
+	all := map[string]*Field{}
+
+	for name, field := range expected {
+		all[name] = field
+	}
+
+	for name, field := range actual {
+		all[name] = field
+	}
+
+	for _, name := range names(all) {
+		path := prefix + name
+		e, a := expected[name], actual[name]
+
+		if e == nil || a == nil || !e.matches(a) {
+			differences = append(differences, Difference{Path: path, Expected: e, Actual: a})
+			continue
+		}
+
+		differences = append(differences, diffProperties(path+".", e.Properties, a.Properties)...)
+	}
+
+	return differences
+}
+
+// whether the field is mapped as f, ignoring its properties
+func (f *Field) matches(actual *Field) bool {
+	if typeOf(f) != typeOf(actual) {
+		return false
+	}
+
+	for key, value := range f.Params {
+		if !reflect.DeepEqual(normalize(value), normalize(actual.Params[key])) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// fields with properties are objects whether or not the type is given
+func typeOf(f *Field) string {
+	if f.Type == "" && f.Properties != nil {
+		return "object"
+	}
+
+	return f.Type
+}
+
+// parameters decoded from JSON and parameters parsed from tags are compared
+// in their JSON form, so that the number 1 equals 1.0
+func normalize(value interface{}) interface{} {
+	js, err := json.Marshal(value)
+
+	if err != nil {
+		return value
+	}
+
+	var normalized interface{}
+	json.Unmarshal(js, &normalized)
+	return normalized
+}
+
+func (f *Field) withoutProperties() *Field {
+	return &Field{Type: f.Type, Params: f.Params}
+}
@@ -0,0 +1,416 @@
+// Package mapping derives elasticsearch index mappings from Go structs. The
+// type of every field is inferred from its Go type and may be overridden with
+// an es struct tag:
+//
+//	type User struct {
+//		Name     string    `json:"name" es:"type=keyword"`
+//		Bio      string    `json:"bio" es:"analyzer=english"`
+//		Password string    `json:"password" es:"index=false"`
+//		Tags     []string  `json:"tags" es:"type=keyword"`
+//		Created  time.Time `json:"created" es:"format=strict_date_optional_time"`
+//		Comments []Comment `json:"comments" es:"type=nested"`
+//		Internal string    `json:"internal" es:"-"`
+//	}
+//
+// Fields are named after their json tag and skipped where encoding/json
+// skips them, as is the ID field tagged es:"_id" or named _id. The resulting
+// mapping may be passed to Index.Create or Index.PutMapping and compared with
+// the live mapping of an index by Diff.
+package mapping
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// Mapping is the mapping of an index: {"properties": {...}}
+	Mapping struct {
+		Properties map[string]*Field
+	}
+
+	// Field is the mapping of a single field. Object fields have no Type but
+	// Properties of their own.
+	Field struct {
+		Type       string
+		Properties map[string]*Field
+		// any other parameter of the field such as analyzer, index or format
+		Params map[string]interface{}
+	}
+)
+
+var (
+	errNotStruct = errors.New("Mappings can only be derived from structs.")
+	timeType     = reflect.TypeOf(time.Time{})
+	rawType      = reflect.TypeOf(json.RawMessage{})
+)
+
+// For derives the mapping of the struct, or pointer to struct, v.
+func For(v interface{}) (*Mapping, error) {
+	t := reflect.TypeOf(v)
+
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, errNotStruct
+	}
+
+	properties, err := structProperties(t, map[reflect.Type]bool{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Mapping{Properties: properties}, nil
+}
+
+// Marshal derives the mapping of v in the form expected by Index.Create and
+// Index.PutMapping.
+func Marshal(v interface{}) ([]byte, error) {
+	m, err := For(v)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(m)
+}
+
+// Parse reads a mapping as returned by Index.GetMapping.
+func Parse(data []byte) (*Mapping, error) {
+	m := &Mapping{}
+	err := json.Unmarshal(data, m)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func (m *Mapping) MarshalJSON() ([]byte, error) {
+	properties := m.Properties
+
+	// elasticsearch rejects a null properties object
+	if properties == nil {
+		properties = map[string]*Field{}
+	}
+
+	return json.Marshal(map[string]interface{}{"properties": properties})
+}
+
+func (m *Mapping) UnmarshalJSON(data []byte) error {
+	raw := struct {
+		Properties map[string]*Field `json:"properties"`
+	}{}
+
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	m.Properties = raw.Properties
+	return nil
+}
+
+func (f *Field) MarshalJSON() ([]byte, error) {
+	source := map[string]interface{}{}
+
+	for key, value := range f.Params {
+		source[key] = value
+	}
+
+	if f.Type != "" {
+		source["type"] = f.Type
+	}
+
+	if f.Properties != nil {
+		source["properties"] = f.Properties
+	}
+
+	return json.Marshal(source)
+}
+
+func (f *Field) UnmarshalJSON(data []byte) error {
+	source := map[string]json.RawMessage{}
+
+	if err := json.Unmarshal(data, &source); err != nil {
+		return err
+	}
+
+	*f = Field{}
+
+	for key, value := range source {
+		var err error
+
+		switch key {
+		case "type":
+			err = json.Unmarshal(value, &f.Type)
+		case "properties":
+			err = json.Unmarshal(value, &f.Properties)
+		default:
+			var param interface{}
+			err = json.Unmarshal(value, &param)
+
+			if f.Params == nil {
+				f.Params = map[string]interface{}{}
+			}
+
+			f.Params[key] = param
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// derive the properties of a struct, seen holds the structs being derived to
+// reject recursive types which would produce an endless mapping
+func structProperties(t reflect.Type, seen map[reflect.Type]bool) (map[string]*Field, error) {
+	if seen[t] {
+		return nil, fmt.Errorf("The mapping of %v is recursive.", t)
+	}
+
+	seen[t] = true
+	defer delete(seen, t)
+
+	properties := map[string]*Field{}
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, skip := fieldName(sf)
+
+		if skip {
+			continue
+		}
+
+		// the fields of embedded structs are promoted, as encoding/json does
+		if sf.Anonymous && name == "" {
+			embedded := sf.Type
+
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+
+			if embedded.Kind() == reflect.Struct {
+				promoted, err := structProperties(embedded, seen)
+
+				if err != nil {
+					return nil, err
+				}
+
+				for key, field := range promoted {
+					if _, ok := properties[key]; !ok {
+						properties[key] = field
+					}
+				}
+
+				continue
+			}
+		}
+
+		if name == "" {
+			name = sf.Name
+		}
+
+		field, err := newField(sf, seen)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if field != nil {
+			properties[name] = field
+		}
+	}
+
+	return properties, nil
+}
+
+// the name of a struct field within documents and whether it is skipped
+func fieldName(sf reflect.StructField) (string, bool) {
+	// the ID of a document is metadata rather than a field of its source
+	if tag := sf.Tag.Get("es"); tag == "-" || tag == "_id" {
+		return "", true
+	}
+
+	// unexported fields are skipped unless they embed a struct
+	if sf.PkgPath != "" && !sf.Anonymous {
+		return "", true
+	}
+
+	tag := sf.Tag.Get("json")
+
+	if tag == "-" {
+		return "", true
+	}
+
+	name := strings.Split(tag, ",")[0]
+	return name, name == "_id"
+}
+
+// derive the mapping of a struct field from its type and es tag, returns nil
+// for fields whose type cannot be inferred and is not given by the tag
+func newField(sf reflect.StructField, seen map[reflect.Type]bool) (*Field, error) {
+	params, err := parseTag(sf.Tag.Get("es"))
+
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", sf.Name, err)
+	}
+
+	field := &Field{}
+
+	if fieldType, ok := params["type"]; ok {
+		field.Type = fmt.Sprint(fieldType)
+		delete(params, "type")
+	}
+
+	if len(params) > 0 {
+		field.Params = params
+	}
+
+	t := elementType(sf.Type)
+
+	// object and nested fields map the struct they hold
+	if t.Kind() == reflect.Struct && t != timeType && (field.Type == "" || field.Type == "object" || field.Type == "nested") {
+		field.Properties, err = structProperties(t, seen)
+
+		if err != nil {
+			return nil, err
+		}
+
+		// elasticsearch reports object fields without a type
+		if field.Type == "object" {
+			field.Type = ""
+		}
+
+		return field, nil
+	}
+
+	if field.Type == "" {
+		field.Type = inferType(t)
+	}
+
+	if field.Type == "" {
+		return nil, nil
+	}
+
+	return field, nil
+}
+
+// elasticsearch has no array type, the mapping of a slice is that of its
+// elements. Pointers map as the value they point to.
+func elementType(t reflect.Type) reflect.Type {
+	for {
+		switch {
+		case t == rawType || t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+			return t
+		case t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+			t = t.Elem()
+		default:
+			return t
+		}
+	}
+}
+
+// the field type elasticsearch would use for values of t
+func inferType(t reflect.Type) string {
+	switch {
+	case t == timeType:
+		return "date"
+	case t == rawType:
+		return "object"
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return "text"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int8:
+		return "byte"
+	case reflect.Int16:
+		return "short"
+	case reflect.Int32:
+		return "integer"
+	case reflect.Int, reflect.Int64, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return "long"
+	case reflect.Uint, reflect.Uint64:
+		return "unsigned_long"
+	case reflect.Float32:
+		return "float"
+	case reflect.Float64:
+		return "double"
+	case reflect.Slice:
+		// []byte is encoded as base64 by encoding/json
+		return "binary"
+	case reflect.Map:
+		return "object"
+	}
+
+	return ""
+}
+
+// parse an es tag of comma separated key=value pairs. Values are typed as
+// elasticsearch expects them, e.g. index=false is a boolean.
+func parseTag(tag string) (map[string]interface{}, error) {
+	params := map[string]interface{}{}
+
+	if tag == "" {
+		return params, nil
+	}
+
+	for _, pair := range strings.Split(tag, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		key := strings.TrimSpace(parts[0])
+
+		if len(parts) != 2 || key == "" {
+			return nil, fmt.Errorf("The es tag option %q is not of the form key=value.", pair)
+		}
+
+		params[key] = parseValue(strings.TrimSpace(parts[1]))
+	}
+
+	return params, nil
+}
+
+func parseValue(value string) interface{} {
+	switch value {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+
+	if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return i
+	}
+
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return f
+	}
+
+	return value
+}
+
+// the sorted names of the properties
+func names(properties map[string]*Field) []string {
+	result := make([]string, 0, len(properties))
+
+	for name := range properties {
+		result = append(result, name)
+	}
+
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,133 @@
+package mapping_test
+
+import (
+	"encoding/json"
+	"github.com/b3ntly/elasticsearch"
+	"github.com/b3ntly/elasticsearch/mapping"
+	"github.com/b3ntly/elasticsearch/mock"
+	"github.com/stretchr/testify/require"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type (
+	comment struct {
+		Author string `json:"author" es:"type=keyword"`
+		Body   string `json:"body"`
+	}
+
+	address struct {
+		City string `json:"city" es:"type=keyword"`
+	}
+
+	Audit struct {
+		Created time.Time  `json:"created" es:"format=strict_date_optional_time"`
+		Updated *time.Time `json:"updated,omitempty"`
+	}
+
+	user struct {
+		Audit
+		ID       string            `json:"_id,omitempty"`
+		Name     string            `json:"name" es:"type=keyword,ignore_above=256"`
+		Bio      string            `json:"bio" es:"analyzer=english"`
+		Password string            `json:"password" es:"index=false"`
+		Age      int32             `json:"age"`
+		Score    float64           `json:"score"`
+		Active   bool              `json:"active"`
+		Tags     []string          `json:"tags" es:"type=keyword"`
+		Avatar   []byte            `json:"avatar"`
+		Address  *address          `json:"address"`
+		Comments []comment         `json:"comments" es:"type=nested"`
+		Labels   map[string]string `json:"labels"`
+		Extra    json.RawMessage   `json:"extra"`
+		Ignored  string            `json:"-"`
+		Any      interface{}       `json:"any"`
+		Untagged string
+		internal string
+	}
+
+	node struct {
+		Name     string  `json:"name"`
+		Children []*node `json:"children"`
+	}
+)
+
+func TestFor(t *testing.T) {
+	t.Run("Infers types and applies es tags", func(t *testing.T) {
+		js, err := mapping.Marshal(&user{})
+		require.Nil(t, err)
+
+		require.JSONEq(t, `{"properties": {
+			"created": {"type": "date", "format": "strict_date_optional_time"},
+			"updated": {"type": "date"},
+			"name": {"type": "keyword", "ignore_above": 256},
+			"bio": {"type": "text", "analyzer": "english"},
+			"password": {"type": "text", "index": false},
+			"age": {"type": "integer"},
+			"score": {"type": "double"},
+			"active": {"type": "boolean"},
+			"tags": {"type": "keyword"},
+			"avatar": {"type": "binary"},
+			"address": {"properties": {"city": {"type": "keyword"}}},
+			"comments": {"type": "nested", "properties": {"author": {"type": "keyword"}, "body": {"type": "text"}}},
+			"labels": {"type": "object"},
+			"extra": {"type": "object"},
+			"Untagged": {"type": "text"}
+		}}`, string(js))
+	})
+
+	t.Run("Rejects recursive types, malformed tags and non structs", func(t *testing.T) {
+		_, err := mapping.For(node{})
+		require.Error(t, err)
+
+		_, err = mapping.For(struct {
+			Name string `es:"keyword"`
+		}{})
+		require.Error(t, err)
+
+		_, err = mapping.For("user")
+		require.Error(t, err)
+	})
+}
+
+func TestDiff(t *testing.T) {
+	expected, err := mapping.For(user{})
+	require.Nil(t, err)
+
+	t.Run("Reports missing, unexpected and changed fields by path", func(t *testing.T) {
+		actual, err := mapping.For(user{})
+		require.Nil(t, err)
+
+		require.Empty(t, mapping.Diff(expected, actual))
+
+		delete(actual.Properties, "bio")
+		actual.Properties["legacy"] = &mapping.Field{Type: "keyword"}
+		actual.Properties["comments"].Properties["author"].Type = "text"
+
+		differences := mapping.Diff(expected, actual)
+		require.Len(t, differences, 3)
+		require.Equal(t, "bio is missing", differences[0].String())
+		require.Equal(t, `comments.author is mapped as {"type":"text"} instead of {"type":"keyword"}`, differences[1].String())
+		require.Equal(t, "legacy is not expected", differences[2].String())
+	})
+
+	t.Run("Matches the live mapping of an index created with it", func(t *testing.T) {
+		server := httptest.NewServer(mock.New().Handler)
+		defer server.Close()
+
+		client, err := elasticsearch.New(&elasticsearch.Options{URI: server.URL})
+		require.Nil(t, err)
+
+		js, err := json.Marshal(expected)
+		require.Nil(t, err)
+		require.Nil(t, client.I("mapping").Create(nil, js))
+
+		live, err := client.I("mapping").GetMapping()
+		require.Nil(t, err)
+
+		actual, err := mapping.Parse(live)
+		require.Nil(t, err)
+		require.Empty(t, mapping.Diff(expected, actual))
+	})
+}
@@ -0,0 +1,383 @@
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// Stats is a snapshot of the metrics recorded by a Client.
+	Stats struct {
+		// keyed by the kind of request, such as insert, get, search or bulk
+		Operations map[string]OperationStats
+		// keyed by the host:port of the node
+		Nodes map[string]NodeStats
+	}
+
+	// OperationStats are the metrics of one kind of request. A request counts
+	// once however often it was retried.
+	OperationStats struct {
+		Requests int64
+		Retries  int64
+		// failed requests keyed by ErrorClass
+		Errors        map[string]int64
+		BytesSent     int64
+		BytesReceived int64
+		// duration of requests including their retries
+		Latency Histogram
+	}
+
+	// NodeStats are the metrics of every attempt sent to a node.
+	NodeStats struct {
+		Requests      int64
+		Errors        int64
+		BytesSent     int64
+		BytesReceived int64
+		Latency       Histogram
+	}
+
+	// Histogram counts durations into buckets. Counts[i] is the number of
+	// durations up to Buckets[i] and above Buckets[i-1], durations above the
+	// last bucket are only part of Count and Sum.
+	Histogram struct {
+		Buckets []time.Duration
+		Counts  []int64
+		Count   int64
+		Sum     time.Duration
+	}
+
+	// records the metrics of a Client
+	metrics struct {
+		sync.Mutex
+		operations map[string]*OperationStats
+		nodes      map[string]*NodeStats
+	}
+
+	operationKey struct{}
+)
+
+// the index APIs recorded as operations of their own, other paths of two
+// segments are documents of a type such as _doc
+var indexActions = map[string]bool{
+	"_close":      true,
+	"_open":       true,
+	"_settings":   true,
+	"_mapping":    true,
+	"_refresh":    true,
+	"_flush":      true,
+	"_forcemerge": true,
+}
+
+// DefaultLatencyBuckets are the upper bounds of the latency histograms.
+var DefaultLatencyBuckets = []time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+func newMetrics() *metrics {
+	return &metrics{operations: map[string]*OperationStats{}, nodes: map[string]*NodeStats{}}
+}
+
+func newHistogram() Histogram {
+	return Histogram{Buckets: DefaultLatencyBuckets, Counts: make([]int64, len(DefaultLatencyBuckets))}
+}
+
+func (h *Histogram) observe(d time.Duration) {
+	h.Count++
+	h.Sum += d
+
+	if i := sort.Search(len(h.Buckets), func(i int) bool { return d <= h.Buckets[i] }); i < len(h.Buckets) {
+		h.Counts[i]++
+	}
+}
+
+func (h Histogram) copy() Histogram {
+	h.Counts = append([]int64(nil), h.Counts...)
+	return h
+}
+
+func (m *metrics) operation(name string) *OperationStats {
+	stats, ok := m.operations[name]
+
+	if !ok {
+		stats = &OperationStats{Errors: map[string]int64{}, Latency: newHistogram()}
+		m.operations[name] = stats
+	}
+
+	return stats
+}
+
+func (m *metrics) node(host string) *NodeStats {
+	stats, ok := m.nodes[host]
+
+	if !ok {
+		stats = &NodeStats{Latency: newHistogram()}
+		m.nodes[host] = stats
+	}
+
+	return stats
+}
+
+// record a request once it succeeded or failed for good
+func (m *metrics) request(operation string, duration time.Duration, retries int, err error) {
+	if m == nil {
+		return
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	stats := m.operation(operation)
+	stats.Requests++
+	stats.Retries += int64(retries)
+	stats.Latency.observe(duration)
+
+	if err != nil {
+		stats.Errors[ErrorClass(err)]++
+	}
+}
+
+// record a single attempt sent to a node
+func (m *metrics) attempt(operation string, host string, sent int64, received int64, duration time.Duration, failed bool) {
+	if m == nil {
+		return
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	stats := m.operation(operation)
+	stats.BytesSent += sent
+	stats.BytesReceived += received
+
+	node := m.node(host)
+	node.Requests++
+	node.BytesSent += sent
+	node.BytesReceived += received
+	node.Latency.observe(duration)
+
+	if failed {
+		node.Errors++
+	}
+}
+
+func (m *metrics) snapshot() Stats {
+	stats := Stats{Operations: map[string]OperationStats{}, Nodes: map[string]NodeStats{}}
+
+	if m == nil {
+		return stats
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	for name, operation := range m.operations {
+		copied := *operation
+		copied.Errors = map[string]int64{}
+		copied.Latency = operation.Latency.copy()
+
+		for class, count := range operation.Errors {
+			copied.Errors[class] = count
+		}
+
+		stats.Operations[name] = copied
+	}
+
+	for host, node := range m.nodes {
+		copied := *node
+		copied.Latency = node.Latency.copy()
+		stats.Nodes[host] = copied
+	}
+
+	return stats
+}
+
+// ErrorClass groups errors returned by the client for metrics: timeout,
+// canceled, connection, client for 4xx responses or server for 5xx responses.
+func ErrorClass(err error) string {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "timeout"
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return "canceled"
+	}
+
+	esErr, ok := asElasticsearchError(err)
+
+	if !ok {
+		return "connection"
+	}
+
+	if esErr.Status >= http.StatusInternalServerError {
+		return "server"
+	}
+
+	return "client"
+}
+
+// name the kind of request from its method and its path below the base URL
+func operationOf(method string, path string) string {
+	path = strings.Trim(path, "/")
+
+	if path == "" {
+		return "ping"
+	}
+
+	segments := strings.Split(path, "/")
+	last := segments[len(segments)-1]
+
+	switch {
+	case last == "_bulk":
+		return "bulk"
+	case last == "_search":
+		return "search"
+	case last == "scroll":
+		return "scroll"
+	case last == "_pit":
+		return "pit"
+	case last == "_update":
+		return "update"
+	case segments[0] == "_nodes":
+		return "nodes"
+	case len(segments) == 1 && method == "DELETE":
+		return "delete_index"
+	case len(segments) == 1 && method == "PUT":
+		return "create_index"
+	case len(segments) == 1 && method == "HEAD":
+		return "index_exists"
+	case len(segments) == 2 && indexActions[last]:
+		return strings.TrimPrefix(last, "_")
+	case len(segments) == 2 && method == "POST":
+		return "insert"
+	case len(segments) == 3 && method == "GET":
+		return "get"
+	case len(segments) == 3 && method == "PUT":
+		return "update"
+	case len(segments) == 3 && method == "DELETE":
+		return "delete"
+	}
+
+	return "other"
+}
+
+// the operation of req, as tagged by sendRequest
+func operationFromContext(ctx context.Context) string {
+	if operation, ok := ctx.Value(operationKey{}).(string); ok {
+		return operation
+	}
+
+	return "ping"
+}
+
+// Stats returns a snapshot of the metrics recorded since the client was created.
+func (c *Client) Stats() Stats {
+	return c.REST.metrics.snapshot()
+}
+
+// MetricsHandler serves the metrics of the client in the Prometheus text
+// exposition format.
+func (c *Client) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		writeMetrics(w, c.Stats())
+	})
+}
+
+func writeMetrics(w http.ResponseWriter, stats Stats) {
+	operations := []string{}
+	nodes := []string{}
+
+	for operation := range stats.Operations {
+		operations = append(operations, operation)
+	}
+
+	for node := range stats.Nodes {
+		nodes = append(nodes, node)
+	}
+
+	sort.Strings(operations)
+	sort.Strings(nodes)
+
+	counter := func(name string, help string, label string, keys []string, value func(key string) int64) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+
+		for _, key := range keys {
+			fmt.Fprintf(w, "%s{%s=%q} %d\n", name, label, key, value(key))
+		}
+	}
+
+	counter("elasticsearch_client_requests_total", "Requests sent, once per request however often it was retried.", "operation", operations,
+		func(key string) int64 { return stats.Operations[key].Requests })
+	counter("elasticsearch_client_retries_total", "Requests sent again after an attempt failed.", "operation", operations,
+		func(key string) int64 { return stats.Operations[key].Retries })
+	counter("elasticsearch_client_sent_bytes_total", "Bytes of request bodies sent.", "operation", operations,
+		func(key string) int64 { return stats.Operations[key].BytesSent })
+	counter("elasticsearch_client_received_bytes_total", "Bytes of response bodies received.", "operation", operations,
+		func(key string) int64 { return stats.Operations[key].BytesReceived })
+
+	fmt.Fprint(w, "# HELP elasticsearch_client_errors_total Requests which failed.\n# TYPE elasticsearch_client_errors_total counter\n")
+
+	for _, operation := range operations {
+		errs := stats.Operations[operation].Errors
+		classes := []string{}
+
+		for class := range errs {
+			classes = append(classes, class)
+		}
+
+		sort.Strings(classes)
+
+		for _, class := range classes {
+			fmt.Fprintf(w, "elasticsearch_client_errors_total{operation=%q,class=%q} %d\n", operation, class, errs[class])
+		}
+	}
+
+	fmt.Fprint(w, "# HELP elasticsearch_client_request_duration_seconds Duration of requests including their retries.\n")
+	fmt.Fprint(w, "# TYPE elasticsearch_client_request_duration_seconds histogram\n")
+
+	for _, operation := range operations {
+		writeHistogram(w, "elasticsearch_client_request_duration_seconds", fmt.Sprintf("operation=%q", operation), stats.Operations[operation].Latency)
+	}
+
+	counter("elasticsearch_client_node_requests_total", "Attempts sent to a node.", "node", nodes,
+		func(key string) int64 { return stats.Nodes[key].Requests })
+	counter("elasticsearch_client_node_errors_total", "Attempts which failed on a node.", "node", nodes,
+		func(key string) int64 { return stats.Nodes[key].Errors })
+
+	fmt.Fprint(w, "# HELP elasticsearch_client_node_request_duration_seconds Duration of attempts sent to a node.\n")
+	fmt.Fprint(w, "# TYPE elasticsearch_client_node_request_duration_seconds histogram\n")
+
+	for _, node := range nodes {
+		writeHistogram(w, "elasticsearch_client_node_request_duration_seconds", fmt.Sprintf("node=%q", node), stats.Nodes[node].Latency)
+	}
+}
+
+// buckets are cumulative in the exposition format
+func writeHistogram(w http.ResponseWriter, name string, labels string, h Histogram) {
+	cumulative := int64(0)
+
+	for i, bucket := range h.Buckets {
+		cumulative += h.Counts[i]
+		fmt.Fprintf(w, "%s_bucket{%s,le=\"%g\"} %d\n", name, labels, bucket.Seconds(), cumulative)
+	}
+
+	fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, h.Count)
+	fmt.Fprintf(w, "%s_sum{%s} %g\n", name, labels, h.Sum.Seconds())
+	fmt.Fprintf(w, "%s_count{%s} %d\n", name, labels, h.Count)
+}
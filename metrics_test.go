@@ -0,0 +1,141 @@
+package elasticsearch_test
+
+import (
+	"github.com/b3ntly/elasticsearch"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Run("Requests are recorded per operation and node", func(t *testing.T) {
+		client, err := elasticsearch.New(&elasticsearch.Options{URI: "http://127.0.0.1:9201"})
+		require.Nil(t, err)
+
+		_ = client.I("metrics").Drop()
+		defer client.I("metrics").Drop()
+
+		collection := client.I("metrics").T("metrics")
+		ID, err := collection.Insert([]byte(`{"message": "hello"}`))
+		require.Nil(t, err)
+
+		_, err = collection.FindById(ID)
+		require.Nil(t, err)
+
+		_, err = collection.FindById("missing")
+		require.True(t, elasticsearch.IsNotFound(err))
+
+		_, err = collection.BulkInsert([][]byte{[]byte(`{"message": "world"}`)})
+		require.Nil(t, err)
+
+		_, err = collection.Execute(elasticsearch.NewSearchRequest())
+		require.Nil(t, err)
+
+		stats := client.Stats()
+
+		require.Equal(t, int64(1), stats.Operations["insert"].Requests)
+		require.Equal(t, int64(2), stats.Operations["get"].Requests)
+		require.Equal(t, map[string]int64{"client": 1}, stats.Operations["get"].Errors)
+		require.Equal(t, int64(1), stats.Operations["bulk"].Requests)
+		require.Equal(t, int64(1), stats.Operations["search"].Requests)
+
+		insert := stats.Operations["insert"]
+		require.Equal(t, int64(len(`{"message": "hello"}`)), insert.BytesSent)
+		require.True(t, insert.BytesReceived > 0)
+		require.Equal(t, int64(1), insert.Latency.Count)
+		require.True(t, insert.Latency.Sum > 0)
+
+		// the mock answers far below the largest bucket
+		total := int64(0)
+
+		for _, count := range insert.Latency.Counts {
+			total += count
+		}
+
+		require.Equal(t, int64(1), total)
+
+		node := stats.Nodes["127.0.0.1:9201"]
+		require.Equal(t, int64(6), node.Requests)
+		require.Equal(t, int64(0), node.Errors)
+	})
+
+	t.Run("Documents of type _doc are not mistaken for index APIs", func(t *testing.T) {
+		client, err := elasticsearch.New(&elasticsearch.Options{URI: "http://127.0.0.1:9201"})
+		require.Nil(t, err)
+
+		_ = client.I("metrics").Drop()
+		defer client.I("metrics").Drop()
+
+		_, err = client.I("metrics").T("_doc").Insert([]byte(`{"message": "hello"}`))
+		require.Nil(t, err)
+		require.Nil(t, client.I("metrics").Refresh())
+
+		stats := client.Stats()
+		require.Equal(t, int64(1), stats.Operations["insert"].Requests)
+		require.Equal(t, int64(1), stats.Operations["refresh"].Requests)
+		require.NotContains(t, stats.Operations, "doc")
+	})
+
+	t.Run("Retries and server errors are recorded", func(t *testing.T) {
+		attempts := 0
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if attempts++; attempts <= 2 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+
+			w.Write([]byte(`{"acknowledged": true}`))
+		}))
+		defer server.Close()
+
+		client, err := elasticsearch.New(&elasticsearch.Options{
+			URI:     server.URL,
+			Retrier: &elasticsearch.BackoffRetrier{MaxRetries: 1, InitialInterval: time.Millisecond, MaxInterval: time.Millisecond},
+		})
+
+		require.Nil(t, err)
+		require.Error(t, client.I("test").Drop())
+		require.Nil(t, client.I("test").Drop())
+
+		stats := client.Stats()
+		require.Equal(t, int64(2), stats.Operations["delete_index"].Requests)
+		require.Equal(t, int64(1), stats.Operations["delete_index"].Retries)
+		require.Equal(t, map[string]int64{"server": 1}, stats.Operations["delete_index"].Errors)
+
+		node := stats.Nodes[strings.TrimPrefix(server.URL, "http://")]
+		require.Equal(t, int64(3), node.Requests)
+		require.Equal(t, int64(2), node.Errors)
+
+		// snapshots are not changed by later requests
+		require.Nil(t, client.I("test").Drop())
+		require.Equal(t, int64(2), stats.Operations["delete_index"].Requests)
+	})
+
+	t.Run("Metrics are exposed in the Prometheus text format", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Write([]byte(`{"acknowledged": true}`))
+		}))
+		defer server.Close()
+
+		client, err := elasticsearch.New(&elasticsearch.Options{URI: server.URL})
+		require.Nil(t, err)
+		require.Nil(t, client.I("test").Drop())
+
+		recorder := httptest.NewRecorder()
+		client.MetricsHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+		body, _ := ioutil.ReadAll(recorder.Body)
+		exposition := string(body)
+		node := strings.TrimPrefix(server.URL, "http://")
+
+		require.Contains(t, exposition, "# TYPE elasticsearch_client_requests_total counter\n")
+		require.Contains(t, exposition, `elasticsearch_client_requests_total{operation="delete_index"} 1`+"\n")
+		require.Contains(t, exposition, `elasticsearch_client_request_duration_seconds_bucket{operation="delete_index",le="+Inf"} 1`+"\n")
+		require.Contains(t, exposition, `elasticsearch_client_request_duration_seconds_count{operation="delete_index"} 1`+"\n")
+		require.Contains(t, exposition, `elasticsearch_client_node_requests_total{node="`+node+`"} 1`+"\n")
+	})
+}
@@ -0,0 +1,87 @@
+package elasticsearch
+
+import (
+	"net/http"
+	"time"
+)
+
+type (
+	// Response is an HTTP response of elasticsearch as seen by middleware,
+	// with its body read and decompressed. The body of successful responses
+	// to streamed searches is decoded as it is read and not kept, it is nil.
+	Response struct {
+		StatusCode int
+		Header     http.Header
+		Body       []byte
+		// time from sending the request until the body was read
+		Duration time.Duration
+	}
+
+	// RoundTripFunc sends a request to a node and returns its response.
+	// Responses with an error status are returned without an error, those
+	// are only converted to an ElasticsearchError once every middleware ran.
+	RoundTripFunc func(req *http.Request) (*Response, error)
+
+	// Middleware wraps the sending of every request, including retries and
+	// pings, once the node serving it was chosen. It may change the request,
+	// inspect or replace the response, or fail the request without calling next.
+	Middleware func(next RoundTripFunc) RoundTripFunc
+)
+
+// Use appends middleware to the chain every request passes through. The first
+// middleware is the outermost one, it sees the request first and the response
+// last. Use should be called before the client sends any request.
+func (c *Client) Use(middleware ...Middleware) {
+	c.REST.mu.Lock()
+	defer c.REST.mu.Unlock()
+
+	c.REST.middleware = append(c.REST.middleware, middleware...)
+}
+
+// send req through the middleware chain
+func (r *rest) roundTrip(req *http.Request) (*Response, error) {
+	r.mu.RLock()
+	next := RoundTripFunc(r.transport)
+
+	// log what is actually sent, after every middleware ran
+	if r.logger != nil {
+		next = r.logged(next)
+	}
+
+	for i := len(r.middleware) - 1; i >= 0; i-- {
+		next = r.middleware[i](next)
+	}
+
+	r.mu.RUnlock()
+	return next(req)
+}
+
+// the end of the middleware chain, sends req with the HTTP client
+func (r *rest) transport(req *http.Request) (*Response, error) {
+	start := time.Now()
+	response, err := r.HTTPClient.Do(req)
+
+	if err != nil {
+		r.metrics.attempt(operationFromContext(req.Context()), req.URL.Host, req.ContentLength, 0, time.Since(start), true)
+		return nil, err
+	}
+
+	defer response.Body.Close()
+
+	// successful responses of streamed requests are never held in memory
+	if s := streamFromContext(req.Context()); s != nil && response.StatusCode < 299 {
+		return r.streamed(req, response, s, start)
+	}
+
+	contents, err := readBody(response)
+	duration := time.Since(start)
+
+	failed := err != nil || response.StatusCode >= http.StatusInternalServerError
+	r.metrics.attempt(operationFromContext(req.Context()), req.URL.Host, req.ContentLength, int64(len(contents)), duration, failed)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Response{StatusCode: response.StatusCode, Header: response.Header, Body: contents, Duration: duration}, nil
+}
@@ -0,0 +1,102 @@
+package elasticsearch_test
+
+import (
+	"errors"
+	"github.com/b3ntly/elasticsearch"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMiddleware(t *testing.T) {
+	var traces []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		traces = append(traces, req.Header.Get("Traceparent"))
+		w.Write([]byte(`{"acknowledged": true}`))
+	}))
+	defer server.Close()
+
+	newClient := func(t *testing.T) *elasticsearch.Client {
+		traces = nil
+		client, err := elasticsearch.New(&elasticsearch.Options{URI: server.URL, Retrier: &elasticsearch.BackoffRetrier{MaxRetries: 1}})
+		require.Nil(t, err)
+		return client
+	}
+
+	t.Run("Middleware sees requests in order and responses in reverse order", func(t *testing.T) {
+		client := newClient(t)
+		var calls []string
+
+		trace := func(name string) elasticsearch.Middleware {
+			return func(next elasticsearch.RoundTripFunc) elasticsearch.RoundTripFunc {
+				return func(req *http.Request) (*elasticsearch.Response, error) {
+					calls = append(calls, "request "+name)
+					req.Header.Set("Traceparent", name)
+
+					response, err := next(req)
+					calls = append(calls, "response "+name)
+					return response, err
+				}
+			}
+		}
+
+		client.Use(trace("outer"), trace("inner"))
+		require.Nil(t, client.I("test").Drop())
+
+		require.Equal(t, []string{"request outer", "request inner", "response inner", "response outer"}, calls)
+		require.Equal(t, []string{"inner"}, traces)
+	})
+
+	t.Run("Middleware can inspect the response", func(t *testing.T) {
+		client := newClient(t)
+		var audited *elasticsearch.Response
+
+		client.Use(func(next elasticsearch.RoundTripFunc) elasticsearch.RoundTripFunc {
+			return func(req *http.Request) (*elasticsearch.Response, error) {
+				response, err := next(req)
+				audited = response
+				return response, err
+			}
+		})
+
+		require.Nil(t, client.I("test").Drop())
+		require.Equal(t, http.StatusOK, audited.StatusCode)
+		require.Equal(t, `{"acknowledged": true}`, string(audited.Body))
+		require.True(t, audited.Duration > 0 && audited.Duration < time.Second)
+	})
+
+	t.Run("Injected faults are retried like real ones", func(t *testing.T) {
+		client := newClient(t)
+		injected := 0
+
+		client.Use(func(next elasticsearch.RoundTripFunc) elasticsearch.RoundTripFunc {
+			return func(req *http.Request) (*elasticsearch.Response, error) {
+				if injected++; injected == 1 {
+					return &elasticsearch.Response{StatusCode: http.StatusServiceUnavailable}, nil
+				}
+
+				return next(req)
+			}
+		})
+
+		require.Nil(t, client.I("test").Drop())
+		require.Equal(t, 2, injected)
+		require.Len(t, traces, 1)
+
+		client = newClient(t)
+		failure := errors.New("injected")
+
+		client.Use(func(next elasticsearch.RoundTripFunc) elasticsearch.RoundTripFunc {
+			return func(req *http.Request) (*elasticsearch.Response, error) {
+				return nil, failure
+			}
+		})
+
+		_, err := client.I("test").T("test").Insert([]byte(`{}`))
+		require.Equal(t, failure, err)
+		require.Len(t, traces, 0)
+	})
+}
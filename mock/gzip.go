@@ -0,0 +1,54 @@
+package mock
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strings"
+)
+
+// gzipWriter compresses everything written to the response
+type gzipWriter struct {
+	http.ResponseWriter
+	writer *gzip.Writer
+}
+
+func (g *gzipWriter) WriteHeader(status int) {
+	g.Header().Del("Content-Length")
+	g.ResponseWriter.WriteHeader(status)
+}
+
+func (g *gzipWriter) Write(b []byte) (int, error) {
+	return g.writer.Write(b)
+}
+
+// Gzip decodes request bodies sent with Content-Encoding: gzip and
+// compresses responses for clients which accept gzip, as elasticsearch
+// does with http.compression enabled.
+func Gzip(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Content-Encoding") == "gzip" {
+			body, err := gzip.NewReader(req.Body)
+
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			defer body.Close()
+			req.Body = body
+			req.Header.Del("Content-Encoding")
+			req.ContentLength = -1
+		}
+
+		if !strings.Contains(req.Header.Get("Accept-Encoding"), "gzip") {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		w.Header().Set("Content-Encoding", "gzip")
+		writer := gzip.NewWriter(w)
+		defer writer.Close()
+
+		next.ServeHTTP(&gzipWriter{ResponseWriter: w, writer: writer}, req)
+	})
+}
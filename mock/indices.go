@@ -0,0 +1,459 @@
+package mock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+var (
+	errIndexExists      = errors.New("already exists")
+	errIndexClosed      = errors.New("closed")
+	errStaticSetting    = errors.New("Can't update non dynamic settings")
+	errSettingsMismatch = errors.New("settings must be an object")
+)
+
+// settings which can only be set when an index is created
+var staticSettings = map[string]bool{"index.number_of_shards": true, "index.codec": true}
+
+// the settings, mappings and state of an index
+type indexMeta struct {
+	// flattened as elasticsearch does, e.g. index.number_of_shards
+	settings map[string]string
+	mappings map[string]json.RawMessage
+	closed   bool
+}
+
+func newIndexMeta() *indexMeta {
+	return &indexMeta{
+		settings: map[string]string{"index.number_of_shards": "1", "index.number_of_replicas": "1"},
+		mappings: map[string]json.RawMessage{},
+	}
+}
+
+// flatten settings into dotted keys below index, accepting nested and dotted
+// forms with or without the index prefix
+func flattenSettings(settings json.RawMessage) (map[string]string, error) {
+	flat := map[string]string{}
+
+	if len(settings) == 0 {
+		return flat, nil
+	}
+
+	var nested map[string]interface{}
+
+	if err := json.Unmarshal(settings, &nested); err != nil {
+		return nil, errSettingsMismatch
+	}
+
+	var flatten func(prefix string, value interface{})
+	flatten = func(prefix string, value interface{}) {
+		if object, ok := value.(map[string]interface{}); ok {
+			for key, child := range object {
+				flatten(prefix+"."+key, child)
+			}
+
+			return
+		}
+
+		key := strings.TrimPrefix(prefix, ".")
+
+		if !strings.HasPrefix(key, "index.") {
+			key = "index." + key
+		}
+
+		// elasticsearch reports every setting as a string
+		flat[key] = fmt.Sprint(value)
+	}
+
+	flatten("", nested)
+	return flat, nil
+}
+
+// nest flattened settings again, as elasticsearch returns them
+func nestSettings(flat map[string]string) map[string]interface{} {
+	nested := map[string]interface{}{}
+
+	for key, value := range flat {
+		parts := strings.Split(key, ".")
+		current := nested
+
+		for _, part := range parts[:len(parts)-1] {
+			child, ok := current[part].(map[string]interface{})
+
+			if !ok {
+				child = map[string]interface{}{}
+				current[part] = child
+			}
+
+			current = child
+		}
+
+		current[parts[len(parts)-1]] = value
+	}
+
+	return nested
+}
+
+// merge the properties of mapping into current, rejecting changes to the type of existing fields
+func mergeMappings(current map[string]json.RawMessage, mapping json.RawMessage) error {
+	update := struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+	}{}
+
+	if len(mapping) == 0 {
+		return nil
+	}
+
+	if err := json.Unmarshal(mapping, &update); err != nil {
+		return err
+	}
+
+	fieldType := func(field json.RawMessage) string {
+		definition := struct {
+			Type string `json:"type"`
+		}{}
+
+		json.Unmarshal(field, &definition)
+		return definition.Type
+	}
+
+	for name, field := range update.Properties {
+		if existing, ok := current[name]; ok && fieldType(existing) != fieldType(field) {
+			return fmt.Errorf("mapper [%s] cannot be changed from type [%s] to [%s]", name, fieldType(existing), fieldType(field))
+		}
+	}
+
+	for name, field := range update.Properties {
+		current[name] = field
+	}
+
+	return nil
+}
+
+// helpers that should be called only in a safe (locked) context
+func (s *store) meta(name string) (*indexMeta, error) {
+	if _, exists := s.Indexes[name]; !exists {
+		return nil, errIndexNotFound
+	}
+
+	return s.indices[name], nil
+}
+
+// endhelpers
+
+func (s *store) createIndex(name string, request *CreateIndexRequest) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, exists := s.Indexes[name]; exists {
+		return errIndexExists
+	}
+
+	meta := newIndexMeta()
+	settings, err := flattenSettings(request.Settings)
+
+	if err != nil {
+		return err
+	}
+
+	for key, value := range settings {
+		meta.settings[key] = value
+	}
+
+	if err := mergeMappings(meta.mappings, request.Mappings); err != nil {
+		return err
+	}
+
+	s.getOrCreateIndex(name)
+	s.indices[name] = meta
+	return nil
+}
+
+func (s *store) indexExists(name string) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	_, exists := s.Indexes[name]
+	return exists
+}
+
+func (s *store) isClosed(name string) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	meta, exists := s.indices[name]
+	return exists && meta.closed
+}
+
+func (s *store) setClosed(name string, closed bool) error {
+	s.Lock()
+	defer s.Unlock()
+
+	meta, err := s.meta(name)
+
+	if err != nil {
+		return err
+	}
+
+	meta.closed = closed
+	return nil
+}
+
+func (s *store) settings(name string) (map[string]string, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	meta, err := s.meta(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	settings := map[string]string{}
+
+	for key, value := range meta.settings {
+		settings[key] = value
+	}
+
+	return settings, nil
+}
+
+func (s *store) putSettings(name string, body json.RawMessage) error {
+	s.Lock()
+	defer s.Unlock()
+
+	meta, err := s.meta(name)
+
+	if err != nil {
+		return err
+	}
+
+	settings, err := flattenSettings(body)
+
+	if err != nil {
+		return err
+	}
+
+	for key, value := range settings {
+		if staticSettings[key] && !meta.closed && meta.settings[key] != value {
+			return fmt.Errorf("%s for open indices [[%s]]", errStaticSetting.Error(), key)
+		}
+	}
+
+	for key, value := range settings {
+		meta.settings[key] = value
+	}
+
+	return nil
+}
+
+func (s *store) mappings(name string) (map[string]json.RawMessage, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	meta, err := s.meta(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	properties := map[string]json.RawMessage{}
+
+	for field, definition := range meta.mappings {
+		properties[field] = definition
+	}
+
+	return properties, nil
+}
+
+func (s *store) putMapping(name string, mapping json.RawMessage) error {
+	s.Lock()
+	defer s.Unlock()
+
+	meta, err := s.meta(name)
+
+	if err != nil {
+		return err
+	}
+
+	return mergeMappings(meta.mappings, mapping)
+}
+
+// write an acknowledged response
+func writeAcknowledged(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"acknowledged":true}`))
+}
+
+// write a JSON response
+func writeJSON(w http.ResponseWriter, response interface{}) {
+	js, err := json.Marshal(response)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(js)
+}
+
+// write an index management error, translating store errors into their elasticsearch equivalents
+func writeIndexError(w http.ResponseWriter, err error, index string) {
+	switch {
+	case err == errIndexNotFound:
+		writeError(w, http.StatusNotFound, "index_not_found_exception", "no such index ["+index+"]", index)
+	case err == errIndexExists:
+		writeError(w, http.StatusBadRequest, "resource_already_exists_exception", "index ["+index+"] already exists", index)
+	case err == errIndexClosed:
+		writeError(w, http.StatusBadRequest, "index_closed_exception", "closed", index)
+	default:
+		writeError(w, http.StatusBadRequest, "illegal_argument_exception", err.Error(), index)
+	}
+}
+
+// rejects requests against closed indices, as elasticsearch does for reads and writes
+func requireOpen(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		index := mux.Vars(req)["index"]
+
+		if database.isClosed(index) {
+			writeIndexError(w, errIndexClosed, index)
+			return
+		}
+
+		handler(w, req)
+	}
+}
+
+func CreateIndex(w http.ResponseWriter, req *http.Request) {
+	index := mux.Vars(req)["index"]
+	request := &CreateIndexRequest{}
+	body, err := ioutil.ReadAll(req.Body)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, request); err != nil {
+			writeError(w, http.StatusBadRequest, "parse_exception", err.Error(), index)
+			return
+		}
+	}
+
+	if err := database.createIndex(index, request); err != nil {
+		writeIndexError(w, err, index)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{"acknowledged": true, "shards_acknowledged": true, "index": index})
+}
+
+func IndexExists(w http.ResponseWriter, req *http.Request) {
+	if !database.indexExists(mux.Vars(req)["index"]) {
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func CloseIndex(w http.ResponseWriter, req *http.Request) {
+	setClosed(w, req, true)
+}
+
+func OpenIndex(w http.ResponseWriter, req *http.Request) {
+	setClosed(w, req, false)
+}
+
+func setClosed(w http.ResponseWriter, req *http.Request, closed bool) {
+	index := mux.Vars(req)["index"]
+
+	if err := database.setClosed(index, closed); err != nil {
+		writeIndexError(w, err, index)
+		return
+	}
+
+	writeAcknowledged(w)
+}
+
+func GetSettings(w http.ResponseWriter, req *http.Request) {
+	index := mux.Vars(req)["index"]
+	settings, err := database.settings(index)
+
+	if err != nil {
+		writeIndexError(w, err, index)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{index: map[string]interface{}{"settings": nestSettings(settings)}})
+}
+
+func PutSettings(w http.ResponseWriter, req *http.Request) {
+	index := mux.Vars(req)["index"]
+	body, err := ioutil.ReadAll(req.Body)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := database.putSettings(index, body); err != nil {
+		writeIndexError(w, err, index)
+		return
+	}
+
+	writeAcknowledged(w)
+}
+
+func GetMapping(w http.ResponseWriter, req *http.Request) {
+	index := mux.Vars(req)["index"]
+	properties, err := database.mappings(index)
+
+	if err != nil {
+		writeIndexError(w, err, index)
+		return
+	}
+
+	mappings := map[string]interface{}{}
+
+	if len(properties) > 0 {
+		mappings["properties"] = properties
+	}
+
+	writeJSON(w, map[string]interface{}{index: map[string]interface{}{"mappings": mappings}})
+}
+
+func PutMapping(w http.ResponseWriter, req *http.Request) {
+	index := mux.Vars(req)["index"]
+	body, err := ioutil.ReadAll(req.Body)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := database.putMapping(index, body); err != nil {
+		writeIndexError(w, err, index)
+		return
+	}
+
+	writeAcknowledged(w)
+}
+
+// refresh, flush and force merge have nothing to do in memory, they only
+// report the shards of the index
+func IndexMaintenance(w http.ResponseWriter, req *http.Request) {
+	index := mux.Vars(req)["index"]
+
+	if !database.indexExists(index) {
+		writeIndexError(w, errIndexNotFound, index)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{"_shards": &ShardMetadata{Total: 2, Successful: 1}})
+}
@@ -17,18 +17,20 @@ type (
 	}
 
 	Generic struct {
-		TimedOut     bool            `json:"timed_out"`
-		Took         int             `json:"took"`
-		Index        string          `json:"_index"`
-		Type         string          `json:"_type"`
-		ID           string          `json:"_id"`
-		Version      int64           `json:"_version"`
-		Created      bool            `json:"created"`
-		Result       string          `json:"result"`
-		Score        float64         `json:"_score"`
-		Source       json.RawMessage `json:"_source"`
-		Hits         SearchResult    `json:"hits"`
-		Status       int
+		TimedOut     bool               `json:"timed_out"`
+		Took         int                `json:"took"`
+		Index        string             `json:"_index"`
+		Type         string             `json:"_type"`
+		ID           string             `json:"_id"`
+		Version      int64              `json:"_version"`
+		SeqNo        *int64             `json:"_seq_no,omitempty"`
+		PrimaryTerm  int64              `json:"_primary_term,omitempty"`
+		Created      bool               `json:"created"`
+		Result       string             `json:"result"`
+		Score        float64            `json:"_score"`
+		Source       json.RawMessage    `json:"_source"`
+		Hits         SearchResult       `json:"hits"`
+		Status       int                `json:"status,omitempty"`
 		Error        ElasticsearchError `json:"error"`
 		Acknowledged bool               `json:"acknowledged"`
 		Found        bool               `json:"found"`
@@ -36,15 +38,34 @@ type (
 		Total        int                `json:"total"`
 		MaxScore     float64            `json:"max_score"`
 
+		// fields for search API
+		Aggregations map[string]json.RawMessage `json:"aggregations,omitempty"`
+		ScrollID     string                     `json:"_scroll_id,omitempty"`
+		PitID        string                     `json:"pit_id,omitempty"`
+
 		// field for bulk API
 		Items []*Operation `json:"items"`
 	}
 
 	// Indicated a Bulk API operation
 	Operation struct {
-		Index  *Generic `json:"index"`
-		Update *Generic `json:"update"`
-		Delete *Generic `json:"delete"`
+		Index  *Generic `json:"index,omitempty"`
+		Create *Generic `json:"create,omitempty"`
+		Update *Generic `json:"update,omitempty"`
+		Delete *Generic `json:"delete,omitempty"`
+	}
+
+	// The metadata line of any bulk action
+	BulkMetadata struct {
+		Index           string `json:"_index,omitempty"`
+		Type            string `json:"_type,omitempty"`
+		ID              string `json:"_id,omitempty"`
+		Routing         string `json:"routing,omitempty"`
+		Version         *int64 `json:"version,omitempty"`
+		VersionType     string `json:"version_type,omitempty"`
+		IfSeqNo         *int64 `json:"if_seq_no,omitempty"`
+		IfPrimaryTerm   *int64 `json:"if_primary_term,omitempty"`
+		RetryOnConflict int    `json:"retry_on_conflict,omitempty"`
 	}
 
 	BulkIndex struct {
@@ -56,7 +77,19 @@ type (
 	}
 
 	BulkUpdatePayload struct {
-		Doc json.RawMessage `json:"doc"`
+		Doc            json.RawMessage `json:"doc,omitempty"`
+		Upsert         json.RawMessage `json:"upsert,omitempty"`
+		DocAsUpsert    bool            `json:"doc_as_upsert,omitempty"`
+		Script         *Script         `json:"script,omitempty"`
+		ScriptedUpsert bool            `json:"scripted_upsert,omitempty"`
+	}
+
+	// A script executed by an update, only a small subset of painless is
+	// understood by the mock
+	Script struct {
+		Source string                 `json:"source"`
+		Lang   string                 `json:"lang,omitempty"`
+		Params map[string]interface{} `json:"params,omitempty"`
 	}
 
 	BulkDelete struct {
@@ -73,36 +106,109 @@ type (
 	Document struct {
 		ID   string
 		Body map[string]json.RawMessage
+		// the operation which last changed the document
+		SeqNo       int64
+		PrimaryTerm int64
+		Version     int64
 	}
 
 	GenericDocument struct {
-		ID string `json:"_id,omitempty"`
+		ID   string `json:"_id,omitempty"`
 		Body []byte
 	}
 
 	SearchResult struct {
-		Total    int          `json:"total"`
+		Total    TotalHits    `json:"total"`
 		MaxScore float64      `json:"max_score"`
 		Hits     []*SearchHit `json:"hits"`
 	}
 
 	SearchHit struct {
-		Index  string          `json:"_index"`
-		Type   string          `json:"_type"`
-		ID     string          `json:"_id"`
-		Score  float64         `json:"_score"`
-		Source json.RawMessage `json:"_source"`
+		Index  string                     `json:"_index"`
+		Type   string                     `json:"_type"`
+		ID     string                     `json:"_id"`
+		Score  float64                    `json:"_score"`
+		Source json.RawMessage            `json:"_source,omitempty"`
+		Sort   []interface{}              `json:"sort,omitempty"`
+		Fields map[string]json.RawMessage `json:"fields,omitempty"`
+	}
+
+	// The body of a create index request
+	CreateIndexRequest struct {
+		Settings json.RawMessage `json:"settings,omitempty"`
+		Mappings json.RawMessage `json:"mappings,omitempty"`
+	}
+
+	// Identifies a point in time opened against an index
+	PointInTime struct {
+		ID        string `json:"id"`
+		KeepAlive string `json:"keep_alive,omitempty"`
+	}
+
+	// The body of a request continuing a scroll
+	ScrollRequest struct {
+		Scroll   string `json:"scroll"`
+		ScrollID string `json:"scroll_id"`
+	}
+
+	// The body of a response to releasing scrolls or points in time
+	ClearResponse struct {
+		Succeeded bool `json:"succeeded"`
+		NumFreed  int  `json:"num_freed"`
+	}
+
+	// The response of the nodes info API
+	NodesInfo struct {
+		ClusterName string               `json:"cluster_name"`
+		Nodes       map[string]*NodeInfo `json:"nodes"`
+	}
+
+	NodeInfo struct {
+		Name  string    `json:"name"`
+		Roles []string  `json:"roles"`
+		HTTP  *NodeHTTP `json:"http,omitempty"`
+	}
+
+	NodeHTTP struct {
+		// host:port, optionally prefixed by the hostname as in hostname/ip:port
+		PublishAddress string `json:"publish_address"`
+	}
+
+	// Total number of hits of a search, elasticsearch 7 reports an object
+	// while earlier versions report a plain number
+	TotalHits struct {
+		Value    int    `json:"value"`
+		Relation string `json:"relation"`
 	}
 
 	ElasticsearchError struct {
-		RootCause []ErrorDescription `json:"root_cause"`
-		Type      string             `json:"type"`
-		Reason    string             `json:"reason"`
+		RootCause    []ErrorDescription `json:"root_cause,omitempty"`
+		Type         string             `json:"type"`
+		Reason       string             `json:"reason"`
+		Index        string             `json:"index,omitempty"`
+		CausedBy     *ErrorDescription  `json:"caused_by,omitempty"`
+		FailedShards []ShardFailure     `json:"failed_shards,omitempty"`
 	}
 
 	ErrorDescription struct {
-		Type   string `json:"type"`
-		Reason string `json:"reason"`
+		Type     string            `json:"type"`
+		Reason   string            `json:"reason"`
+		Index    string            `json:"index,omitempty"`
+		CausedBy *ErrorDescription `json:"caused_by,omitempty"`
+	}
+
+	// Describes the failure of a single shard within a larger request
+	ShardFailure struct {
+		Shard  int               `json:"shard"`
+		Index  string            `json:"index"`
+		Node   string            `json:"node"`
+		Reason *ErrorDescription `json:"reason"`
+	}
+
+	// The body elasticsearch returns alongside a non 2xx status code
+	ErrorResponse struct {
+		Error  *ElasticsearchError `json:"error"`
+		Status int                 `json:"status"`
 	}
 
 	ElasticsearchErrors struct {
@@ -110,3 +216,31 @@ type (
 		Status int                `json:"status"`
 	}
 )
+
+// UnmarshalJSON accepts both the numeric and object forms of hits.total
+func (t *TotalHits) UnmarshalJSON(data []byte) error {
+	var value int
+
+	if err := json.Unmarshal(data, &value); err == nil {
+		t.Value = value
+		t.Relation = "eq"
+		return nil
+	}
+
+	type totalHits TotalHits
+	return json.Unmarshal(data, (*totalHits)(t))
+}
+
+// the result of the single action set on the operation
+func (op *Operation) item() *Generic {
+	switch {
+	case op.Index != nil:
+		return op.Index
+	case op.Create != nil:
+		return op.Create
+	case op.Update != nil:
+		return op.Update
+	}
+
+	return op.Delete
+}
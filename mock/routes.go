@@ -3,6 +3,7 @@ package mock
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"github.com/gorilla/mux"
 	"io/ioutil"
 	"net/http"
@@ -12,66 +13,68 @@ import (
 // individual bulk request operations and their payloads
 // note the request body is NDJSON not regular JSON
 func parseBulkRequest(body []byte) ([]*Operation, [][]byte, error) {
-	// parse the first object of the body and determine if
-	// this is a bulk delete operation (which must be handled separately)
-	objects := bytes.Split(body, []byte("\n"))
+	lines := bytes.Split(body, []byte("\n"))
+	operations := []*Operation{}
+	payloads := [][]byte{}
+
+	// every action is followed by a payload except for delete actions, which
+	// may be mixed freely with the others
+	for i := 0; i < len(lines); i++ {
+		// the last line is empty, conforming with elasticsearch's implementation of NDJSON...
+		if len(bytes.TrimSpace(lines[i])) == 0 {
+			continue
+		}
 
-	firstOperation := &Operation{}
-	err := json.Unmarshal(objects[0], firstOperation)
+		op := &Operation{}
+		err := json.Unmarshal(lines[i], op)
 
-	if err != nil {
-		return nil, nil, err
-	}
-
-	// objects are formatted as a series of JSON objects in an alternating
-	// pattern of operation, payload, operation, payload
-	// except for delete operations which do not contain payloads
-	// thus we must only process operations for pure delete requests
-	var operations []*Operation
-	var payloads [][]byte
-	if firstOperation.Delete != nil {
-		// the last object is an empty line, conforming with elasticsearch's implementation of NDJSON...
-		operations = make([]*Operation, len(objects)-1)
-		payloads = make([][]byte, 0)
-
-		// process each object into an operation
-		for idx, object := range objects {
-			// handle the edge case of a blank line
-			if len(object) == 0 {
-				continue
-			}
+		if err != nil {
+			return nil, nil, err
+		}
 
-			op := &Operation{}
-			err := json.Unmarshal(object, op)
+		var payload []byte
+		if op.Delete == nil {
+			i++
 
-			if err != nil {
-				return nil, nil, err
+			if i >= len(lines) || len(bytes.TrimSpace(lines[i])) == 0 {
+				return nil, nil, errors.New("Bulk action is missing its payload.")
 			}
 
-			operations[idx] = op
+			payload = lines[i]
 		}
 
-		// for all other cases we split objects into a slice
-		// operations containing all odd-indexed elements of objects
-		// and payloads containing all even-indexed elements
-	} else {
-		operations = make([]*Operation, len(objects)/2)
-		payloads = make([][]byte, len(objects)/2)
+		operations = append(operations, op)
+		payloads = append(payloads, payload)
+	}
 
-		for i := 0; i < len(objects)/2; i++ {
-			op := new(*Operation)
-			err := json.Unmarshal(objects[i*2], op)
+	return operations, payloads, nil
+}
 
-			if err != nil {
-				return nil, nil, err
-			}
+// write an error in the format elasticsearch uses for failed requests
+func writeError(w http.ResponseWriter, status int, errorType string, reason string, index string) {
+	description := &ElasticsearchError{Type: errorType, Reason: reason, Index: index}
+	description.RootCause = []ErrorDescription{{Type: errorType, Reason: reason, Index: index}}
 
-			operations[i] = *op
-			payloads[i] = objects[i*2+1]
-		}
+	js, err := json.Marshal(&ErrorResponse{Error: description, Status: status})
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(js)
+}
+
+// write a search error, translating store errors into their elasticsearch equivalents
+func writeSearchError(w http.ResponseWriter, err error, index string) {
+	if err == errIndexNotFound {
+		writeError(w, http.StatusNotFound, "index_not_found_exception", err.Error(), index)
+		return
 	}
 
-	return operations, payloads, err
+	writeError(w, http.StatusBadRequest, "search_phase_execution_exception", err.Error(), index)
 }
 
 func BulkAPI(w http.ResponseWriter, req *http.Request) {
@@ -79,63 +82,138 @@ func BulkAPI(w http.ResponseWriter, req *http.Request) {
 
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
 	operations, payloads, err := parseBulkRequest(contents)
 
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "illegal_argument_exception", err.Error(), "")
+		return
 	}
 
+	response := &Generic{Items: operations}
+	vars := mux.Vars(req)
+
+	// unlike the other APIs a failing action does not fail the request, its
+	// error is reported within the item of the response
 	for idx, operation := range operations {
-		// handle bulk insert operations
-		if operation.Index != nil {
-			payload := payloads[idx]
-			doc, err := database.insert(operation.Index.Index, operation.Index.Type, payload)
-
-			if err != nil {
-				// return without executing any operations
-				// this does not match elasticsearch's process
-				http.Error(w, "testing", http.StatusBadRequest)
-				return
-			}
+		item := operation.item()
 
-			operation.Index.Created = true
-			operation.Index.ID = doc.ID
+		if item == nil {
+			writeError(w, http.StatusBadRequest, "illegal_argument_exception", "Unknown bulk action.", "")
+			return
+		}
 
-			// handle bulk update operations
-		} else if operation.Update != nil {
-			payload := payloads[idx]
-			updated, err := database.upsertDocument(operation.Update.Index, operation.Update.Type, operation.Update.ID, payload)
+		// actions default to the index and type of the request path
+		if item.Index == "" {
+			item.Index = vars["index"]
+		}
 
-			if err != nil {
-				// return without executing any operations
-				// this does not match elasticsearch's process
-				http.Error(w, "testing", http.StatusBadRequest)
-				return
-			}
+		if item.Type == "" {
+			item.Type = vars["_type"]
+		}
 
-			operation.Update.Created = !updated
+		if database.isClosed(item.Index) {
+			setItemError(item, http.StatusBadRequest, "index_closed_exception", "closed")
+			response.Errors = true
+			continue
+		}
 
-		} else if operation.Delete != nil {
-			deleted := database.deleteDocument(operation.Delete.Index, operation.Delete.Type, operation.Delete.ID)
-			operation.Delete.Found = deleted
+		switch {
+		case operation.Index != nil:
+			bulkIndex(item, payloads[idx], false)
+		case operation.Create != nil:
+			bulkIndex(item, payloads[idx], true)
+		case operation.Update != nil:
+			bulkUpdate(item, payloads[idx])
+		case operation.Delete != nil:
+			item.Found = database.deleteDocument(item.Index, item.Type, item.ID)
+			item.Status, item.Result = http.StatusOK, "deleted"
+
+			if !item.Found {
+				item.Status, item.Result = http.StatusNotFound, "not_found"
+			}
 		}
+
+		response.Errors = response.Errors || item.Error.Type != ""
 	}
 
-	response := &Generic{Items: operations}
 	js, err := json.Marshal(response)
 
 	if err != nil {
-		// return without executing any operations
-		// this does not match elasticsearch's process
-		http.Error(w, "testing", http.StatusBadRequest)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(js)
 }
 
+// execute a bulk index or create action, recording the outcome on its item
+func bulkIndex(item *Generic, payload []byte, create bool) {
+	doc, created, err := database.index(item.Index, item.Type, item.ID, payload, create)
+
+	switch {
+	case err == errDocumentExists:
+		setItemError(item, http.StatusConflict, "version_conflict_engine_exception", err.Error())
+	case err != nil:
+		setItemError(item, http.StatusBadRequest, "mapper_parsing_exception", err.Error())
+	case created:
+		item.ID, item.Created, item.Status, item.Result = doc.ID, true, http.StatusCreated, "created"
+		doc.versioned(item)
+	default:
+		item.ID, item.Status, item.Result = doc.ID, http.StatusOK, "updated"
+		doc.versioned(item)
+	}
+}
+
+// execute a bulk update action, recording the outcome on its item
+func bulkUpdate(item *Generic, payload []byte) {
+	update := &BulkUpdatePayload{}
+
+	if err := json.Unmarshal(payload, update); err != nil {
+		setItemError(item, http.StatusBadRequest, "parsing_exception", err.Error())
+		return
+	}
+
+	doc, result, err := database.update(item.Index, item.Type, item.ID, update, nil)
+
+	if err != nil {
+		status, errorType := updateError(err)
+		setItemError(item, status, errorType, "["+item.ID+"]: "+err.Error())
+		return
+	}
+
+	item.Status, item.Result = http.StatusOK, result
+	doc.versioned(item)
+
+	if result == "created" {
+		item.Created, item.Status = true, http.StatusCreated
+	}
+}
+
+// the status and exception type elasticsearch reports a failed update with
+func updateError(err error) (int, string) {
+	if _, ok := err.(*versionConflict); ok {
+		return http.StatusConflict, "version_conflict_engine_exception"
+	}
+
+	switch err {
+	case errDocumentMissing:
+		return http.StatusNotFound, "document_missing_exception"
+	case errUnsupportedScript:
+		return http.StatusBadRequest, "script_exception"
+	}
+
+	return http.StatusBadRequest, "mapper_parsing_exception"
+}
+
+func setItemError(item *Generic, status int, errorType string, reason string) {
+	item.Status = status
+	item.Error = ElasticsearchError{Type: errorType, Reason: reason, Index: item.Index}
+}
+
 func DeleteIndex(w http.ResponseWriter, req *http.Request) {
 	vars := mux.Vars(req)
 	index := vars["index"]
@@ -166,13 +244,68 @@ func SearchIndex(w http.ResponseWriter, req *http.Request) {
 	hits, err := database.searchIndex(index)
 
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeSearchError(w, err, index)
+		return
+	}
+
+	writeSearchResponse(w, req, hits, index)
+}
+
+func SearchType(w http.ResponseWriter, req *http.Request) {
+	// todo: fix nomenclature
+	vars := mux.Vars(req)
+	index := vars["index"]
+	_type := vars["_type"]
+
+	hits, err := database.searchType(index, _type)
+
+	if err != nil {
+		writeSearchError(w, err, index)
+		return
+	}
+
+	writeSearchResponse(w, req, hits, index)
+}
+
+// apply the parameters of a search request to the matching hits and write
+// the page, opening a scroll over the remaining hits if one was requested
+func writeSearchResponse(w http.ResponseWriter, req *http.Request, hits []*SearchHit, index string) {
+	search, err := parseSearchRequest(req)
+
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "parsing_exception", err.Error(), index)
 		return
 	}
 
-	resp := Generic{}
+	resp := &Generic{}
+	hits = search.order(hits)
+	total := len(hits)
+	page := search.page(hits, search.from())
+
+	if req.URL.Query().Get("scroll") != "" {
+		next := search.from() + len(page)
+
+		if next > len(hits) {
+			next = len(hits)
+		}
+
+		resp.ScrollID = database.openScroll(search, hits[next:], total)
+	}
+
+	if search.Pit != nil {
+		resp.PitID = search.Pit.ID
+	}
+
+	writeHits(w, resp, search, page, total)
+}
+
+func writeHits(w http.ResponseWriter, resp *Generic, search *searchRequest, hits []*SearchHit, total int) {
+	for _, hit := range hits {
+		search.filterSource(hit)
+	}
+
 	resp.Hits.Hits = hits
-	resp.Hits.Total = len(hits)
+	resp.Hits.Total = TotalHits{Value: total, Relation: "eq"}
 
 	js, err := json.Marshal(resp)
 
@@ -185,30 +318,160 @@ func SearchIndex(w http.ResponseWriter, req *http.Request) {
 	w.Write(js)
 }
 
-func SearchType(w http.ResponseWriter, req *http.Request) {
-	// todo: fix nomenclature
-	vars := mux.Vars(req)
-	index := vars["index"]
-	_type := vars["_type"]
+// search without an index in the path, which is how points in time are searched
+func Search(w http.ResponseWriter, req *http.Request) {
+	contents, err := ioutil.ReadAll(req.Body)
 
-	hits, err := database.searchType(index, _type)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	search := &searchRequest{}
+
+	if len(contents) > 0 {
+		if err := json.Unmarshal(contents, search); err != nil {
+			writeError(w, http.StatusBadRequest, "parsing_exception", err.Error(), "")
+			return
+		}
+	}
+
+	if search.Pit == nil {
+		writeError(w, http.StatusBadRequest, "action_request_validation_exception", "the mock only supports searching every index through a point in time", "")
+		return
+	}
+
+	index, exists := database.pointInTime(search.Pit.ID)
+
+	if !exists {
+		writeError(w, http.StatusNotFound, "search_context_missing_exception", "No search context found for id ["+search.Pit.ID+"]", "")
+		return
+	}
+
+	hits, err := database.searchIndex(index)
+
+	if err != nil {
+		writeSearchError(w, err, index)
+		return
+	}
+
+	// parse the body a second time as part of the full search request
+	req.Body = ioutil.NopCloser(bytes.NewReader(contents))
+	writeSearchResponse(w, req, hits, index)
+}
+
+func Scroll(w http.ResponseWriter, req *http.Request) {
+	scrollRequest := &ScrollRequest{}
+	err := json.NewDecoder(req.Body).Decode(scrollRequest)
+
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "parsing_exception", err.Error(), "")
+		return
+	}
+
+	search, hits, total, exists := database.nextScroll(scrollRequest.ScrollID)
+
+	if !exists {
+		writeError(w, http.StatusNotFound, "search_context_missing_exception", "No search context found for id ["+scrollRequest.ScrollID+"]", "")
+		return
+	}
+
+	writeHits(w, &Generic{ScrollID: scrollRequest.ScrollID}, search, hits, total)
+}
+
+func ClearScroll(w http.ResponseWriter, req *http.Request) {
+	clear := &struct {
+		ScrollID json.RawMessage `json:"scroll_id"`
+	}{}
+
+	if err := json.NewDecoder(req.Body).Decode(clear); err != nil {
+		writeError(w, http.StatusBadRequest, "parsing_exception", err.Error(), "")
+		return
+	}
+
+	// the scroll_id may either be a single ID or a list of them
+	IDs := []string{}
+	if err := json.Unmarshal(clear.ScrollID, &IDs); err != nil {
+		var ID string
+		json.Unmarshal(clear.ScrollID, &ID)
+		IDs = append(IDs, ID)
+	}
+
+	freed := database.clearScrolls(IDs)
+	writeClearResponse(w, freed)
+}
+
+func OpenPointInTime(w http.ResponseWriter, req *http.Request) {
+	index := mux.Vars(req)["index"]
+	ID, err := database.openPointInTime(index)
+
+	if err != nil {
+		writeSearchError(w, err, index)
+		return
+	}
+
+	js, err := json.Marshal(&PointInTime{ID: ID})
 
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	resp := Generic{}
-	resp.Hits.Hits = hits
-	resp.Hits.Total = len(resp.Hits.Hits)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(js)
+}
 
-	js, err := json.Marshal(resp)
+func ClosePointInTime(w http.ResponseWriter, req *http.Request) {
+	pit := &PointInTime{}
+
+	if err := json.NewDecoder(req.Body).Decode(pit); err != nil {
+		writeError(w, http.StatusBadRequest, "parsing_exception", err.Error(), "")
+		return
+	}
+
+	freed := 0
+	if database.closePointInTime(pit.ID) {
+		freed = 1
+	}
+
+	writeClearResponse(w, freed)
+}
+
+func writeClearResponse(w http.ResponseWriter, freed int) {
+	js, err := json.Marshal(&ClearResponse{Succeeded: true, NumFreed: freed})
 
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+
+	// elasticsearch reports releasing nothing as not found
+	if freed == 0 {
+		w.WriteHeader(http.StatusNotFound)
+	}
+
+	w.Write(js)
+}
+
+// Nodes describes a fake cluster made of the mock itself, a second data node
+// and a dedicated master node, neither of which exist
+func Nodes(w http.ResponseWriter, req *http.Request) {
+	js, err := json.Marshal(&NodesInfo{
+		ClusterName: "mock",
+		Nodes: map[string]*NodeInfo{
+			"mock-1": {Name: "mock-1", Roles: []string{"data", "ingest", "master"}, HTTP: &NodeHTTP{PublishAddress: req.Host}},
+			"mock-2": {Name: "mock-2", Roles: []string{"data"}, HTTP: &NodeHTTP{PublishAddress: "mock-2/127.0.0.1:9202"}},
+			"mock-3": {Name: "mock-3", Roles: []string{"master"}, HTTP: &NodeHTTP{PublishAddress: "127.0.0.1:9203"}},
+		},
+	})
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(js)
 }
@@ -241,6 +504,8 @@ func InsertDocument(w http.ResponseWriter, req *http.Request) {
 		Result:  "created",
 	}
 
+	doc.versioned(&resp)
+
 	js, err := json.Marshal(resp)
 
 	if err != nil {
@@ -277,6 +542,8 @@ func GetDocumentByID(w http.ResponseWriter, req *http.Request) {
 			Source: body,
 		}
 
+		doc.versioned(&resp)
+
 		js, err := json.Marshal(resp)
 
 		if err != nil {
@@ -303,6 +570,7 @@ func GetDocumentByID(w http.ResponseWriter, req *http.Request) {
 		}
 
 		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
 		w.Write(js)
 	}
 }
@@ -320,7 +588,19 @@ func UpdateDocumentByID(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	updated, err := database.upsertDocument(index, _type, ID, body)
+	conditions, err := conditionsFromQuery(req.URL.Query())
+
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "illegal_argument_exception", err.Error(), index)
+		return
+	}
+
+	doc, updated, err := database.replaceDocument(index, _type, ID, body, conditions)
+
+	if conflict, ok := err.(*versionConflict); ok {
+		writeError(w, http.StatusConflict, "version_conflict_engine_exception", conflict.reason, index)
+		return
+	}
 
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -340,6 +620,8 @@ func UpdateDocumentByID(w http.ResponseWriter, req *http.Request) {
 		Result:  result,
 	}
 
+	doc.versioned(resp)
+
 	js, err := json.Marshal(resp)
 
 	if err != nil {
@@ -348,6 +630,66 @@ func UpdateDocumentByID(w http.ResponseWriter, req *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+
+	if !updated {
+		w.WriteHeader(http.StatusCreated)
+	}
+
+	w.Write(js)
+}
+
+// the update API, applying a partial document, an upsert or a script
+func UpdateAPI(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	index := vars["index"]
+	_type := vars["_type"]
+	ID := vars["id"]
+
+	update := &BulkUpdatePayload{}
+
+	if err := json.NewDecoder(req.Body).Decode(update); err != nil {
+		writeError(w, http.StatusBadRequest, "parsing_exception", err.Error(), index)
+		return
+	}
+
+	conditions, err := conditionsFromQuery(req.URL.Query())
+
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "illegal_argument_exception", err.Error(), index)
+		return
+	}
+
+	// retry_on_conflict is accepted but never needed, the store applies updates atomically
+	doc, result, err := database.update(index, _type, ID, update, conditions)
+
+	if err != nil {
+		reason := err.Error()
+
+		if _, ok := err.(*versionConflict); !ok {
+			reason = "[" + ID + "]: " + reason
+		}
+
+		status, errorType := updateError(err)
+		writeError(w, status, errorType, reason, index)
+		return
+	}
+
+	resp := &Generic{Index: index, Type: _type, ID: ID, Result: result, Created: result == "created"}
+	doc.versioned(resp)
+
+	js, err := json.Marshal(resp)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if resp.Created {
+		w.WriteHeader(http.StatusCreated)
+	}
+
 	w.Write(js)
 }
 
@@ -357,13 +699,32 @@ func DeleteDocumentByID(w http.ResponseWriter, req *http.Request) {
 	_type := vars["_type"]
 	ID := vars["id"]
 
-	deleted := database.deleteDocument(index, _type, ID)
+	conditions, err := conditionsFromQuery(req.URL.Query())
+
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "illegal_argument_exception", err.Error(), index)
+		return
+	}
+
+	deleted, err := database.deleteDocumentIf(index, _type, ID, conditions)
+
+	if conflict, ok := err.(*versionConflict); ok {
+		writeError(w, http.StatusConflict, "version_conflict_engine_exception", conflict.reason, index)
+		return
+	}
 
 	resp := &Generic{
-		Found: deleted,
-		ID:    ID,
-		Index: index,
-		Type:  _type,
+		Found:  deleted,
+		ID:     ID,
+		Index:  index,
+		Type:   _type,
+		Result: "deleted",
+	}
+
+	status := http.StatusOK
+	if !deleted {
+		resp.Result = "not_found"
+		status = http.StatusNotFound
 	}
 
 	js, err := json.Marshal(resp)
@@ -374,5 +735,6 @@ func DeleteDocumentByID(w http.ResponseWriter, req *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
 	w.Write(js)
 }
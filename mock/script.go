@@ -0,0 +1,109 @@
+package mock
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+)
+
+var (
+	errUnsupportedScript = errors.New("only assignments to and removals of ctx._source fields are supported")
+
+	// ctx._source.field = value, ctx._source.field += value or ctx._source.field -= value
+	assignment = regexp.MustCompile(`^ctx\._source\.(\w+)\s*(=|\+=|-=)\s*(.+)$`)
+	// ctx._source.remove('field')
+	removal = regexp.MustCompile(`^ctx\._source\.remove\(\s*['"](\w+)['"]\s*\)$`)
+)
+
+// run a script against the source of a document. Statements are separated by
+// semicolons and values are either params.name or a literal.
+func runScript(script *Script, source map[string]json.RawMessage) error {
+	if script.Lang != "" && script.Lang != "painless" {
+		return errUnsupportedScript
+	}
+
+	for _, statement := range strings.Split(script.Source, ";") {
+		statement = strings.TrimSpace(statement)
+
+		if statement == "" {
+			continue
+		}
+
+		if match := removal.FindStringSubmatch(statement); match != nil {
+			delete(source, match[1])
+			continue
+		}
+
+		match := assignment.FindStringSubmatch(statement)
+
+		if match == nil {
+			return errUnsupportedScript
+		}
+
+		value, err := scriptValue(script, strings.TrimSpace(match[3]))
+
+		if err != nil {
+			return err
+		}
+
+		if match[2] != "=" {
+			var current interface{}
+			json.Unmarshal(source[match[1]], &current)
+
+			if value, err = combine(current, value, match[2]); err != nil {
+				return err
+			}
+		}
+
+		if source[match[1]], err = json.Marshal(value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// the value of a params reference or a literal, strings may use single quotes
+func scriptValue(script *Script, expression string) (interface{}, error) {
+	if strings.HasPrefix(expression, "params.") {
+		value, exists := script.Params[strings.TrimPrefix(expression, "params.")]
+
+		if !exists {
+			return nil, errors.New("missing parameter " + expression)
+		}
+
+		return value, nil
+	}
+
+	if len(expression) >= 2 && expression[0] == '\'' && expression[len(expression)-1] == '\'' {
+		return expression[1 : len(expression)-1], nil
+	}
+
+	var value interface{}
+
+	if err := json.Unmarshal([]byte(expression), &value); err != nil {
+		return nil, errUnsupportedScript
+	}
+
+	return value, nil
+}
+
+// apply += or -= to numbers, += also concatenates strings
+func combine(current interface{}, value interface{}, operator string) (interface{}, error) {
+	a, aIsNumber := current.(float64)
+	b, bIsNumber := value.(float64)
+
+	switch {
+	case aIsNumber && bIsNumber && operator == "+=":
+		return a + b, nil
+	case aIsNumber && bIsNumber:
+		return a - b, nil
+	case operator == "+=":
+		if s, ok := current.(string); ok {
+			return s + toString(value), nil
+		}
+	}
+
+	return nil, errUnsupportedScript
+}
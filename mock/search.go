@@ -0,0 +1,310 @@
+package mock
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const defaultSearchSize = 10
+
+// the subset of a search request understood by the mock, queries themselves
+// are not evaluated and every document of the searched index or type matches
+type searchRequest struct {
+	From        *int            `json:"from"`
+	Size        *int            `json:"size"`
+	Sort        []interface{}   `json:"sort"`
+	Source      json.RawMessage `json:"_source"`
+	SearchAfter []interface{}   `json:"search_after"`
+	Pit         *PointInTime    `json:"pit"`
+
+	sortFields []sortField
+	includes   []string
+	excludes   []string
+	noSource   bool
+}
+
+type sortField struct {
+	field string
+	desc  bool
+}
+
+// read the search parameters from the request body and querystring
+func parseSearchRequest(req *http.Request) (*searchRequest, error) {
+	search := &searchRequest{}
+	body, err := ioutil.ReadAll(req.Body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, search); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, param := range []struct {
+		name  string
+		value **int
+	}{{"from", &search.From}, {"size", &search.Size}} {
+		if raw := req.URL.Query().Get(param.name); raw != "" {
+			value, err := strconv.Atoi(raw)
+
+			if err != nil {
+				return nil, err
+			}
+
+			*param.value = &value
+		}
+	}
+
+	search.parseSort()
+	return search, search.parseSource()
+}
+
+// sort clauses are either a field name, {field: order} or {field: {order: order}}
+func (s *searchRequest) parseSort() {
+	for _, clause := range s.Sort {
+		switch c := clause.(type) {
+		case string:
+			s.sortFields = append(s.sortFields, sortField{field: c})
+		case map[string]interface{}:
+			for field, options := range c {
+				order, _ := options.(string)
+
+				if params, ok := options.(map[string]interface{}); ok {
+					order, _ = params["order"].(string)
+				}
+
+				s.sortFields = append(s.sortFields, sortField{field: field, desc: order == "desc"})
+			}
+		}
+	}
+}
+
+// _source is either a boolean, a field, a list of fields or {includes, excludes}
+func (s *searchRequest) parseSource() error {
+	if len(s.Source) == 0 {
+		return nil
+	}
+
+	var enabled bool
+	if err := json.Unmarshal(s.Source, &enabled); err == nil {
+		s.noSource = !enabled
+		return nil
+	}
+
+	var field string
+	if err := json.Unmarshal(s.Source, &field); err == nil {
+		s.includes = []string{field}
+		return nil
+	}
+
+	if err := json.Unmarshal(s.Source, &s.includes); err == nil {
+		return nil
+	}
+
+	filter := &struct {
+		Includes []string `json:"includes"`
+		Excludes []string `json:"excludes"`
+	}{}
+
+	if err := json.Unmarshal(s.Source, filter); err != nil {
+		return err
+	}
+
+	s.includes, s.excludes = filter.Includes, filter.Excludes
+	return nil
+}
+
+// apply sorting, pagination and source filtering to every matching hit,
+// returning the total number of matches alongside the requested page
+func (s *searchRequest) apply(hits []*SearchHit) (int, []*SearchHit) {
+	hits = s.order(hits)
+	total := len(hits)
+	hits = s.page(hits, s.from())
+
+	for _, hit := range hits {
+		s.filterSource(hit)
+	}
+
+	return total, hits
+}
+
+// sort the hits and drop those preceding search_after
+func (s *searchRequest) order(hits []*SearchHit) []*SearchHit {
+	// documents are ordered by ID unless told otherwise to keep pages stable
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].ID < hits[j].ID })
+
+	if len(s.sortFields) == 0 {
+		return hits
+	}
+
+	for _, hit := range hits {
+		hit.Sort = make([]interface{}, len(s.sortFields))
+
+		for i, field := range s.sortFields {
+			hit.Sort[i] = sortValue(hit, field.field)
+		}
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		return s.compareSort(hits[i].Sort, hits[j].Sort) < 0
+	})
+
+	if len(s.SearchAfter) == 0 {
+		return hits
+	}
+
+	after := []*SearchHit{}
+
+	for _, hit := range hits {
+		if s.compareSort(hit.Sort, s.SearchAfter) > 0 {
+			after = append(after, hit)
+		}
+	}
+
+	return after
+}
+
+// compare two lists of sort values in the order requested
+func (s *searchRequest) compareSort(a []interface{}, b []interface{}) int {
+	for k, field := range s.sortFields {
+		if k >= len(a) || k >= len(b) {
+			break
+		}
+
+		cmp := compareValues(a[k], b[k])
+
+		if field.desc {
+			cmp = -cmp
+		}
+
+		if cmp != 0 {
+			return cmp
+		}
+	}
+
+	return 0
+}
+
+func (s *searchRequest) from() int {
+	if s.From != nil {
+		return *s.From
+	}
+
+	return 0
+}
+
+func (s *searchRequest) size() int {
+	if s.Size != nil {
+		return *s.Size
+	}
+
+	return defaultSearchSize
+}
+
+// the hits of the page of the requested size starting at from
+func (s *searchRequest) page(hits []*SearchHit, from int) []*SearchHit {
+	size := s.size()
+
+	if from > len(hits) {
+		from = len(hits)
+	}
+
+	if from+size < len(hits) {
+		return hits[from : from+size]
+	}
+
+	return hits[from:]
+}
+
+func (s *searchRequest) filterSource(hit *SearchHit) {
+	if s.noSource {
+		hit.Source = nil
+		return
+	}
+
+	if len(s.includes) == 0 && len(s.excludes) == 0 {
+		return
+	}
+
+	fields := map[string]json.RawMessage{}
+	json.Unmarshal(hit.Source, &fields)
+
+	for field := range fields {
+		if (len(s.includes) > 0 && !contains(s.includes, field)) || contains(s.excludes, field) {
+			delete(fields, field)
+		}
+	}
+
+	hit.Source, _ = json.Marshal(fields)
+}
+
+func contains(fields []string, field string) bool {
+	for _, f := range fields {
+		if f == field || (strings.HasSuffix(f, "*") && strings.HasPrefix(field, strings.TrimSuffix(f, "*"))) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// the value a hit is sorted on, only top level fields of the source are supported
+func sortValue(hit *SearchHit, field string) interface{} {
+	switch field {
+	case "_id", "_doc", "_shard_doc":
+		return hit.ID
+	case "_score":
+		return hit.Score
+	}
+
+	fields := map[string]interface{}{}
+	json.Unmarshal(hit.Source, &fields)
+	return fields[field]
+}
+
+// order numbers before strings and missing values last
+func compareValues(a interface{}, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+
+	af, aIsNumber := a.(float64)
+	bf, bIsNumber := b.(float64)
+
+	switch {
+	case aIsNumber && bIsNumber:
+		if af < bf {
+			return -1
+		} else if af > bf {
+			return 1
+		}
+		return 0
+	case aIsNumber:
+		return -1
+	case bIsNumber:
+		return 1
+	}
+
+	return strings.Compare(toString(a), toString(b))
+}
+
+func toString(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+
+	js, _ := json.Marshal(value)
+	return string(js)
+}
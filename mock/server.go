@@ -26,16 +26,36 @@ func New() *http.Server {
 	router := mux.NewRouter().StrictSlash(true)
 
 	router.HandleFunc("/_bulk", BulkAPI).Methods("POST").Queries()
+	router.HandleFunc("/_search", Search).Methods("GET", "POST")
+	router.HandleFunc("/_search/scroll", Scroll).Methods("GET", "POST")
+	router.HandleFunc("/_search/scroll", ClearScroll).Methods("DELETE")
+	router.HandleFunc("/_nodes/http", Nodes).Methods("GET")
+	router.HandleFunc("/_pit", ClosePointInTime).Methods("DELETE")
+	router.HandleFunc("/{index}/_pit", requireOpen(OpenPointInTime)).Methods("POST")
 	router.HandleFunc("/{index}", DeleteIndex).Methods("DELETE")
-	router.HandleFunc("/{index}/_search", SearchIndex).Methods("GET")
-	router.HandleFunc("/{index}/{_type}/_search", SearchType).Methods("GET")
-	router.HandleFunc("/{index}/{_type}", InsertDocument).Methods("POST")
-	router.HandleFunc("/{index}/{_type}/{id}", GetDocumentByID).Methods("GET")
-	router.HandleFunc("/{index}/{_type}/{id}", UpdateDocumentByID).Methods("PUT")
-	router.HandleFunc("/{index}/{_type}/{id}", DeleteDocumentByID).Methods("DELETE")
+	router.HandleFunc("/{index}", CreateIndex).Methods("PUT")
+	router.HandleFunc("/{index}", IndexExists).Methods("HEAD")
+	router.HandleFunc("/{index}/_close", CloseIndex).Methods("POST")
+	router.HandleFunc("/{index}/_open", OpenIndex).Methods("POST")
+	router.HandleFunc("/{index}/_settings", GetSettings).Methods("GET")
+	router.HandleFunc("/{index}/_settings", PutSettings).Methods("PUT")
+	router.HandleFunc("/{index}/_mapping", GetMapping).Methods("GET")
+	router.HandleFunc("/{index}/_mapping", PutMapping).Methods("PUT")
+	router.HandleFunc("/{index}/_refresh", requireOpen(IndexMaintenance)).Methods("GET", "POST")
+	router.HandleFunc("/{index}/_flush", requireOpen(IndexMaintenance)).Methods("GET", "POST")
+	router.HandleFunc("/{index}/_forcemerge", requireOpen(IndexMaintenance)).Methods("POST")
+	router.HandleFunc("/{index}/_search", requireOpen(SearchIndex)).Methods("GET", "POST")
+	router.HandleFunc("/{index}/{_type}/_search", requireOpen(SearchType)).Methods("GET", "POST")
+	router.HandleFunc("/{index}/_bulk", BulkAPI).Methods("POST")
+	router.HandleFunc("/{index}/{_type}/_bulk", BulkAPI).Methods("POST")
+	router.HandleFunc("/{index}/{_type}", requireOpen(InsertDocument)).Methods("POST")
+	router.HandleFunc("/{index}/{_type}/{id}", requireOpen(GetDocumentByID)).Methods("GET")
+	router.HandleFunc("/{index}/{_type}/{id}", requireOpen(UpdateDocumentByID)).Methods("PUT")
+	router.HandleFunc("/{index}/{_type}/{id}", requireOpen(DeleteDocumentByID)).Methods("DELETE")
+	router.HandleFunc("/{index}/{_type}/{id}/_update", requireOpen(UpdateAPI)).Methods("POST")
 
 	return &http.Server{
-		Handler: router,
+		Handler: Gzip(router),
 		Addr:    "127.0.0.1:9201",
 	}
 }
@@ -1,11 +1,18 @@
 package mock
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"sync"
 )
 
+var (
+	errIndexNotFound   = errors.New("no such index")
+	errDocumentExists  = errors.New("version conflict, document already exists")
+	errDocumentMissing = errors.New("document missing")
+)
+
 // store provides the facilities for replicating in-memory operations
 // via a flat map structure
 type store struct {
@@ -13,11 +20,33 @@ type store struct {
 
 	// index:type:ids:document
 	Indexes map[string]map[string]map[string]*Document
+
+	// settings, mappings and state of every index in Indexes
+	indices map[string]*indexMeta
+
+	// open scroll contexts by scroll ID
+	scrolls map[string]*scroll
+
+	// indices of open points in time by ID
+	pointsInTime map[string]string
+
+	// sequence number of the next change of any document
+	seqNo int64
+}
+
+// the remaining hits of a scroll, snapshotted when it was opened
+type scroll struct {
+	search *searchRequest
+	hits   []*SearchHit
+	total  int
 }
 
 func newStore() *store {
 	return &store{
-		Indexes: make(map[string]map[string]map[string]*Document),
+		Indexes:      make(map[string]map[string]map[string]*Document),
+		indices:      make(map[string]*indexMeta),
+		scrolls:      make(map[string]*scroll),
+		pointsInTime: make(map[string]string),
 	}
 }
 
@@ -28,6 +57,7 @@ func (s *store) getOrCreateIndex(name string) map[string]map[string]*Document {
 	if !exists {
 		index = make(map[string]map[string]*Document)
 		s.Indexes[name] = index
+		s.indices[name] = newIndexMeta()
 	}
 	return index
 }
@@ -49,16 +79,112 @@ func (s *store) insert(index string, _type string, payload []byte) (*Document, e
 	s.Lock()
 	defer s.Unlock()
 
-	document := &Document{ID: ULID()}
+	document, _, err := s.put(index, _type, ULID(), payload)
+	return document, err
+}
+
+// replace or create a document with the given ID, should only be called in a
+// safe (locked) context
+func (s *store) put(index string, _type string, ID string, payload []byte) (*Document, bool, error) {
+	document := &Document{ID: ID}
 	err := json.Unmarshal(payload, &document.Body)
 
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
 	collection := s.getOrCreateType(index, _type)
-	collection[document.ID] = document
-	return document, nil
+	previous, exists := collection[ID]
+	s.changed(document, previous, nil)
+	collection[ID] = document
+	return document, !exists, nil
+}
+
+// index a document as the bulk index or create action would, generating an
+// ID if none is given. Returns whether the document was newly created.
+func (s *store) index(index string, _type string, ID string, payload []byte, create bool) (*Document, bool, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if ID == "" {
+		ID = ULID()
+	}
+
+	if _, exists := s.Indexes[index][_type][ID]; exists && create {
+		return nil, false, errDocumentExists
+	}
+
+	return s.put(index, _type, ID, payload)
+}
+
+// apply a partial update as the update API would, returns the document and
+// whether it was created, updated or left unchanged: "created", "updated" or "noop"
+func (s *store) update(index string, _type string, ID string, update *BulkUpdatePayload, c *conditions) (*Document, string, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	document, exists := s.Indexes[index][_type][ID]
+
+	if err := c.check(ID, document); err != nil {
+		return nil, "", err
+	}
+
+	switch {
+	case !exists && update.DocAsUpsert:
+		document, _, err := s.put(index, _type, ID, update.Doc)
+		return document, "created", err
+	case !exists && len(update.Upsert) > 0 && update.ScriptedUpsert:
+		document, _, err := s.put(index, _type, ID, update.Upsert)
+
+		if err != nil {
+			return nil, "", err
+		}
+
+		return document, "created", runScript(update.Script, document.Body)
+	case !exists && len(update.Upsert) > 0:
+		document, _, err := s.put(index, _type, ID, update.Upsert)
+		return document, "created", err
+	case !exists:
+		return nil, "", errDocumentMissing
+	case update.Script != nil:
+		// scripts run against a copy so that a failing one leaves the document untouched
+		source := make(map[string]json.RawMessage, len(document.Body))
+
+		for k, v := range document.Body {
+			source[k] = v
+		}
+
+		if err := runScript(update.Script, source); err != nil {
+			return nil, "", err
+		}
+
+		document.Body = source
+		s.changed(document, document, nil)
+		return document, "updated", nil
+	}
+
+	fields := map[string]json.RawMessage{}
+
+	if err := json.Unmarshal(update.Doc, &fields); err != nil {
+		return nil, "", err
+	}
+
+	// like elasticsearch, a partial document which changes nothing is a noop
+	changed := false
+
+	for k, v := range fields {
+		if current, ok := document.Body[k]; !ok || !bytes.Equal(current, v) {
+			document.Body[k] = v
+			changed = true
+		}
+	}
+
+	if !changed {
+		return document, "noop", nil
+	}
+
+	s.changed(document, document, nil)
+	return document, "updated", nil
 }
 
 // search index currently returns the entire store
@@ -84,7 +210,7 @@ func (s *store) searchIndex(index string) ([]*SearchHit, error) {
 			}
 		}
 	} else {
-		return nil, errors.New("Index does not exist.")
+		return nil, errIndexNotFound
 	}
 
 	return hits, nil
@@ -96,6 +222,11 @@ func (s *store) searchType(index string, _type string) ([]*SearchHit, error) {
 	defer s.Unlock()
 	hits := []*SearchHit{}
 
+	if _, exists := s.Indexes[index]; !exists {
+		return nil, errIndexNotFound
+	}
+
+	// a type which has never been written to simply yields no hits
 	if collection, exists := s.Indexes[index][_type]; exists {
 		for _, doc := range collection {
 			body, _ := json.Marshal(doc.Body)
@@ -109,8 +240,6 @@ func (s *store) searchType(index string, _type string) ([]*SearchHit, error) {
 
 			hits = append(hits, hit)
 		}
-	} else {
-		return nil, errors.New("Type does not exist")
 	}
 
 	return hits, nil
@@ -120,55 +249,146 @@ func (s *store) deleteIndex(name string) {
 	s.Lock()
 	defer s.Unlock()
 	delete(s.Indexes, name)
+	delete(s.indices, name)
 }
 
 func (s *store) getDocument(index string, _type string, ID string) *Document {
 	s.Lock()
 	defer s.Unlock()
 
-	if doc, exists := s.Indexes[index][_type][ID]; exists {
-		return doc
+	doc, exists := s.Indexes[index][_type][ID]
+
+	if !exists {
+		return nil
+	}
+
+	// a copy which later writes do not change
+	copied := *doc
+	copied.Body = make(map[string]json.RawMessage, len(doc.Body))
+
+	for k, v := range doc.Body {
+		copied.Body[k] = v
 	}
 
-	return nil
+	return &copied
 }
 
-func (s *store) upsertDocument(index string, _type string, ID string, body []byte) (bool, error) {
+// replace the document with the given ID or create it, returns the new
+// document and whether one existed before
+func (s *store) replaceDocument(index string, _type string, ID string, body []byte, c *conditions) (*Document, bool, error) {
 	s.Lock()
 	defer s.Unlock()
-	var err error
-	var updated bool
 
-	if document, exists := s.Indexes[index][_type][ID]; !exists {
-		_, err = s.insert(index, _type, body)
-		updated = false
-	} else {
-		update := &map[string]json.RawMessage{}
-		err = json.Unmarshal(body, update)
+	if err := c.check(ID, s.Indexes[index][_type][ID]); err != nil {
+		return nil, false, err
+	}
 
-		if err != nil {
-			return false, err
-		}
+	document, created, err := s.put(index, _type, ID, body)
 
-		for k, v := range *update {
-			document.Body[k] = v
-		}
+	if err != nil {
+		return nil, false, err
+	}
 
-		s.Indexes[index][_type][ID] = document
-		updated = true
+	if c.external() {
+		document.Version = *c.version
 	}
 
-	return updated, err
+	return document, !created, nil
 }
 
 func (s *store) deleteDocument(index string, _type string, ID string) bool {
+	deleted, _ := s.deleteDocumentIf(index, _type, ID, nil)
+	return deleted
+}
+
+// delete a document if its preconditions match, returns whether it existed
+func (s *store) deleteDocumentIf(index string, _type string, ID string, c *conditions) (bool, error) {
 	s.Lock()
 	defer s.Unlock()
 
-	if _, exists := s.Indexes[index][_type][ID]; !exists {
-		return false
+	document, exists := s.Indexes[index][_type][ID]
+
+	if err := c.check(ID, document); err != nil {
+		return exists, err
 	}
 
+	if !exists {
+		return false, nil
+	}
+
+	s.seqNo++
 	delete(s.Indexes[index][_type], ID)
-	return true
+	return true, nil
+}
+
+// open a scroll over the remaining hits of a search
+func (s *store) openScroll(search *searchRequest, hits []*SearchHit, total int) string {
+	s.Lock()
+	defer s.Unlock()
+
+	ID := ULID()
+	s.scrolls[ID] = &scroll{search: search, hits: hits, total: total}
+	return ID
+}
+
+// return the next page of a scroll, false if the scroll does not exist
+func (s *store) nextScroll(ID string) (*searchRequest, []*SearchHit, int, bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	scroll, exists := s.scrolls[ID]
+
+	if !exists {
+		return nil, nil, 0, false
+	}
+
+	page := scroll.search.page(scroll.hits, 0)
+	scroll.hits = scroll.hits[len(page):]
+	return scroll.search, page, scroll.total, true
+}
+
+// release scrolls, returning the number which existed
+func (s *store) clearScrolls(IDs []string) int {
+	s.Lock()
+	defer s.Unlock()
+	freed := 0
+
+	for _, ID := range IDs {
+		if _, exists := s.scrolls[ID]; exists {
+			delete(s.scrolls, ID)
+			freed++
+		}
+	}
+
+	return freed
+}
+
+func (s *store) openPointInTime(index string) (string, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, exists := s.Indexes[index]; !exists {
+		return "", errIndexNotFound
+	}
+
+	ID := ULID()
+	s.pointsInTime[ID] = index
+	return ID, nil
+}
+
+func (s *store) pointInTime(ID string) (string, bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	index, exists := s.pointsInTime[ID]
+	return index, exists
+}
+
+func (s *store) closePointInTime(ID string) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	_, exists := s.pointsInTime[ID]
+	delete(s.pointsInTime, ID)
+	return exists
 }
@@ -0,0 +1,117 @@
+package mock
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// primary term of every document, the mock never fails over between shard copies
+const primaryTerm = 1
+
+// preconditions of a write, as given by the if_seq_no, if_primary_term,
+// version and version_type parameters
+type conditions struct {
+	ifSeqNo       *int64
+	ifPrimaryTerm *int64
+	version       *int64
+	versionType   string
+}
+
+// a write whose preconditions do not match the current document
+type versionConflict struct {
+	reason string
+}
+
+func (e *versionConflict) Error() string {
+	return e.reason
+}
+
+func conditionsFromQuery(query url.Values) (*conditions, error) {
+	c := &conditions{versionType: query.Get("version_type")}
+	params := map[string]**int64{"if_seq_no": &c.ifSeqNo, "if_primary_term": &c.ifPrimaryTerm, "version": &c.version}
+
+	for name, target := range params {
+		value := query.Get(name)
+
+		if value == "" {
+			continue
+		}
+
+		parsed, err := strconv.ParseInt(value, 10, 64)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse [%s]: %s", name, value)
+		}
+
+		*target = &parsed
+	}
+
+	return c, nil
+}
+
+func (c *conditions) external() bool {
+	return c != nil && c.version != nil && (c.versionType == "external" || c.versionType == "external_gt" || c.versionType == "external_gte")
+}
+
+// check the preconditions against the current document, which is nil if it does not exist
+func (c *conditions) check(ID string, current *Document) error {
+	if c == nil {
+		return nil
+	}
+
+	if c.ifSeqNo != nil {
+		if current == nil {
+			return &versionConflict{fmt.Sprintf("[%s]: version conflict, required seqNo [%d], primary term [%d]. but no document was found", ID, *c.ifSeqNo, c.primaryTerm())}
+		}
+
+		if current.SeqNo != *c.ifSeqNo || current.PrimaryTerm != c.primaryTerm() {
+			return &versionConflict{fmt.Sprintf("[%s]: version conflict, required seqNo [%d], primary term [%d]. current document has seqNo [%d] and primary term [%d]",
+				ID, *c.ifSeqNo, c.primaryTerm(), current.SeqNo, current.PrimaryTerm)}
+		}
+	}
+
+	if c.version == nil || current == nil {
+		return nil
+	}
+
+	switch {
+	case c.versionType == "external_gte" && *c.version < current.Version,
+		(c.versionType == "external" || c.versionType == "external_gt") && *c.version <= current.Version:
+		return &versionConflict{fmt.Sprintf("[%s]: version conflict, current version [%d] is higher or equal to the one provided [%d]", ID, current.Version, *c.version)}
+	case !c.external() && *c.version != current.Version:
+		return &versionConflict{fmt.Sprintf("[%s]: version conflict, current version [%d] is different than the one provided [%d]", ID, current.Version, *c.version)}
+	}
+
+	return nil
+}
+
+func (c *conditions) primaryTerm() int64 {
+	if c.ifPrimaryTerm == nil {
+		return primaryTerm
+	}
+
+	return *c.ifPrimaryTerm
+}
+
+// record a change of document, should only be called in a safe (locked) context
+func (s *store) changed(document *Document, previous *Document, c *conditions) {
+	version := int64(1)
+
+	if previous != nil {
+		version = previous.Version + 1
+	}
+
+	document.SeqNo, document.PrimaryTerm, document.Version = s.seqNo, primaryTerm, version
+	s.seqNo++
+
+	if c.external() {
+		document.Version = *c.version
+	}
+}
+
+// the version metadata of a document in responses
+func (d *Document) versioned(response *Generic) {
+	seqNo := d.SeqNo
+	response.Version, response.SeqNo, response.PrimaryTerm = d.Version, &seqNo, d.PrimaryTerm
+}
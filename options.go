@@ -1,22 +1,85 @@
 package elasticsearch
 
 import (
+	"context"
+	"errors"
 	"github.com/hashicorp/go-cleanhttp"
 	"net/http"
 	"net/url"
+	"strings"
+	"time"
 )
 
 type Options struct {
-	URI        string
+	URI string
+	// URIs of every node of the cluster, requests are spread across them and
+	// fail over to the next node when one does not answer. Defaults to URI.
+	URIs       []string
 	HTTPClient *http.Client
+	// builds the default HTTP client for clusters served over HTTPS with
+	// private certificate authorities or client certificates
+	TLS *TLSOptions
+	// how the node serving a request is chosen, defaults to RoundRobin
+	Strategy PoolStrategy
+	// how long a failing node is taken out of rotation before it is pinged,
+	// doubled for every consecutive failure up to MaxDeadTimeout
+	DeadTimeout    time.Duration
+	MaxDeadTimeout time.Duration
+	// decides whether failed requests are sent again, defaults to a
+	// BackoffRetrier. Use NoRetries to disable retries.
+	Retrier Retrier
+	// discover the nodes of the cluster when the client is created and,
+	// if SnifferInterval is set, periodically afterwards
+	Sniff           bool
+	SnifferInterval time.Duration
+	SnifferTimeout  time.Duration
+	// decides which of the discovered nodes receive requests, defaults to DataNodes
+	SnifferFilter func(node NodeInfo) bool
+	// credentials for basic auth
+	Username string
+	Password string
+	// base64 encoding of id:key, see EncodeAPIKey
+	APIKey      string
+	BearerToken string
+	// called for a new bearer token when a request is rejected with a 401,
+	// the request is then sent once more with the new token
+	RefreshToken func(ctx context.Context) (string, error)
+	// sent with every request, including pings and bulk requests
+	Headers http.Header
+	// compress request bodies with gzip and ask for compressed responses,
+	// which requires http.compression to be enabled on the cluster
+	Gzip bool
+	// logs every request sent to a node, a *slog.Logger may be passed
+	Logger Logger
+	// include request and response bodies in the log, passed through Redact if set
+	LogBodies bool
+	Redact    func(body []byte) []byte
+	// searches taking at least this long are logged as a warning with their
+	// query, regardless of LogBodies. Zero disables slow query logging.
+	SlowQueryThreshold time.Duration
+	// encodes requests and decodes responses, defaults to DefaultCodec
+	Codec Codec
 }
 
+// suffix expanded into the path of every request
+const uriTemplate = "{/index,type,suffix}"
+
 var (
-	DefaultURL        = "http://127.0.0.1:9200"
-	DefaultHTTPClient = cleanhttp.DefaultClient()
+	DefaultURL            = "http://127.0.0.1:9200"
+	DefaultHTTPClient     = cleanhttp.DefaultClient()
+	DefaultDeadTimeout    = time.Minute
+	DefaultMaxDeadTimeout = 30 * time.Minute
+	DefaultPingTimeout    = time.Second
+	DefaultSnifferTimeout = 2 * time.Second
+
+	errUnknownStrategy = errors.New("Unknown connection pool strategy.")
 )
 
 func (opts *Options) Init() error {
+	if opts.URI == "" && len(opts.URIs) > 0 {
+		opts.URI = opts.URIs[0]
+	}
+
 	if opts.URI == "" {
 		opts.URI = DefaultURL
 	} else {
@@ -29,12 +92,88 @@ func (opts *Options) Init() error {
 		opts.URI = uri.String()
 	}
 
+	if len(opts.URIs) == 0 {
+		opts.URIs = []string{opts.URI}
+	}
+
+	for i, URI := range opts.URIs {
+		uri, err := url.Parse(URI)
+
+		if err != nil {
+			return err
+		}
+
+		opts.URIs[i] = strings.TrimSuffix(uri.String(), "/")
+	}
+
 	// add templating suffix
-	opts.URI = opts.URI + "{/index,type,suffix}"
+	opts.URI = opts.URI + uriTemplate
+
+	if opts.TLS != nil && opts.HTTPClient != nil {
+		return errTLSWithHTTPClient
+	}
+
+	if opts.TLS != nil {
+		client, err := opts.TLS.client()
+
+		if err != nil {
+			return err
+		}
+
+		opts.HTTPClient = client
+	}
 
 	if opts.HTTPClient == nil {
 		opts.HTTPClient = DefaultHTTPClient
 	}
 
+	switch opts.Strategy {
+	case "":
+		opts.Strategy = RoundRobin
+	case RoundRobin, LeastConnections:
+	default:
+		return errUnknownStrategy
+	}
+
+	if opts.DeadTimeout <= 0 {
+		opts.DeadTimeout = DefaultDeadTimeout
+	}
+
+	if opts.MaxDeadTimeout <= 0 {
+		opts.MaxDeadTimeout = DefaultMaxDeadTimeout
+	}
+
+	if opts.MaxDeadTimeout < opts.DeadTimeout {
+		opts.MaxDeadTimeout = opts.DeadTimeout
+	}
+
+	if opts.Retrier == nil {
+		opts.Retrier = NewBackoffRetrier()
+	}
+
+	if opts.SnifferTimeout <= 0 {
+		opts.SnifferTimeout = DefaultSnifferTimeout
+	}
+
+	if opts.SnifferFilter == nil {
+		opts.SnifferFilter = DataNodes
+	}
+
+	if opts.Codec == nil {
+		opts.Codec = DefaultCodec
+	}
+
+	methods := 0
+
+	for _, configured := range []bool{opts.Username != "" || opts.Password != "", opts.APIKey != "", opts.BearerToken != "" || opts.RefreshToken != nil} {
+		if configured {
+			methods++
+		}
+	}
+
+	if methods > 1 {
+		return errConflictingAuth
+	}
+
 	return nil
 }
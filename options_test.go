@@ -26,6 +26,24 @@ func TestOptions(t *testing.T) {
 		asserts.Equal("http://elasticsearch:9200{/index,type,suffix}", options.URI)
 	})
 
+	t.Run("Options.init will default the nodes of the pool to URI", func(t *testing.T) {
+		options := &elasticsearch.Options{URI: "http://elasticsearch:9200/"}
+		err := options.Init()
+
+		asserts.Nil(err)
+		asserts.Equal([]string{"http://elasticsearch:9200"}, options.URIs)
+		asserts.Equal(elasticsearch.RoundRobin, options.Strategy)
+	})
+
+	t.Run("Options.init will build requests against the first of URIs", func(t *testing.T) {
+		options := &elasticsearch.Options{URIs: []string{"http://es1:9200", "http://es2:9200"}}
+		err := options.Init()
+
+		asserts.Nil(err)
+		asserts.Equal("http://es1:9200{/index,type,suffix}", options.URI)
+		asserts.Equal([]string{"http://es1:9200", "http://es2:9200"}, options.URIs)
+	})
+
 	t.Run("Options.init will return an error if URL does not declare a protocol", func(t *testing.T) {
 		const malformedURL = "127.0.0.1:9200"
 		options := &elasticsearch.Options{URI: malformedURL}
@@ -0,0 +1,287 @@
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PoolStrategy decides which of the live nodes serves the next request
+type PoolStrategy string
+
+const (
+	// RoundRobin cycles through the live nodes in order
+	RoundRobin PoolStrategy = "round_robin"
+	// LeastConnections picks the live node with the fewest requests in flight
+	LeastConnections PoolStrategy = "least_connections"
+)
+
+type (
+	// Node is a snapshot of the state of a node of the connection pool
+	Node struct {
+		URL   string
+		Alive bool
+		// consecutive failures, reset once the node answers again
+		Failures int
+		// when a dead node will next be pinged to check whether it is back
+		DeadUntil time.Time
+		// requests currently in flight to the node
+		Requests int
+	}
+
+	// pool spreads requests across the nodes of a cluster, taking nodes out
+	// of rotation when they fail and pinging them once their dead timeout,
+	// which doubles with every consecutive failure, has passed
+	pool struct {
+		sync.Mutex
+		nodes          []*node
+		strategy       PoolStrategy
+		next           int
+		deadTimeout    time.Duration
+		maxDeadTimeout time.Duration
+		ping           func(ctx context.Context, n *node) error
+		now            func() time.Time
+	}
+
+	node struct {
+		url       *url.URL
+		dead      bool
+		failures  int
+		deadUntil time.Time
+		requests  int
+	}
+)
+
+var errNoNodes = errors.New("The connection pool has no nodes.")
+
+func newPool(URIs []string, strategy PoolStrategy, deadTimeout time.Duration, maxDeadTimeout time.Duration) (*pool, error) {
+	p := &pool{strategy: strategy, deadTimeout: deadTimeout, maxDeadTimeout: maxDeadTimeout, now: time.Now}
+
+	for _, URI := range URIs {
+		u, err := url.Parse(URI)
+
+		if err != nil {
+			return nil, err
+		}
+
+		p.nodes = append(p.nodes, &node{url: u})
+	}
+
+	if len(p.nodes) == 0 {
+		return nil, errNoNodes
+	}
+
+	return p, nil
+}
+
+// acquire picks the node to send the next request to. Dead nodes whose
+// timeout has passed are pinged first and return to rotation if they answer.
+// If every node is dead the one which will be retried first is returned
+// anyway rather than failing without trying.
+func (p *pool) acquire(ctx context.Context) (*node, error) {
+	p.resurrect(ctx)
+
+	p.Lock()
+	defer p.Unlock()
+
+	if len(p.nodes) == 0 {
+		return nil, errNoNodes
+	}
+
+	var selected *node
+
+	for i := range p.nodes {
+		n := p.nodes[(p.next+i)%len(p.nodes)]
+
+		if n.dead {
+			continue
+		}
+
+		if selected == nil || (p.strategy == LeastConnections && n.requests < selected.requests) {
+			selected = n
+		}
+	}
+
+	if selected == nil {
+		for _, n := range p.nodes {
+			if selected == nil || n.deadUntil.Before(selected.deadUntil) {
+				selected = n
+			}
+		}
+	}
+
+	p.next = (p.indexOf(selected) + 1) % len(p.nodes)
+	selected.requests++
+	return selected, nil
+}
+
+// replace the nodes of the pool, nodes which were already known keep their state
+func (p *pool) update(URIs []string) error {
+	p.Lock()
+	defer p.Unlock()
+
+	known := map[string]*node{}
+
+	for _, n := range p.nodes {
+		known[n.url.String()] = n
+	}
+
+	nodes := make([]*node, len(URIs))
+
+	for i, URI := range URIs {
+		u, err := url.Parse(URI)
+
+		if err != nil {
+			return err
+		}
+
+		if n, exists := known[u.String()]; exists {
+			nodes[i] = n
+		} else {
+			nodes[i] = &node{url: u}
+		}
+	}
+
+	p.nodes, p.next = nodes, 0
+	return nil
+}
+
+// the number of nodes which are not dead
+func (p *pool) alive() int {
+	p.Lock()
+	defer p.Unlock()
+
+	alive := 0
+
+	for _, n := range p.nodes {
+		if !n.dead {
+			alive++
+		}
+	}
+
+	return alive
+}
+
+// release records the outcome of a request sent to a node acquired before
+func (p *pool) release(n *node, err error) {
+	p.Lock()
+	defer p.Unlock()
+
+	n.requests--
+
+	if err != nil {
+		p.markDead(n)
+	} else {
+		p.markAlive(n)
+	}
+}
+
+// ping the dead nodes whose timeout has passed, outside of the lock so that
+// other requests are not held up by a node which does not answer
+func (p *pool) resurrect(ctx context.Context) {
+	if p.ping == nil {
+		return
+	}
+
+	p.Lock()
+	due := []*node{}
+
+	for _, n := range p.nodes {
+		if n.dead && !p.now().Before(n.deadUntil) {
+			// push the timeout back so that concurrent requests do not ping it as well
+			n.deadUntil = p.now().Add(p.timeout(n.failures))
+			due = append(due, n)
+		}
+	}
+
+	p.Unlock()
+
+	for _, n := range due {
+		err := p.ping(ctx, n)
+
+		p.Lock()
+
+		if err != nil {
+			p.markDead(n)
+		} else {
+			p.markAlive(n)
+		}
+
+		p.Unlock()
+	}
+}
+
+// should only be called in a safe (locked) context
+func (p *pool) markDead(n *node) {
+	n.dead = true
+	n.failures++
+	n.deadUntil = p.now().Add(p.timeout(n.failures))
+}
+
+// should only be called in a safe (locked) context
+func (p *pool) markAlive(n *node) {
+	n.dead = false
+	n.failures = 0
+	n.deadUntil = time.Time{}
+}
+
+// the dead timeout after the given number of consecutive failures
+func (p *pool) timeout(failures int) time.Duration {
+	timeout := p.deadTimeout
+
+	for i := 1; i < failures && timeout < p.maxDeadTimeout; i++ {
+		timeout *= 2
+	}
+
+	if timeout > p.maxDeadTimeout {
+		return p.maxDeadTimeout
+	}
+
+	return timeout
+}
+
+func (p *pool) indexOf(n *node) int {
+	for i, candidate := range p.nodes {
+		if candidate == n {
+			return i
+		}
+	}
+
+	return 0
+}
+
+// snapshot of every node, for debugging
+func (p *pool) state() []Node {
+	p.Lock()
+	defer p.Unlock()
+
+	nodes := make([]Node, len(p.nodes))
+
+	for i, n := range p.nodes {
+		nodes[i] = Node{URL: n.url.String(), Alive: !n.dead, Failures: n.failures, DeadUntil: n.deadUntil, Requests: n.requests}
+	}
+
+	return nodes
+}
+
+// rewrite a request built against base to target the node instead
+func (n *node) rewrite(req *http.Request, base *url.URL) *url.URL {
+	u := *req.URL
+	u.Scheme, u.Host, u.User = n.url.Scheme, n.url.Host, n.url.User
+
+	// nodes may be served below different path prefixes
+	if basePath, nodePath := strings.TrimSuffix(base.EscapedPath(), "/"), strings.TrimSuffix(n.url.EscapedPath(), "/"); basePath != nodePath {
+		rawPath := nodePath + strings.TrimPrefix(u.EscapedPath(), basePath)
+		path, err := url.PathUnescape(rawPath)
+
+		if err == nil {
+			u.Path, u.RawPath = path, rawPath
+		}
+	}
+
+	return &u
+}
@@ -0,0 +1,172 @@
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPool(t *testing.T) {
+	ctx := context.Background()
+	errDown := errors.New("down")
+
+	newTestPool := func(t *testing.T, strategy PoolStrategy) (*pool, *time.Time) {
+		p, err := newPool([]string{"http://a:9200", "http://b:9200", "http://c:9200"}, strategy, time.Second, 4*time.Second)
+		require.Nil(t, err)
+
+		now := time.Unix(0, 0)
+		p.now = func() time.Time { return now }
+		return p, &now
+	}
+
+	hosts := func(t *testing.T, p *pool, n int) []string {
+		result := []string{}
+
+		for i := 0; i < n; i++ {
+			selected, err := p.acquire(ctx)
+			require.Nil(t, err)
+			p.release(selected, nil)
+			result = append(result, selected.url.Host)
+		}
+
+		return result
+	}
+
+	t.Run("Round robin cycles through the live nodes", func(t *testing.T) {
+		p, _ := newTestPool(t, RoundRobin)
+		require.Equal(t, []string{"a:9200", "b:9200", "c:9200", "a:9200"}, hosts(t, p, 4))
+
+		p.markDead(p.nodes[1])
+		require.Equal(t, []string{"c:9200", "a:9200", "c:9200"}, hosts(t, p, 3))
+	})
+
+	t.Run("Least connections picks the node with the fewest requests in flight", func(t *testing.T) {
+		p, _ := newTestPool(t, LeastConnections)
+
+		first, _ := p.acquire(ctx)
+		second, _ := p.acquire(ctx)
+		require.NotEqual(t, first, second)
+
+		p.release(first, nil)
+		third, _ := p.acquire(ctx)
+		require.NotEqual(t, second, third)
+	})
+
+	t.Run("Dead nodes back off exponentially and are pinged once their timeout passes", func(t *testing.T) {
+		p, now := newTestPool(t, RoundRobin)
+		pings := 0
+		p.ping = func(ctx context.Context, n *node) error {
+			pings++
+			return errDown
+		}
+
+		a := p.nodes[0]
+		p.markDead(a)
+		require.Equal(t, now.Add(time.Second), a.deadUntil)
+
+		// still dead, no ping is due yet
+		hosts(t, p, 1)
+		require.Equal(t, 0, pings)
+
+		// the ping fails and the timeout doubles
+		*now = now.Add(time.Second)
+		hosts(t, p, 1)
+		require.Equal(t, 1, pings)
+		require.Equal(t, 2, a.failures)
+		require.Equal(t, now.Add(2*time.Second), a.deadUntil)
+
+		// the timeout is capped
+		for i := 0; i < 3; i++ {
+			p.markDead(a)
+		}
+
+		require.Equal(t, now.Add(4*time.Second), a.deadUntil)
+
+		// a successful ping returns the node to rotation
+		p.ping = func(ctx context.Context, n *node) error { return nil }
+		*now = now.Add(4 * time.Second)
+		hosts(t, p, 1)
+
+		state := p.state()
+		require.True(t, state[0].Alive)
+		require.Equal(t, 0, state[0].Failures)
+	})
+
+	t.Run("The node retried first is used when every node is dead", func(t *testing.T) {
+		p, _ := newTestPool(t, RoundRobin)
+
+		p.markDead(p.nodes[0])
+		p.markDead(p.nodes[0])
+		p.markDead(p.nodes[1])
+		p.markDead(p.nodes[2])
+		p.markDead(p.nodes[2])
+
+		require.Equal(t, []string{"b:9200"}, hosts(t, p, 1))
+	})
+}
+
+func TestREST_Failover(t *testing.T) {
+	var mu sync.Mutex
+	var served []string
+
+	node := func(name string, status int) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			mu.Lock()
+			served = append(served, name+" "+req.URL.Path)
+			mu.Unlock()
+
+			w.WriteHeader(status)
+			w.Write([]byte(`{"acknowledged": true}`))
+		}))
+	}
+
+	unavailable := node("unavailable", http.StatusServiceUnavailable)
+	defer unavailable.Close()
+
+	healthy := node("healthy", http.StatusOK)
+	defer healthy.Close()
+
+	// a node below a path prefix, as when elasticsearch is served behind a proxy
+	prefixed := node("prefixed", http.StatusOK)
+	defer prefixed.Close()
+
+	client, err := New(&Options{URIs: []string{"http://127.0.0.1:1", unavailable.URL, healthy.URL, prefixed.URL + "/es/"}})
+	require.Nil(t, err)
+
+	require.Nil(t, client.I("test").Drop())
+	require.Equal(t, []string{"unavailable /test", "healthy /test"}, served)
+
+	nodes := client.Nodes()
+	require.Len(t, nodes, 4)
+	require.False(t, nodes[0].Alive)
+	require.False(t, nodes[1].Alive)
+	require.True(t, nodes[2].Alive)
+	require.Equal(t, prefixed.URL+"/es", nodes[3].URL)
+
+	// the dead nodes are skipped until their timeout passes
+	served = nil
+	require.Nil(t, client.I("test").Drop())
+	require.Nil(t, client.I("test").Drop())
+	require.Equal(t, []string{"prefixed /es/test", "healthy /test"}, served)
+
+	t.Run("Errors of the request itself do not fail over", func(t *testing.T) {
+		missing := httptest.NewServer(http.NotFoundHandler())
+		defer missing.Close()
+
+		client, err := New(&Options{URIs: []string{missing.URL, healthy.URL}})
+		require.Nil(t, err)
+
+		require.True(t, IsNotFound(client.I("test").Drop()))
+		require.True(t, client.Nodes()[0].Alive)
+	})
+
+	t.Run("Unknown strategies are rejected", func(t *testing.T) {
+		_, err := New(&Options{Strategy: "random"})
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,352 @@
+package query
+
+type (
+	// Combines other queries with boolean logic
+	BoolQuery struct {
+		must               []Query
+		should             []Query
+		filter             []Query
+		mustNot            []Query
+		minimumShouldMatch string
+		boost              *float64
+	}
+
+	// Queries nested objects as if they were indexed as separate documents
+	NestedQuery struct {
+		path      string
+		query     Query
+		scoreMode string
+	}
+
+	// Modifies the score of documents matched by a query
+	FunctionScoreQuery struct {
+		query     Query
+		functions []*scoreFunctionClause
+		scoreMode string
+		boostMode string
+		maxBoost  *float64
+		minScore  *float64
+	}
+
+	// ScoreFunction is implemented by the functions of a function_score query
+	ScoreFunction interface {
+		// the key of the function within its clause, e.g. field_value_factor
+		Name() string
+		Source() interface{}
+	}
+
+	// Multiplies the score by a constant weight
+	WeightFunction struct {
+		weight float64
+	}
+
+	// Computes the score from the value of a numeric field
+	FieldValueFactorFunction struct {
+		params map[string]interface{}
+	}
+
+	// Generates uniformly distributed scores
+	RandomScoreFunction struct {
+		params map[string]interface{}
+	}
+
+	// Scores documents by their distance from an origin
+	DecayFunction struct {
+		kind   string
+		field  string
+		params map[string]interface{}
+	}
+
+	scoreFunctionClause struct {
+		filter   Query
+		function ScoreFunction
+	}
+)
+
+// Bool creates an empty boolean query, add clauses with Must, Should, Filter
+// and MustNot. Nil clauses are ignored.
+func Bool() *BoolQuery {
+	return &BoolQuery{}
+}
+
+// Must adds clauses which are required to match and contribute to the score.
+func (q *BoolQuery) Must(queries ...Query) *BoolQuery {
+	q.must = appendQueries(q.must, queries)
+	return q
+}
+
+// Should adds clauses of which at least one should match.
+func (q *BoolQuery) Should(queries ...Query) *BoolQuery {
+	q.should = appendQueries(q.should, queries)
+	return q
+}
+
+// Filter adds clauses which are required to match but do not contribute to the score.
+func (q *BoolQuery) Filter(queries ...Query) *BoolQuery {
+	q.filter = appendQueries(q.filter, queries)
+	return q
+}
+
+// MustNot adds clauses which are required not to match.
+func (q *BoolQuery) MustNot(queries ...Query) *BoolQuery {
+	q.mustNot = appendQueries(q.mustNot, queries)
+	return q
+}
+
+// MinimumShouldMatch sets the number or percentage of should clauses which must match.
+func (q *BoolQuery) MinimumShouldMatch(minimum string) *BoolQuery {
+	q.minimumShouldMatch = minimum
+	return q
+}
+
+// Boost sets the relative weight of the query.
+func (q *BoolQuery) Boost(boost float64) *BoolQuery {
+	q.boost = &boost
+	return q
+}
+
+func (q *BoolQuery) Source() map[string]interface{} {
+	params := map[string]interface{}{}
+
+	if len(q.must) > 0 {
+		params["must"] = sources(q.must)
+	}
+
+	if len(q.should) > 0 {
+		params["should"] = sources(q.should)
+	}
+
+	if len(q.filter) > 0 {
+		params["filter"] = sources(q.filter)
+	}
+
+	if len(q.mustNot) > 0 {
+		params["must_not"] = sources(q.mustNot)
+	}
+
+	if q.minimumShouldMatch != "" {
+		params["minimum_should_match"] = q.minimumShouldMatch
+	}
+
+	if q.boost != nil {
+		params["boost"] = *q.boost
+	}
+
+	return map[string]interface{}{"bool": params}
+}
+
+// Nested creates a query against the objects of a nested field at path. A
+// nil query matches every nested object.
+func Nested(path string, q Query) *NestedQuery {
+	if q == nil {
+		q = MatchAll()
+	}
+
+	return &NestedQuery{path: path, query: q}
+}
+
+// ScoreMode sets how the scores of matching nested objects are combined (avg, max, min, sum, none).
+func (q *NestedQuery) ScoreMode(mode string) *NestedQuery {
+	q.scoreMode = mode
+	return q
+}
+
+func (q *NestedQuery) Source() map[string]interface{} {
+	params := map[string]interface{}{"path": q.path, "query": q.query.Source()}
+
+	if q.scoreMode != "" {
+		params["score_mode"] = q.scoreMode
+	}
+
+	return map[string]interface{}{"nested": params}
+}
+
+// FunctionScore creates a query which rescores the documents matched by q.
+func FunctionScore(q Query) *FunctionScoreQuery {
+	return &FunctionScoreQuery{query: q}
+}
+
+// Add a function which applies to every matched document, nil functions are ignored.
+func (q *FunctionScoreQuery) Add(function ScoreFunction) *FunctionScoreQuery {
+	return q.AddFiltered(nil, function)
+}
+
+// AddFiltered adds a function which only applies to documents matching
+// filter, or every document if filter is nil.
+func (q *FunctionScoreQuery) AddFiltered(filter Query, function ScoreFunction) *FunctionScoreQuery {
+	if function != nil {
+		q.functions = append(q.functions, &scoreFunctionClause{filter: filter, function: function})
+	}
+
+	return q
+}
+
+// ScoreMode sets how the results of the functions are combined (multiply, sum, avg, first, max, min).
+func (q *FunctionScoreQuery) ScoreMode(mode string) *FunctionScoreQuery {
+	q.scoreMode = mode
+	return q
+}
+
+// BoostMode sets how the combined function score is merged with the query score.
+func (q *FunctionScoreQuery) BoostMode(mode string) *FunctionScoreQuery {
+	q.boostMode = mode
+	return q
+}
+
+// MaxBoost caps the score computed by the functions.
+func (q *FunctionScoreQuery) MaxBoost(max float64) *FunctionScoreQuery {
+	q.maxBoost = &max
+	return q
+}
+
+// MinScore excludes documents scoring below min.
+func (q *FunctionScoreQuery) MinScore(min float64) *FunctionScoreQuery {
+	q.minScore = &min
+	return q
+}
+
+func (q *FunctionScoreQuery) Source() map[string]interface{} {
+	params := map[string]interface{}{}
+
+	if q.query != nil {
+		params["query"] = q.query.Source()
+	}
+
+	if len(q.functions) > 0 {
+		functions := make([]interface{}, len(q.functions))
+
+		for i, clause := range q.functions {
+			functions[i] = clause.source()
+		}
+
+		params["functions"] = functions
+	}
+
+	if q.scoreMode != "" {
+		params["score_mode"] = q.scoreMode
+	}
+
+	if q.boostMode != "" {
+		params["boost_mode"] = q.boostMode
+	}
+
+	if q.maxBoost != nil {
+		params["max_boost"] = *q.maxBoost
+	}
+
+	if q.minScore != nil {
+		params["min_score"] = *q.minScore
+	}
+
+	return map[string]interface{}{"function_score": params}
+}
+
+func (c *scoreFunctionClause) source() map[string]interface{} {
+	clause := map[string]interface{}{}
+
+	if c.filter != nil {
+		clause["filter"] = c.filter.Source()
+	}
+
+	// weight is a top level property of the clause rather than a function body
+	if weight, ok := c.function.(*WeightFunction); ok {
+		clause["weight"] = weight.weight
+		return clause
+	}
+
+	clause[c.function.Name()] = c.function.Source()
+	return clause
+}
+
+// Weight creates a function multiplying the score by weight.
+func Weight(weight float64) *WeightFunction {
+	return &WeightFunction{weight: weight}
+}
+
+func (f *WeightFunction) Name() string { return "weight" }
+
+func (f *WeightFunction) Source() interface{} { return f.weight }
+
+// FieldValueFactor creates a function scoring documents by the value of field.
+func FieldValueFactor(field string) *FieldValueFactorFunction {
+	return &FieldValueFactorFunction{params: map[string]interface{}{"field": field}}
+}
+
+// Factor multiplies the value of the field.
+func (f *FieldValueFactorFunction) Factor(factor float64) *FieldValueFactorFunction {
+	f.params["factor"] = factor
+	return f
+}
+
+// Modifier applies a function to the field value, e.g. log1p or sqrt.
+func (f *FieldValueFactorFunction) Modifier(modifier string) *FieldValueFactorFunction {
+	f.params["modifier"] = modifier
+	return f
+}
+
+// Missing sets the value used for documents without the field.
+func (f *FieldValueFactorFunction) Missing(missing float64) *FieldValueFactorFunction {
+	f.params["missing"] = missing
+	return f
+}
+
+func (f *FieldValueFactorFunction) Name() string { return "field_value_factor" }
+
+func (f *FieldValueFactorFunction) Source() interface{} { return f.params }
+
+// RandomScore creates a function generating random scores. Pass a seed and
+// field to make the scores reproducible.
+func RandomScore() *RandomScoreFunction {
+	return &RandomScoreFunction{params: map[string]interface{}{}}
+}
+
+// Seed makes the scores reproducible across requests, requires Field.
+func (f *RandomScoreFunction) Seed(seed int64) *RandomScoreFunction {
+	f.params["seed"] = seed
+	return f
+}
+
+// Field sets the field whose values are hashed together with the seed.
+func (f *RandomScoreFunction) Field(field string) *RandomScoreFunction {
+	f.params["field"] = field
+	return f
+}
+
+func (f *RandomScoreFunction) Name() string { return "random_score" }
+
+func (f *RandomScoreFunction) Source() interface{} { return f.params }
+
+// Decay creates a decay function of the given kind (gauss, linear or exp)
+// centered on origin with the given scale.
+func Decay(kind string, field string, origin interface{}, scale interface{}) *DecayFunction {
+	return &DecayFunction{kind: kind, field: field, params: map[string]interface{}{"origin": origin, "scale": scale}}
+}
+
+// Offset sets the distance from origin before the score begins to decay.
+func (f *DecayFunction) Offset(offset interface{}) *DecayFunction {
+	f.params["offset"] = offset
+	return f
+}
+
+// Decay sets the score of documents at scale distance from origin.
+func (f *DecayFunction) Decay(decay float64) *DecayFunction {
+	f.params["decay"] = decay
+	return f
+}
+
+func (f *DecayFunction) Name() string { return f.kind }
+
+func (f *DecayFunction) Source() interface{} {
+	return map[string]interface{}{f.field: f.params}
+}
+
+// append the queries which are not nil to clauses
+func appendQueries(clauses []Query, queries []Query) []Query {
+	for _, q := range queries {
+		if q != nil {
+			clauses = append(clauses, q)
+		}
+	}
+
+	return clauses
+}
@@ -0,0 +1,118 @@
+package query
+
+type (
+	// Matches every document, optionally with a constant boost
+	MatchAllQuery struct {
+		boost *float64
+	}
+
+	// Full text query which analyzes the passed text before matching it against a field
+	MatchQuery struct {
+		field     string
+		text      interface{}
+		operator  string
+		fuzziness string
+		boost     *float64
+	}
+
+	// Full text query which matches the passed text as a phrase
+	MatchPhraseQuery struct {
+		field    string
+		text     string
+		slop     *int
+		analyzer string
+	}
+)
+
+// MatchAll creates a query matching every document.
+func MatchAll() *MatchAllQuery {
+	return &MatchAllQuery{}
+}
+
+// Boost sets the constant score of every matched document.
+func (q *MatchAllQuery) Boost(boost float64) *MatchAllQuery {
+	q.boost = &boost
+	return q
+}
+
+func (q *MatchAllQuery) Source() map[string]interface{} {
+	params := map[string]interface{}{}
+
+	if q.boost != nil {
+		params["boost"] = *q.boost
+	}
+
+	return map[string]interface{}{"match_all": params}
+}
+
+// Match creates a full text query on a field.
+func Match(field string, text interface{}) *MatchQuery {
+	return &MatchQuery{field: field, text: text}
+}
+
+// Operator sets the boolean logic ("and" or "or") used to combine analyzed terms.
+func (q *MatchQuery) Operator(operator string) *MatchQuery {
+	q.operator = operator
+	return q
+}
+
+// Fuzziness sets the allowed edit distance, e.g. "AUTO" or "2".
+func (q *MatchQuery) Fuzziness(fuzziness string) *MatchQuery {
+	q.fuzziness = fuzziness
+	return q
+}
+
+// Boost sets the relative weight of the query.
+func (q *MatchQuery) Boost(boost float64) *MatchQuery {
+	q.boost = &boost
+	return q
+}
+
+func (q *MatchQuery) Source() map[string]interface{} {
+	params := map[string]interface{}{"query": q.text}
+
+	if q.operator != "" {
+		params["operator"] = q.operator
+	}
+
+	if q.fuzziness != "" {
+		params["fuzziness"] = q.fuzziness
+	}
+
+	if q.boost != nil {
+		params["boost"] = *q.boost
+	}
+
+	return map[string]interface{}{"match": map[string]interface{}{q.field: params}}
+}
+
+// MatchPhrase creates a query matching the passed text as a phrase.
+func MatchPhrase(field string, text string) *MatchPhraseQuery {
+	return &MatchPhraseQuery{field: field, text: text}
+}
+
+// Slop sets how far apart the terms of the phrase may be.
+func (q *MatchPhraseQuery) Slop(slop int) *MatchPhraseQuery {
+	q.slop = &slop
+	return q
+}
+
+// Analyzer overrides the analyzer used for the phrase.
+func (q *MatchPhraseQuery) Analyzer(analyzer string) *MatchPhraseQuery {
+	q.analyzer = analyzer
+	return q
+}
+
+func (q *MatchPhraseQuery) Source() map[string]interface{} {
+	params := map[string]interface{}{"query": q.text}
+
+	if q.slop != nil {
+		params["slop"] = *q.slop
+	}
+
+	if q.analyzer != "" {
+		params["analyzer"] = q.analyzer
+	}
+
+	return map[string]interface{}{"match_phrase": map[string]interface{}{q.field: params}}
+}
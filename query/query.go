@@ -0,0 +1,34 @@
+// Package query provides builders for the elasticsearch query DSL. Every
+// builder implements Query and may be nested within compound queries such as
+// Bool, Nested and FunctionScore.
+package query
+
+import "encoding/json"
+
+// Query is implemented by every clause of the query DSL
+type Query interface {
+	// Source returns the clause in the form elasticsearch expects it to be
+	// serialized within a request body
+	Source() map[string]interface{}
+}
+
+// Body wraps a query in the top level object expected by the Search API.
+func Body(q Query) map[string]interface{} {
+	return map[string]interface{}{"query": q.Source()}
+}
+
+// Marshal serializes a query into a request body for the Search API.
+func Marshal(q Query) ([]byte, error) {
+	return json.Marshal(Body(q))
+}
+
+// sources converts a list of queries to their serializable form
+func sources(queries []Query) []interface{} {
+	clauses := make([]interface{}, len(queries))
+
+	for i, q := range queries {
+		clauses[i] = q.Source()
+	}
+
+	return clauses
+}
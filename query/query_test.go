@@ -0,0 +1,82 @@
+package query_test
+
+import (
+	"encoding/json"
+	"github.com/b3ntly/elasticsearch/query"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func requireSource(t *testing.T, expected string, q query.Query) {
+	js, err := json.Marshal(q.Source())
+	require.Nil(t, err)
+	require.JSONEq(t, expected, string(js))
+}
+
+func TestQueries(t *testing.T) {
+	t.Run("Leaf queries serialize to their DSL form", func(t *testing.T) {
+		requireSource(t, `{"term": {"user": "kimchy"}}`, query.Term("user", "kimchy"))
+		requireSource(t, `{"term": {"user": {"value": "kimchy", "boost": 2}}}`, query.Term("user", "kimchy").Boost(2))
+		requireSource(t, `{"terms": {"tags": ["a", "b"]}}`, query.Terms("tags", "a", "b"))
+		requireSource(t, `{"terms": {"tags": []}}`, query.Terms("tags"))
+		requireSource(t, `{"match": {"message": {"query": "hello", "operator": "and"}}}`, query.Match("message", "hello").Operator("and"))
+		requireSource(t, `{"match_phrase": {"message": {"query": "hello world", "slop": 1}}}`, query.MatchPhrase("message", "hello world").Slop(1))
+		requireSource(t, `{"range": {"age": {"gte": 10, "lt": 20}}}`, query.Range("age").Gte(10).Lt(20))
+		requireSource(t, `{"exists": {"field": "user"}}`, query.Exists("user"))
+		requireSource(t, `{"prefix": {"user": "ki"}}`, query.Prefix("user", "ki"))
+		requireSource(t, `{"wildcard": {"user": "ki*y"}}`, query.Wildcard("user", "ki*y"))
+		requireSource(t, `{"match_all": {}}`, query.MatchAll())
+	})
+
+	t.Run("Compound queries nest their clauses", func(t *testing.T) {
+		q := query.Bool().
+			Must(query.Match("message", "hello")).
+			Should(query.Term("tag", "a"), query.Term("tag", "b")).
+			Filter(query.Range("age").Gt(1)).
+			MustNot(query.Exists("deleted")).
+			MinimumShouldMatch("1")
+
+		requireSource(t, `{"bool": {
+			"must": [{"match": {"message": {"query": "hello"}}}],
+			"should": [{"term": {"tag": "a"}}, {"term": {"tag": "b"}}],
+			"filter": [{"range": {"age": {"gt": 1}}}],
+			"must_not": [{"exists": {"field": "deleted"}}],
+			"minimum_should_match": "1"
+		}}`, q)
+
+		requireSource(t, `{"nested": {"path": "comments", "score_mode": "max", "query": {"term": {"comments.author": "bob"}}}}`,
+			query.Nested("comments", query.Term("comments.author", "bob")).ScoreMode("max"))
+	})
+
+	t.Run("Function score queries serialize their functions", func(t *testing.T) {
+		q := query.FunctionScore(query.MatchAll()).
+			Add(query.FieldValueFactor("likes").Modifier("log1p")).
+			AddFiltered(query.Term("featured", true), query.Weight(3)).
+			Add(query.Decay("gauss", "date", "now", "10d")).
+			ScoreMode("sum").
+			BoostMode("multiply")
+
+		requireSource(t, `{"function_score": {
+			"query": {"match_all": {}},
+			"functions": [
+				{"field_value_factor": {"field": "likes", "modifier": "log1p"}},
+				{"filter": {"term": {"featured": true}}, "weight": 3},
+				{"gauss": {"date": {"origin": "now", "scale": "10d"}}}
+			],
+			"score_mode": "sum",
+			"boost_mode": "multiply"
+		}}`, q)
+	})
+
+	t.Run("Nil clauses are ignored or match everything", func(t *testing.T) {
+		requireSource(t, `{"bool": {"must": [{"term": {"tag": "a"}}]}}`, query.Bool().Must(nil, query.Term("tag", "a")).Filter(nil))
+		requireSource(t, `{"nested": {"path": "comments", "query": {"match_all": {}}}}`, query.Nested("comments", nil))
+		requireSource(t, `{"function_score": {"query": {"match_all": {}}}}`, query.FunctionScore(query.MatchAll()).Add(nil))
+	})
+
+	t.Run("Marshal wraps the query in a search body", func(t *testing.T) {
+		js, err := query.Marshal(query.Term("user", "kimchy"))
+		require.Nil(t, err)
+		require.JSONEq(t, `{"query": {"term": {"user": "kimchy"}}}`, string(js))
+	})
+}
@@ -0,0 +1,142 @@
+package query
+
+type (
+	// Matches documents containing an exact term
+	TermQuery struct {
+		field string
+		value interface{}
+		boost *float64
+	}
+
+	// Matches documents containing any of the exact terms
+	TermsQuery struct {
+		field  string
+		values []interface{}
+	}
+
+	// Matches documents with a field within the given bounds
+	RangeQuery struct {
+		field  string
+		params map[string]interface{}
+	}
+
+	// Matches documents with a non null value for a field
+	ExistsQuery struct {
+		field string
+	}
+
+	// Matches documents with a field beginning with a prefix
+	PrefixQuery struct {
+		field  string
+		prefix string
+	}
+
+	// Matches documents with a field matching a wildcard pattern (* and ?)
+	WildcardQuery struct {
+		field   string
+		pattern string
+	}
+)
+
+// Term creates a query matching an exact, unanalyzed value.
+func Term(field string, value interface{}) *TermQuery {
+	return &TermQuery{field: field, value: value}
+}
+
+// Boost sets the relative weight of the query.
+func (q *TermQuery) Boost(boost float64) *TermQuery {
+	q.boost = &boost
+	return q
+}
+
+func (q *TermQuery) Source() map[string]interface{} {
+	if q.boost == nil {
+		return map[string]interface{}{"term": map[string]interface{}{q.field: q.value}}
+	}
+
+	params := map[string]interface{}{"value": q.value, "boost": *q.boost}
+	return map[string]interface{}{"term": map[string]interface{}{q.field: params}}
+}
+
+// Terms creates a query matching any of the passed exact values.
+func Terms(field string, values ...interface{}) *TermsQuery {
+	return &TermsQuery{field: field, values: values}
+}
+
+func (q *TermsQuery) Source() map[string]interface{} {
+	values := q.values
+
+	// elasticsearch rejects a null list of terms
+	if values == nil {
+		values = []interface{}{}
+	}
+
+	return map[string]interface{}{"terms": map[string]interface{}{q.field: values}}
+}
+
+// Range creates a query bounding the values of a field. Bounds are added with
+// Gt, Gte, Lt and Lte.
+func Range(field string) *RangeQuery {
+	return &RangeQuery{field: field, params: map[string]interface{}{}}
+}
+
+// Gt sets an exclusive lower bound.
+func (q *RangeQuery) Gt(value interface{}) *RangeQuery {
+	q.params["gt"] = value
+	return q
+}
+
+// Gte sets an inclusive lower bound.
+func (q *RangeQuery) Gte(value interface{}) *RangeQuery {
+	q.params["gte"] = value
+	return q
+}
+
+// Lt sets an exclusive upper bound.
+func (q *RangeQuery) Lt(value interface{}) *RangeQuery {
+	q.params["lt"] = value
+	return q
+}
+
+// Lte sets an inclusive upper bound.
+func (q *RangeQuery) Lte(value interface{}) *RangeQuery {
+	q.params["lte"] = value
+	return q
+}
+
+// Format sets the date format used to parse the bounds of a date field.
+func (q *RangeQuery) Format(format string) *RangeQuery {
+	q.params["format"] = format
+	return q
+}
+
+func (q *RangeQuery) Source() map[string]interface{} {
+	return map[string]interface{}{"range": map[string]interface{}{q.field: q.params}}
+}
+
+// Exists creates a query matching documents with a value for field.
+func Exists(field string) *ExistsQuery {
+	return &ExistsQuery{field: field}
+}
+
+func (q *ExistsQuery) Source() map[string]interface{} {
+	return map[string]interface{}{"exists": map[string]interface{}{"field": q.field}}
+}
+
+// Prefix creates a query matching values of field which begin with prefix.
+func Prefix(field string, prefix string) *PrefixQuery {
+	return &PrefixQuery{field: field, prefix: prefix}
+}
+
+func (q *PrefixQuery) Source() map[string]interface{} {
+	return map[string]interface{}{"prefix": map[string]interface{}{q.field: q.prefix}}
+}
+
+// Wildcard creates a query matching values of field against a pattern.
+func Wildcard(field string, pattern string) *WildcardQuery {
+	return &WildcardQuery{field: field, pattern: pattern}
+}
+
+func (q *WildcardQuery) Source() map[string]interface{} {
+	return map[string]interface{}{"wildcard": map[string]interface{}{q.field: q.pattern}}
+}
@@ -2,56 +2,118 @@ package elasticsearch
 
 import (
 	"bytes"
-	"encoding/json"
-	"fmt"
+	"compress/gzip"
+	"context"
+	"errors"
 	"github.com/b3ntly/elasticsearch/mock"
+	"github.com/b3ntly/elasticsearch/query"
 	"github.com/cch123/elasticsql"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
 )
 
 // rest interface with elasticsearch
 type rest struct {
 	HTTPClient *http.Client
 	BaseURI    string
+	// requests are built against BaseURI and sent to a node of the pool, if any
+	pool    *pool
+	base    *url.URL
+	retrier Retrier
+	// credentials and default headers, nil if none are configured
+	auth *auth
+	// compress request bodies and accept compressed responses
+	gzip bool
+
+	// guards middleware
+	mu         sync.RWMutex
+	middleware []Middleware
+
+	// nil if requests are not logged
+	logger    Logger
+	logBodies bool
+	redact    func(body []byte) []byte
+	slowQuery time.Duration
+
+	metrics *metrics
+	codec   Codec
+}
+
+func newREST(options *Options) (*rest, error) {
+	base, err := url.Parse(strings.TrimSuffix(options.URI, uriTemplate))
+
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := newPool(options.URIs, options.Strategy, options.DeadTimeout, options.MaxDeadTimeout)
+
+	if err != nil {
+		return nil, err
+	}
+
+	r := &rest{
+		BaseURI:    options.URI,
+		HTTPClient: options.HTTPClient,
+		pool:       p,
+		base:       base,
+		retrier:    options.Retrier,
+		auth:       newAuth(options),
+		gzip:       options.Gzip,
+		logger:     options.Logger,
+		logBodies:  options.LogBodies,
+		redact:     options.Redact,
+		slowQuery:  options.SlowQueryThreshold,
+		metrics:    newMetrics(),
+		codec:      options.Codec,
+	}
+
+	p.ping = r.ping
+	return r, nil
 }
 
 // Call the elasticsearch Search API for  given index
-func (r *rest) searchIndex(index string, queryString string) ([][]byte, error) {
+func (r *rest) searchIndex(ctx context.Context, index string, queryString string) ([][]byte, error) {
 	URL, err := buildURI(r.BaseURI, map[string]string{"index": index, "suffix": "_search"}, map[string]string{"q": queryString})
 
 	if err != nil {
 		return nil, err
 	}
 
-	body, err := r.request("GET", URL, nil)
+	body, err := r.request(ctx, "GET", URL, nil)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return searchResponseToDocument(body)
+	return searchResponseToDocument(r.codec, body)
 }
 
 // Call the elasticsearch Index API
-func (r *rest) deleteIndex(index string) error {
+func (r *rest) deleteIndex(ctx context.Context, index string) error {
 	URL, err := buildURI(r.BaseURI, map[string]string{"index": index}, nil)
 
 	if err != nil {
 		return err
 	}
 
-	body, err := r.request("DELETE", URL, nil)
+	body, err := r.request(ctx, "DELETE", URL, nil)
 
 	if err != nil {
 		return err
 	}
 
-	return deleteIndexResponseToDocument(body)
+	return deleteIndexResponseToDocument(r.codec, body)
 }
 
-func (r *rest) searchSQL(index string, _type string, sql string) ([][]byte, error) {
-	URL, err := buildURI(r.BaseURI, map[string]string{"index": index, "type": index, "suffix": "_search"}, nil)
+// Call the elasticsearch Search API with the query DSL generated from an SQL statement
+func (r *rest) sqlRequest(ctx context.Context, index string, _type string, sql string) ([]byte, error) {
+	URL, err := r.searchURI(index, _type, nil)
 
 	if err != nil {
 		return nil, err
@@ -63,222 +125,383 @@ func (r *rest) searchSQL(index string, _type string, sql string) ([][]byte, erro
 		return nil, err
 	}
 
-	body, err := r.request("GET", URL, []byte(query))
+	return r.request(ctx, "POST", URL, []byte(query))
+}
+
+func (r *rest) searchSQL(ctx context.Context, index string, _type string, sql string) ([][]byte, error) {
+	body, err := r.sqlRequest(ctx, index, _type, sql)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return searchResponseToDocument(r.codec, body)
+}
+
+// like searchSQL but retains the metadata and aggregations of the response,
+// which GROUP BY and aggregate functions such as count(*) are converted to
+func (r *rest) executeSQL(ctx context.Context, index string, _type string, sql string) (*SearchResponse, error) {
+	body, err := r.sqlRequest(ctx, index, _type, sql)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return searchResponseToDocument(body)
+	return searchResponseToResponse(r.codec, body)
 }
 
 // Call the elasticsearch Search API for  given index
-func (r *rest) searchType(index string, _type string, queryString string) ([][]byte, error) {
+func (r *rest) searchType(ctx context.Context, index string, _type string, queryString string) ([][]byte, error) {
 	URL, err := buildURI(r.BaseURI, map[string]string{"index": index, "type": _type, "suffix": "_search"}, map[string]string{"q": queryString})
 
 	if err != nil {
 		return nil, err
 	}
 
-	body, err := r.request("GET", URL, nil)
+	body, err := r.request(ctx, "GET", URL, nil)
 
 	if err != nil {
-		fmt.Println(err)
 		return nil, err
 	}
 
-	return searchResponseToDocument(body)
+	return searchResponseToDocument(r.codec, body)
 }
 
-// Call the elasticsearch Index API
-func (r *rest) insertDocument(index string, _type string, doc []byte) (string, error) {
-	URL, err := buildURI(r.BaseURI, map[string]string{"index": index, "type": _type}, map[string]string{"refresh": "true"})
+// build the URL of the Search API, _type may be empty to search every type in
+// the index and index may be empty to search through a point in time
+func (r *rest) searchURI(index string, _type string, queryMap map[string]string) (string, error) {
+	pathMap := map[string]string{"suffix": "_search"}
+
+	if index != "" {
+		pathMap["index"] = index
+	}
+
+	if _type != "" {
+		pathMap["type"] = _type
+	}
+
+	return buildURI(r.BaseURI, pathMap, queryMap)
+}
+
+// Call the elasticsearch Search API with a query DSL request body
+func (r *rest) query(ctx context.Context, index string, _type string, q query.Query) ([][]byte, error) {
+	URL, err := r.searchURI(index, _type, nil)
 
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	body, err := r.request("POST", URL, doc)
+	payload, err := r.codec.Marshal(query.Body(q))
 
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return indexResponseToDocument(body)
-}
+	body, err := r.request(ctx, "POST", URL, payload)
 
-// Call the elasticsearch Bulk API with insert operations
-func (r *rest) bulkInsertDocuments(index string, _type string, docs [][]byte) ([]string, error) {
-	// construct an NDJSON payload that satisfies the Elasticsearch API
-	payload := make([][]byte, len(docs)*2)
+	if err != nil {
+		return nil, err
+	}
 
-	// insert a bulk operation prefix before each document in the docs slice
-	for i := 0; i < len(docs); i++ {
-		operation, err := json.Marshal(&mock.BulkIndex{Index: &mock.Base{Index: index, Type: _type}})
+	return searchResponseToDocument(r.codec, body)
+}
 
-		if err != nil {
-			return nil, err
-		}
+// Call the elasticsearch Search API with a full search request
+func (r *rest) search(ctx context.Context, index string, _type string, search *SearchRequest, queryMap map[string]string) (*SearchResponse, error) {
+	URL, err := r.searchURI(index, _type, queryMap)
 
-		payload[i*2] = operation
-		payload[(i*2)+1] = docs[i]
+	if err != nil {
+		return nil, err
 	}
 
-	URL, err := buildURI(r.BaseURI, map[string]string{"suffix": "_bulk"}, map[string]string{"refresh": "true"})
+	payload, err := r.codec.Marshal(search.Body())
 
 	if err != nil {
 		return nil, err
 	}
 
-	body, err := r.bulkRequest("POST", URL, payload)
+	body, err := r.request(ctx, "POST", URL, payload)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return bulkInsertResponseToIDs(body)
+	return searchResponseToResponse(r.codec, body)
 }
 
-// Call the elasticsearch Document API
-func (r *rest) getDocument(index string, _type string, ID string) ([]byte, error) {
-	URL, err := buildURI(r.BaseURI, map[string]string{"index": index, "type": _type, "suffix": ID}, nil)
+// the segments of a URL are expanded individually, thus /_search/scroll
+// is expressed as an index and suffix
+var scrollPath = map[string]string{"index": "_search", "suffix": "scroll"}
+
+// Call the elasticsearch Scroll API for the next page of an open scroll
+func (r *rest) scroll(ctx context.Context, scrollID string, keepAlive time.Duration) (*SearchResponse, error) {
+	URL, err := buildURI(r.BaseURI, scrollPath, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := r.codec.Marshal(&mock.ScrollRequest{Scroll: formatDuration(keepAlive), ScrollID: scrollID})
 
 	if err != nil {
 		return nil, err
 	}
 
-	body, err := r.request("GET", URL, nil)
+	body, err := r.request(ctx, "POST", URL, payload)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return getDocumentResponseToDocument(body)
+	return searchResponseToResponse(r.codec, body)
 }
 
-// Call the elasticsearch Document API
-func (r *rest) updateDocument(index string, _type string, ID string, doc []byte) error {
-	URL, err := buildURI(r.BaseURI, map[string]string{"index": index, "type": _type, "suffix": ID}, map[string]string{"refresh": "true"})
+// Call the elasticsearch Clear Scroll API
+func (r *rest) clearScroll(ctx context.Context, scrollID string) error {
+	URL, err := buildURI(r.BaseURI, scrollPath, nil)
 
 	if err != nil {
 		return err
 	}
 
-	body, err := r.request("PUT", URL, doc)
+	payload, err := r.codec.Marshal(map[string][]string{"scroll_id": {scrollID}})
 
 	if err != nil {
 		return err
 	}
 
-	return updateDocumentResponseToDocument(body)
+	_, err = r.request(ctx, "DELETE", URL, payload)
+	return err
 }
 
-// Call the elasticsearch Bulk API with update operations
-func (r *rest) bulkUpdateDocuments(index string, _type string, docs []*mock.GenericDocument) ([]string, error) {
-	// construct an NDJSON payload that satisfies the Elasticsearch API
-	payload := make([][]byte, len(docs)*2)
+// Call the elasticsearch Point In Time API
+func (r *rest) openPointInTime(ctx context.Context, index string, keepAlive time.Duration) (string, error) {
+	URL, err := buildURI(r.BaseURI, map[string]string{"index": index, "suffix": "_pit"}, map[string]string{"keep_alive": formatDuration(keepAlive)})
 
-	// insert a bulk operation prefix before each document in the docs slice
-	for i := 0; i < len(docs); i++ {
-		operation, err := json.Marshal(&mock.BulkUpdate{Update: &mock.Resource{Index: index, Type: _type, ID: docs[i].ID}})
+	if err != nil {
+		return "", err
+	}
 
-		if err != nil {
-			return nil, err
-		}
+	body, err := r.request(ctx, "POST", URL, nil)
 
-		payload[i*2] = operation
+	if err != nil {
+		return "", err
+	}
+
+	return pointInTimeResponseToID(r.codec, body)
+}
 
-		doc, err := json.Marshal(&mock.BulkUpdatePayload{Doc: docs[i].Body})
+// Call the elasticsearch Point In Time API to release a point in time
+func (r *rest) closePointInTime(ctx context.Context, ID string) error {
+	URL, err := buildURI(r.BaseURI, map[string]string{"suffix": "_pit"}, nil)
 
-		if err != nil {
-			return nil, err
-		}
+	if err != nil {
+		return err
+	}
 
-		payload[(i*2)+1] = doc
+	payload, err := r.codec.Marshal(&mock.PointInTime{ID: ID})
+
+	if err != nil {
+		return err
 	}
 
-	URL, err := buildURI(r.BaseURI, map[string]string{"suffix": "_bulk"}, nil)
+	_, err = r.request(ctx, "DELETE", URL, payload)
+	return err
+}
+
+// Call the elasticsearch Index API
+func (r *rest) insertDocument(ctx context.Context, index string, _type string, doc []byte) (string, error) {
+	URL, err := buildURI(r.BaseURI, map[string]string{"index": index, "type": _type}, map[string]string{"refresh": "true"})
 
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
-	body, err := r.bulkRequest("POST", URL, payload)
+	body, err := r.request(ctx, "POST", URL, doc)
 
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
-	return bulkUpdateResponseToIDs(body)
+	return indexResponseToDocument(r.codec, body)
 }
 
-// Call the elasticsearch Document API
-func (r *rest) deleteDocument(index string, _type string, ID string) error {
-	URL, err := buildURI(r.BaseURI, map[string]string{"index": index, "type": _type, "suffix": ID}, map[string]string{"refresh": "true"})
+// Call the elasticsearch Bulk API with insert operations
+func (r *rest) bulkInsertDocuments(ctx context.Context, index string, _type string, docs [][]byte) (*BulkResponse, error) {
+	requests := make([]BulkableRequest, len(docs))
+
+	for i, doc := range docs {
+		requests[i] = NewBulkIndexRequest(doc).Index(index).Type(_type)
+	}
+
+	return r.bulkRequests(ctx, "", "", requests, map[string]string{"refresh": "true"})
+}
+
+// Call the elasticsearch Bulk API with the NDJSON lines of any number of actions
+func (r *rest) bulk(ctx context.Context, index string, _type string, payload [][]byte, queryMap map[string]string) (*BulkResponse, error) {
+	pathMap := map[string]string{"suffix": "_bulk"}
+
+	// actions which do not name their index or type default to those of the path
+	if index != "" {
+		pathMap["index"] = index
+	}
+
+	if _type != "" {
+		pathMap["type"] = _type
+	}
+
+	URL, err := buildURI(r.BaseURI, pathMap, queryMap)
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	body, err := r.request("DELETE", URL, nil)
+	body, err := r.bulkRequest(ctx, "POST", URL, payload)
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return deleteDocumentResponseToDocument(body)
+	return bulkResponseToResponse(r.codec, body)
 }
 
-// Call the elasticsearch Bulk API with delete operations
-func (r *rest) bulkDeleteDocuments(index string, _type string, IDs []string) ([]string, error) {
-	// construct an NDJSON payload that satisfies the Elasticsearch bulk API delete operation
-	payload := make([][]byte, len(IDs))
+// Call the elasticsearch Bulk API with a list of actions
+func (r *rest) bulkRequests(ctx context.Context, index string, _type string, requests []BulkableRequest, queryMap map[string]string) (*BulkResponse, error) {
+	payload := [][]byte{}
 
-	// insert a bulk operation prefix before each document in the docs slice
-	for idx, ID := range IDs {
-		operation, err := json.Marshal(&mock.BulkDelete{Delete: &mock.Resource{Index: index, Type: _type, ID: ID}})
+	for _, request := range requests {
+		lines, err := encodeBulkRequest(r.codec, request)
 
 		if err != nil {
 			return nil, err
 		}
 
-		payload[idx] = operation
+		payload = append(payload, lines...)
+	}
+
+	return r.bulk(ctx, index, _type, payload, queryMap)
+}
+
+// Call the elasticsearch Document API
+func (r *rest) getDocument(ctx context.Context, index string, _type string, ID string) ([]byte, error) {
+	URL, err := buildURI(r.BaseURI, map[string]string{"index": index, "type": _type, "suffix": ID}, nil)
+
+	if err != nil {
+		return nil, err
 	}
 
-	URL, err := buildURI(r.BaseURI, map[string]string{"suffix": "_bulk"}, map[string]string{"refresh": "true"})
+	body, err := r.request(ctx, "GET", URL, nil)
 
 	if err != nil {
 		return nil, err
 	}
 
-	body, err := r.bulkRequest("POST", URL, payload)
+	return getDocumentResponseToDocument(r.codec, body)
+}
+
+// like getDocument but retains the version of the document
+func (r *rest) getVersionedDocument(ctx context.Context, index string, _type string, ID string) (*VersionedDocument, error) {
+	body, err := r.getDocumentBody(ctx, index, _type, ID)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return bulkDeleteResponseToIDs(body)
+	return getDocumentResponseToVersionedDocument(r.codec, body)
 }
 
-func (r *rest) buildRequest(method string, url string, body []byte) (*http.Request, error) {
-	var req *http.Request
-	var err error
+// like getDocument but returns the decoded response, with the source as it was stored
+func (r *rest) getDocumentResponse(ctx context.Context, index string, _type string, ID string) (*mock.Generic, error) {
+	body, err := r.getDocumentBody(ctx, index, _type, ID)
 
-	if body == nil {
-		req, err = http.NewRequest(method, url, bytes.NewBuffer([]byte{}))
-	} else {
-		req, err = http.NewRequest(method, url, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
 	}
 
+	return getDocumentResponseToResponse(r.codec, body)
+}
+
+// Call the elasticsearch Document API
+func (r *rest) getDocumentBody(ctx context.Context, index string, _type string, ID string) ([]byte, error) {
+	URL, err := buildURI(r.BaseURI, map[string]string{"index": index, "type": _type, "suffix": ID}, nil)
+
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Add("Content-Type", "application/json")
-	return req, nil
+	return r.request(ctx, "GET", URL, nil)
+}
+
+// Call the elasticsearch Document API
+func (r *rest) updateDocument(ctx context.Context, index string, _type string, ID string, doc []byte) error {
+	_, err := r.updateDocumentIf(ctx, index, _type, ID, doc, nil)
+	return err
+}
+
+// like updateDocument but only if the precondition holds, returns the new version of the document
+func (r *rest) updateDocumentIf(ctx context.Context, index string, _type string, ID string, doc []byte, precondition *Precondition) (DocumentVersion, error) {
+	URL, err := buildURI(r.BaseURI, map[string]string{"index": index, "type": _type, "suffix": ID}, precondition.query(map[string]string{"refresh": "true"}))
+
+	if err != nil {
+		return DocumentVersion{}, err
+	}
+
+	body, err := r.request(ctx, "PUT", URL, doc)
+
+	if err != nil {
+		return DocumentVersion{}, err
+	}
+
+	return updateDocumentResponseToVersion(r.codec, body)
+}
+
+func (r *rest) bulkUpdateDocuments(ctx context.Context, index string, _type string, docs []*mock.GenericDocument) (*BulkResponse, error) {
+	requests := make([]BulkableRequest, len(docs))
+
+	for i, doc := range docs {
+		requests[i] = NewBulkUpdateRequest(doc.ID).Index(index).Type(_type).Doc(doc.Body)
+	}
+
+	return r.bulkRequests(ctx, "", "", requests, nil)
 }
 
-func (r *rest) buildBulkRequest(method string, url string, bodies [][]byte) (*http.Request, error) {
+// Call the elasticsearch Document API
+func (r *rest) deleteDocument(ctx context.Context, index string, _type string, ID string) error {
+	return r.deleteDocumentIf(ctx, index, _type, ID, nil)
+}
+
+// like deleteDocument but only if the precondition holds
+func (r *rest) deleteDocumentIf(ctx context.Context, index string, _type string, ID string, precondition *Precondition) error {
+	URL, err := buildURI(r.BaseURI, map[string]string{"index": index, "type": _type, "suffix": ID}, precondition.query(map[string]string{"refresh": "true"}))
+
+	if err != nil {
+		return err
+	}
+
+	body, err := r.request(ctx, "DELETE", URL, nil)
+
+	if err != nil {
+		return err
+	}
+
+	return deleteDocumentResponseToDocument(r.codec, body)
+}
+
+func (r *rest) bulkDeleteDocuments(ctx context.Context, index string, _type string, IDs []string) (*BulkResponse, error) {
+	requests := make([]BulkableRequest, len(IDs))
+
+	for i, ID := range IDs {
+		requests[i] = NewBulkDeleteRequest(ID).Index(index).Type(_type)
+	}
+
+	return r.bulkRequests(ctx, "", "", requests, map[string]string{"refresh": "true"})
+}
+
+func (r *rest) buildRequest(ctx context.Context, method string, url string, body []byte) (*http.Request, error) {
+	return r.newRequest(ctx, method, url, body)
+}
+
+func (r *rest) buildBulkRequest(ctx context.Context, method string, url string, bodies [][]byte) (*http.Request, error) {
 	buffer := new(bytes.Buffer)
 
 	for _, body := range bodies {
@@ -286,40 +509,274 @@ func (r *rest) buildBulkRequest(method string, url string, bodies [][]byte) (*ht
 		buffer.Write([]byte("\n"))
 	}
 
-	req, err := http.NewRequest(method, url, buffer)
+	return r.newRequest(ctx, method, url, buffer.Bytes())
+}
+
+// build a JSON request, compressing its body if gzip is enabled
+func (r *rest) newRequest(ctx context.Context, method string, url string, body []byte) (*http.Request, error) {
+	compressed := r.gzip && len(body) > 0
+
+	if compressed {
+		buffer := new(bytes.Buffer)
+		writer := gzip.NewWriter(buffer)
+
+		if _, err := writer.Write(body); err != nil {
+			return nil, err
+		}
+
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+
+		body = buffer.Bytes()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewBuffer(body))
 
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header.Add("Content-Type", "application/json")
+
+	if compressed {
+		req.Header.Add("Content-Encoding", "gzip")
+	}
+
+	// responses are then decoded by send rather than by the transport
+	if r.gzip {
+		req.Header.Add("Accept-Encoding", "gzip")
+	}
+
 	return req, nil
 }
 
+// send a request, retrying it as long as the Retrier allows. Attempts are
+// spread across the nodes of the pool, if any. A request rejected for its
+// bearer token is sent once more after the token was refreshed.
 func (r *rest) sendRequest(req *http.Request) ([]byte, error) {
-	response, err := r.HTTPClient.Do(req)
+	path := req.URL.Path
+
+	if r.base != nil {
+		path = strings.TrimPrefix(path, r.base.Path)
+	}
+
+	operation := operationOf(req.Method, path)
+	req = req.WithContext(context.WithValue(req.Context(), operationKey{}, operation))
+
+	start := time.Now()
+	body, retries, err := r.retry(req)
+
+	var sErr *streamError
+
+	if errors.As(err, &sErr) {
+		err = sErr.err
+	}
+
+	r.metrics.request(operation, time.Since(start), retries, err)
+	return body, err
+}
+
+// send req until it succeeds or the Retrier gives up, returns how often it was retried
+func (r *rest) retry(req *http.Request) ([]byte, int, error) {
+	start := time.Now()
+	refreshed := false
+
+	for attempt := 1; ; attempt++ {
+		token := ""
+
+		if r.auth != nil {
+			token = r.auth.apply(req)
+		}
+
+		body, failover, err := r.attempt(req, attempt)
+
+		if err != nil && !refreshed && r.auth != nil && r.auth.canRefresh(err) {
+			refreshed = true
+
+			if err := r.auth.refreshToken(req.Context(), token); err != nil {
+				return nil, attempt - 1, err
+			}
+
+			continue
+		}
+
+		if err == nil || r.retrier == nil || isStreamError(err) {
+			return body, attempt - 1, err
+		}
+
+		wait, retry := r.retrier.Retry(req.Context(), req, attempt, time.Since(start), err)
+
+		if !retry {
+			return nil, attempt - 1, err
+		}
+
+		// another node can serve the request straight away
+		if failover {
+			wait = 0
+		}
+
+		if err := sleepContext(req.Context(), wait); err != nil {
+			return nil, attempt - 1, err
+		}
+	}
+}
+
+// send req once, to the next node of the pool if there is one. Reports
+// whether the node failed while others remain to fail over to.
+func (r *rest) attempt(req *http.Request, attempt int) ([]byte, bool, error) {
+	attemptReq, err := r.attemptRequest(req, attempt)
 
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
-	defer response.Body.Close()
-	contents, err := ioutil.ReadAll(response.Body)
+	if r.pool == nil {
+		body, err := r.send(attemptReq)
+		return body, false, err
+	}
+
+	n, err := r.pool.acquire(req.Context())
 
 	if err != nil {
-		return nil, err
+		return nil, false, err
+	}
+
+	attemptReq.URL = n.rewrite(req, r.base)
+	attemptReq.Host = ""
+
+	body, err := r.send(attemptReq)
+
+	if !isNodeFailure(req.Context(), err) {
+		r.pool.release(n, nil)
+		return body, false, err
+	}
+
+	r.pool.release(n, err)
+	return nil, r.pool.alive() > 0, err
+}
+
+// a copy of req with a fresh body if the original one was consumed by a
+// previous attempt
+func (r *rest) attemptRequest(req *http.Request, attempt int) (*http.Request, error) {
+	attemptReq := req.Clone(req.Context())
+
+	if attempt > 1 && req.GetBody != nil {
+		body, err := req.GetBody()
+
+		if err != nil {
+			return nil, err
+		}
+
+		attemptReq.Body = body
+	}
+
+	return attemptReq, nil
+}
+
+// whether err means the node could not serve the request, as opposed to the
+// request itself being rejected or cancelled
+func isNodeFailure(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil || isStreamError(err) {
+		return false
+	}
+
+	var esErr *ElasticsearchError
+
+	if errors.As(err, &esErr) {
+		switch esErr.Status {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+
+		return false
+	}
+
+	return true
+}
+
+// check whether a dead node answers again
+func (r *rest) ping(ctx context.Context, n *node) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), DefaultPingTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "HEAD", n.url.String(), nil)
+
+	if err != nil {
+		return err
+	}
+
+	// secured clusters reject anonymous pings
+	if r.auth != nil {
+		r.auth.apply(req)
+	}
+
+	response, err := r.roundTrip(req)
+
+	if err != nil {
+		return err
+	}
+
+	if response.StatusCode >= http.StatusInternalServerError {
+		return errorResponseToError(r.codec, response.StatusCode, nil)
+	}
+
+	return nil
+}
+
+func (r *rest) send(req *http.Request) ([]byte, error) {
+	response, err := r.roundTrip(req)
+
+	if err != nil {
+		return nil, contextError(req.Context(), err)
 	}
 
 	if response.StatusCode >= 299 {
-		return nil, errorResponseToError(contents)
+		return nil, errorResponseToError(r.codec, response.StatusCode, response.Body)
+	}
+
+	if s := streamFromContext(req.Context()); s != nil {
+		return nil, s.consumeResponse(response)
+	}
+
+	return response.Body, nil
+}
+
+// read the body of response, decompressing it if it was gzipped
+func readBody(response *http.Response) ([]byte, error) {
+	body, err := decompress(response)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer body.Close()
+	return ioutil.ReadAll(body)
+}
+
+// the body of response, decompressed if it was gzipped
+func decompress(response *http.Response) (io.ReadCloser, error) {
+	if response.Header.Get("Content-Encoding") != "gzip" || response.Uncompressed {
+		return ioutil.NopCloser(response.Body), nil
+	}
+
+	return gzip.NewReader(response.Body)
+}
+
+// contextError prefers the error of a cancelled or expired context over the
+// transport error it caused so that callers may compare against
+// context.Canceled and context.DeadlineExceeded directly.
+func contextError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
 	}
 
-	return contents, err
+	return err
 }
 
 // Generic method to make a JSON request against a configured endpoint.
-func (r *rest) request(method string, url string, body []byte) ([]byte, error) {
-	req, err := r.buildRequest(method, url, body)
+func (r *rest) request(ctx context.Context, method string, url string, body []byte) ([]byte, error) {
+	req, err := r.buildRequest(ctx, method, url, body)
 
 	if err != nil {
 		return nil, err
@@ -329,8 +786,8 @@ func (r *rest) request(method string, url string, body []byte) ([]byte, error) {
 }
 
 // Generic method to make an NDJSON request against a configured endpoint
-func (r *rest) bulkRequest(method string, url string, bodies [][]byte) ([]byte, error) {
-	req, err := r.buildBulkRequest(method, url, bodies)
+func (r *rest) bulkRequest(ctx context.Context, method string, url string, bodies [][]byte) ([]byte, error) {
+	req, err := r.buildBulkRequest(ctx, method, url, bodies)
 
 	if err != nil {
 		return nil, err
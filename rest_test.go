@@ -1,8 +1,13 @@
 package elasticsearch
 
 import (
+	"context"
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
 	"testing"
-	//"github.com/stretchr/testify/assert"
+	"time"
 )
 
 func TestREST_BuildRequest(t *testing.T) {
@@ -10,7 +15,32 @@ func TestREST_BuildRequest(t *testing.T) {
 }
 
 func TestREST_SendRequest(t *testing.T) {
+	// a node that never answers before the client gives up
+	hung := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		select {
+		case <-req.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer hung.Close()
 
+	r := &rest{BaseURI: hung.URL + "{/index,type,suffix}", HTTPClient: cleanhttp.DefaultClient()}
+
+	t.Run("Will return context.DeadlineExceeded when the deadline passes", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		_, err := r.getDocument(ctx, "test", "test", "1")
+		require.Equal(t, context.DeadlineExceeded, err)
+	})
+
+	t.Run("Will return context.Canceled when the context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := r.searchType(ctx, "test", "test", "*:*")
+		require.Equal(t, context.Canceled, err)
+	})
 }
 
 func TestREST_Request(t *testing.T) {
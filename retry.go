@@ -0,0 +1,159 @@
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+type (
+	// Retrier decides whether a failed request is sent again and how long to
+	// wait before doing so. It is consulted after every failed attempt.
+	Retrier interface {
+		// Retry is called once attempt, counting from 1, of req failed with
+		// err after elapsed time since the first attempt.
+		Retry(ctx context.Context, req *http.Request, attempt int, elapsed time.Duration, err error) (time.Duration, bool)
+	}
+
+	// BackoffRetrier retries connection errors and the errors IsRetryable
+	// reports with exponential backoff and jitter. Requests which are not
+	// idempotent are only retried if they never reached a node, unless
+	// RetryNonIdempotent is set.
+	BackoffRetrier struct {
+		MaxRetries      int
+		InitialInterval time.Duration
+		MaxInterval     time.Duration
+		// no retry is attempted once this much time passed since the first attempt
+		MaxElapsedTime     time.Duration
+		RetryNonIdempotent bool
+	}
+
+	noRetrier struct{}
+)
+
+// NoRetries is a Retrier which never retries, not even on another node of the pool.
+var NoRetries Retrier = noRetrier{}
+
+func (noRetrier) Retry(ctx context.Context, req *http.Request, attempt int, elapsed time.Duration, err error) (time.Duration, bool) {
+	return 0, false
+}
+
+// NewBackoffRetrier creates the default Retrier.
+func NewBackoffRetrier() *BackoffRetrier {
+	return &BackoffRetrier{
+		MaxRetries:      3,
+		InitialInterval: 100 * time.Millisecond,
+		MaxInterval:     5 * time.Second,
+		MaxElapsedTime:  30 * time.Second,
+	}
+}
+
+func (b *BackoffRetrier) Retry(ctx context.Context, req *http.Request, attempt int, elapsed time.Duration, err error) (time.Duration, bool) {
+	if attempt > b.MaxRetries || ctx.Err() != nil || !isRetryableError(err) {
+		return 0, false
+	}
+
+	if !b.RetryNonIdempotent && !isIdempotent(req) && !isDialError(err) {
+		return 0, false
+	}
+
+	wait := b.backoff(attempt)
+
+	if b.MaxElapsedTime > 0 && elapsed+wait > b.MaxElapsedTime {
+		return 0, false
+	}
+
+	// rather return the error than wait past the deadline of the request
+	if deadline, ok := ctx.Deadline(); ok && time.Now().Add(wait).After(deadline) {
+		return 0, false
+	}
+
+	return wait, true
+}
+
+// the interval doubles with every attempt, the wait is picked at random
+// between half and the full interval so that clients do not retry in lockstep
+func (b *BackoffRetrier) backoff(attempt int) time.Duration {
+	interval := b.InitialInterval
+
+	for i := 1; i < attempt && interval < b.MaxInterval; i++ {
+		interval *= 2
+	}
+
+	if interval > b.MaxInterval {
+		interval = b.MaxInterval
+	}
+
+	if interval <= 0 {
+		return 0
+	}
+
+	return interval/2 + time.Duration(rand.Int63n(int64(interval/2)+1))
+}
+
+// whether the request may succeed if sent again: the node reported a
+// transient condition, see IsRetryable, or the connection to it failed
+func isRetryableError(err error) bool {
+	return IsRetryable(err) || isConnectionError(err)
+}
+
+// a failure of the connection to a node, as opposed to errors building the
+// request, verifying the certificate of the node or decoding its response
+func isConnectionError(err error) bool {
+	var urlErr *url.Error
+
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+
+	var opErr *net.OpError
+
+	// alerts of the TLS handshake are reported as net.OpError too
+	if errors.As(err, &opErr) {
+		return opErr.Op != "local error" && opErr.Op != "remote error"
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// whether sending req twice has the same effect as sending it once. Searches
+// are sent with POST but do not change anything.
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case "GET", "HEAD", "PUT", "DELETE", "OPTIONS":
+		return true
+	}
+
+	return strings.HasSuffix(req.URL.Path, "/_search")
+}
+
+// a request which failed to connect never reached the node and is always safe to retry
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// wait for d unless ctx is done first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
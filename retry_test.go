@@ -0,0 +1,190 @@
+package elasticsearch
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBackoffRetrier(t *testing.T) {
+	ctx := context.Background()
+	retrier := &BackoffRetrier{MaxRetries: 3, InitialInterval: 100 * time.Millisecond, MaxInterval: 300 * time.Millisecond, MaxElapsedTime: time.Second}
+
+	get, _ := http.NewRequest("GET", "http://127.0.0.1:9200/test/_doc/1", nil)
+	search, _ := http.NewRequest("POST", "http://127.0.0.1:9200/test/_search", nil)
+	insert, _ := http.NewRequest("POST", "http://127.0.0.1:9200/test/test", nil)
+
+	unavailable := &ElasticsearchError{Status: http.StatusServiceUnavailable}
+	refused := &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+	reset := &net.OpError{Op: "read", Err: errors.New("connection reset by peer")}
+
+	t.Run("Retries connection errors and overloaded nodes", func(t *testing.T) {
+		for _, err := range []error{refused, reset, unavailable, &ElasticsearchError{Status: http.StatusTooManyRequests}} {
+			_, retry := retrier.Retry(ctx, get, 1, 0, err)
+			require.True(t, retry, err.Error())
+		}
+
+		for _, err := range []error{&ElasticsearchError{Status: http.StatusNotFound}, &ElasticsearchError{Status: http.StatusInternalServerError}} {
+			_, retry := retrier.Retry(ctx, get, 1, 0, err)
+			require.False(t, retry, err.Error())
+		}
+
+		// rejected executions may be reported with other statuses
+		_, retry := retrier.Retry(ctx, get, 1, 0, &ElasticsearchError{Status: http.StatusInternalServerError, Type: "es_rejected_execution_exception"})
+		require.True(t, retry)
+
+		_, retry = retrier.Retry(ctx, get, 1, 0, &url.Error{Op: "Get", URL: get.URL.String(), Err: io.EOF})
+		require.True(t, retry)
+	})
+
+	t.Run("Does not retry errors other than those of the connection", func(t *testing.T) {
+		certificate := &url.Error{Op: "Get", URL: get.URL.String(), Err: &tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}}
+		alert := &url.Error{Op: "Get", URL: get.URL.String(), Err: &net.OpError{Op: "remote error", Err: errors.New("tls: bad certificate")}}
+
+		for _, err := range []error{certificate, alert, errFingerprint, &json.SyntaxError{}, errors.New("plain")} {
+			_, retry := retrier.Retry(ctx, get, 1, 0, err)
+			require.False(t, retry, err.Error())
+		}
+	})
+
+	t.Run("Waits exponentially longer with jitter, up to MaxInterval", func(t *testing.T) {
+		for attempt, interval := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond} {
+			wait, retry := retrier.Retry(ctx, get, attempt+1, 0, unavailable)
+			require.True(t, retry)
+			require.True(t, wait >= interval/2 && wait <= interval, wait.String())
+		}
+	})
+
+	t.Run("Stops after MaxRetries, MaxElapsedTime or once the context is done", func(t *testing.T) {
+		_, retry := retrier.Retry(ctx, get, 4, 0, unavailable)
+		require.False(t, retry)
+
+		_, retry = retrier.Retry(ctx, get, 1, time.Second, unavailable)
+		require.False(t, retry)
+
+		expired, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+
+		_, retry = retrier.Retry(expired, get, 1, 0, unavailable)
+		require.False(t, retry)
+	})
+
+	t.Run("Only retries non-idempotent requests which never reached the node", func(t *testing.T) {
+		_, retry := retrier.Retry(ctx, insert, 1, 0, unavailable)
+		require.False(t, retry)
+
+		_, retry = retrier.Retry(ctx, insert, 1, 0, refused)
+		require.True(t, retry)
+
+		_, retry = retrier.Retry(ctx, search, 1, 0, unavailable)
+		require.True(t, retry)
+
+		allowed := *retrier
+		allowed.RetryNonIdempotent = true
+		_, retry = allowed.Retry(ctx, insert, 1, 0, unavailable)
+		require.True(t, retry)
+	})
+}
+
+func TestREST_Retry(t *testing.T) {
+	var mu sync.Mutex
+	attempts := 0
+	bodies := []string{}
+
+	// overloaded for the first two attempts
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		attempts++
+		body, _ := ioutil.ReadAll(req.Body)
+		bodies = append(bodies, string(body))
+
+		if attempts <= 2 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		w.Write([]byte(`{"hits": {"total": 0, "hits": []}}`))
+	}))
+	defer server.Close()
+
+	reset := func() {
+		mu.Lock()
+		attempts, bodies = 0, nil
+		mu.Unlock()
+	}
+
+	t.Run("Retries with the same body until the request succeeds", func(t *testing.T) {
+		reset()
+
+		client, err := New(&Options{URI: server.URL, Retrier: &BackoffRetrier{MaxRetries: 3, InitialInterval: time.Millisecond, MaxInterval: time.Millisecond}})
+		require.Nil(t, err)
+
+		_, err = client.I("test").Execute(NewSearchRequest().Size(1))
+		require.Nil(t, err)
+		require.Equal(t, 3, attempts)
+		require.Equal(t, []string{`{"size":1}`, `{"size":1}`, `{"size":1}`}, bodies)
+	})
+
+	t.Run("Returns the last error once retries are exhausted", func(t *testing.T) {
+		reset()
+
+		client, err := New(&Options{URI: server.URL, Retrier: NoRetries})
+		require.Nil(t, err)
+
+		_, err = client.I("test").Execute(NewSearchRequest())
+		require.True(t, IsRetryable(err))
+		require.Equal(t, 1, attempts)
+	})
+
+	t.Run("Does not retry non-idempotent requests", func(t *testing.T) {
+		reset()
+
+		client, err := New(&Options{URI: server.URL, Retrier: &BackoffRetrier{MaxRetries: 3, InitialInterval: time.Millisecond, MaxInterval: time.Millisecond}})
+		require.Nil(t, err)
+
+		_, err = client.I("test").T("test").Insert([]byte(`{}`))
+		require.Error(t, err)
+		require.Equal(t, 1, attempts)
+	})
+
+	t.Run("Does not retry nodes presenting an untrusted certificate", func(t *testing.T) {
+		handshakes := 0
+
+		secured := httptest.NewUnstartedServer(http.NotFoundHandler())
+		secured.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+			if state == http.StateNew {
+				mu.Lock()
+				handshakes++
+				mu.Unlock()
+			}
+		}
+		secured.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+		secured.StartTLS()
+		defer secured.Close()
+
+		client, err := New(&Options{URI: secured.URL, Retrier: &BackoffRetrier{MaxRetries: 3, InitialInterval: time.Millisecond, MaxInterval: time.Millisecond}})
+		require.Nil(t, err)
+
+		_, err = client.I("test").Execute(NewSearchRequest())
+		require.Error(t, err)
+
+		mu.Lock()
+		defer mu.Unlock()
+		require.Equal(t, 1, handshakes)
+	})
+}
@@ -0,0 +1,39 @@
+package elasticsearch
+
+import (
+	"github.com/b3ntly/elasticsearch/mock"
+)
+
+// Script is an inline script executed by elasticsearch, painless unless told
+// otherwise. Values should be passed as params rather than formatted into the
+// source so that elasticsearch can cache the compiled script.
+type Script struct {
+	source string
+	lang   string
+	params map[string]interface{}
+}
+
+// NewScript creates a script from its source.
+func NewScript(source string) *Script {
+	return &Script{source: source}
+}
+
+// Lang sets the language of the script.
+func (s *Script) Lang(lang string) *Script {
+	s.lang = lang
+	return s
+}
+
+// Param sets a value the script may refer to as params.name.
+func (s *Script) Param(name string, value interface{}) *Script {
+	if s.params == nil {
+		s.params = map[string]interface{}{}
+	}
+
+	s.params[name] = value
+	return s
+}
+
+func (s *Script) body() *mock.Script {
+	return &mock.Script{Source: s.source, Lang: s.lang, Params: s.params}
+}
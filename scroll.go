@@ -0,0 +1,249 @@
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+type (
+	// hitIterator yields the hits of successive pages one at a time, fetching
+	// the next page once the current one is exhausted
+	hitIterator struct {
+		ctx     context.Context
+		fetch   func() ([]*SearchHit, error)
+		hits    []*SearchHit
+		current *SearchHit
+		done    bool
+		err     error
+	}
+
+	// Scroller walks every hit of a search using the scroll API. Only a single
+	// page is held in memory at a time. Call Close once done to release the
+	// scroll on the server.
+	//
+	//	scroller := collection.Scan(elasticsearch.NewSearchRequest().Size(1000), time.Minute)
+	//	defer scroller.Close()
+	//
+	//	for scroller.Next() {
+	//		doc := scroller.Doc()
+	//	}
+	//
+	//	if err := scroller.Err(); err != nil {
+	//	}
+	Scroller struct {
+		*hitIterator
+		rest      *rest
+		index     string
+		_type     string
+		search    *SearchRequest
+		keepAlive time.Duration
+		scrollID  string
+	}
+
+	// Cursor walks every hit of a search by paginating with search_after,
+	// optionally against a point in time so that the hits are consistent
+	// across pages. Call Close once done to release the point in time.
+	Cursor struct {
+		*hitIterator
+		rest      *rest
+		index     string
+		_type     string
+		search    *SearchRequest
+		keepAlive time.Duration
+		pitID     string
+		after     []interface{}
+	}
+)
+
+var (
+	errMissingSortValues = errors.New("Hits do not carry the sort values required to request the next page.")
+	errSearchAfterSort   = errors.New("Paginating without a point in time requires a sort ending with a field unique per document.")
+)
+
+// Next advances to the next hit, returning false once every hit has been
+// visited or an error occurred.
+func (it *hitIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+
+	for len(it.hits) == 0 {
+		if it.done {
+			return false
+		}
+
+		hits, err := it.fetch()
+
+		if err != nil {
+			it.err = err
+			return false
+		}
+
+		if len(hits) == 0 {
+			it.done = true
+		}
+
+		it.hits = hits
+	}
+
+	it.current, it.hits = it.hits[0], it.hits[1:]
+	return true
+}
+
+// Hit returns the hit Next advanced to.
+func (it *hitIterator) Hit() *SearchHit {
+	return it.current
+}
+
+// Doc returns the source of the hit Next advanced to with its _id injected,
+// in the same form as returned by Type.Search.
+func (it *hitIterator) Doc() []byte {
+	if it.current == nil {
+		return nil
+	}
+
+	return withID(it.current.ID, it.current.Source)
+}
+
+// Err returns the error which stopped the iteration, if any.
+func (it *hitIterator) Err() error {
+	return it.err
+}
+
+func newScroller(ctx context.Context, r *rest, index string, _type string, search *SearchRequest, keepAlive time.Duration) *Scroller {
+	s := &Scroller{rest: r, index: index, _type: _type, search: search, keepAlive: keepAlive}
+	s.hitIterator = &hitIterator{ctx: ctx, fetch: s.fetch}
+	return s
+}
+
+func (s *Scroller) fetch() ([]*SearchHit, error) {
+	var response *SearchResponse
+	var err error
+
+	if s.scrollID == "" {
+		response, err = s.rest.search(s.ctx, s.index, s._type, s.search, map[string]string{"scroll": formatDuration(s.keepAlive)})
+	} else {
+		response, err = s.rest.scroll(s.ctx, s.scrollID, s.keepAlive)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.scrollID = response.ScrollID
+
+	// release the scroll as soon as it is exhausted rather than waiting for Close
+	if len(response.Hits) == 0 {
+		return nil, s.Close()
+	}
+
+	return response.Hits, nil
+}
+
+// Close releases the scroll on the server. It is safe to call more than once.
+func (s *Scroller) Close() error {
+	s.done, s.hits = true, nil
+
+	if s.scrollID == "" {
+		return nil
+	}
+
+	scrollID := s.scrollID
+	s.scrollID = ""
+
+	// the scroll is released even if the iteration was cancelled
+	err := s.rest.clearScroll(context.WithoutCancel(s.ctx), scrollID)
+
+	if IsNotFound(err) {
+		return nil
+	}
+
+	return err
+}
+
+func newCursor(ctx context.Context, r *rest, index string, _type string, search *SearchRequest, keepAlive time.Duration) *Cursor {
+	c := &Cursor{rest: r, index: index, _type: _type, search: search.clone(), keepAlive: keepAlive}
+	c.hitIterator = &hitIterator{ctx: ctx, fetch: c.fetch}
+
+	// search_after requires a total order of the hits, which only points in
+	// time provide by default. Sorting by _id requires fielddata.
+	if len(c.search.sort) == 0 && keepAlive > 0 {
+		c.search.Sort(NewSort("_shard_doc"))
+	} else if len(c.search.sort) == 0 {
+		c.err = errSearchAfterSort
+	}
+
+	return c
+}
+
+func (c *Cursor) fetch() ([]*SearchHit, error) {
+	search := c.search.clone()
+	index, _type := c.index, c._type
+
+	if c.keepAlive > 0 {
+		if c.pitID == "" {
+			ID, err := c.rest.openPointInTime(c.ctx, c.index, c.keepAlive)
+
+			if err != nil {
+				return nil, err
+			}
+
+			c.pitID = ID
+		}
+
+		// a point in time already identifies the index to search
+		search.PointInTime(c.pitID, c.keepAlive)
+		index, _type = "", ""
+	}
+
+	if c.after != nil {
+		search.SearchAfter(c.after...)
+	}
+
+	response, err := c.rest.search(c.ctx, index, _type, search, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// elasticsearch may return a new ID for the point in time with every page
+	if response.PitID != "" {
+		c.pitID = response.PitID
+	}
+
+	if len(response.Hits) == 0 {
+		return nil, nil
+	}
+
+	last := response.Hits[len(response.Hits)-1]
+
+	if len(last.Sort) == 0 {
+		return nil, errMissingSortValues
+	}
+
+	c.after = last.Sort
+	return response.Hits, nil
+}
+
+// Close releases the point in time, if one was opened. It is safe to call
+// more than once.
+func (c *Cursor) Close() error {
+	c.done, c.hits = true, nil
+
+	if c.pitID == "" {
+		return nil
+	}
+
+	pitID := c.pitID
+	c.pitID = ""
+
+	// the point in time is released even if the iteration was cancelled
+	err := c.rest.closePointInTime(context.WithoutCancel(c.ctx), pitID)
+
+	if IsNotFound(err) {
+		return nil
+	}
+
+	return err
+}
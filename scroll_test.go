@@ -0,0 +1,96 @@
+package elasticsearch_test
+
+import (
+	"fmt"
+	"github.com/b3ntly/elasticsearch"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+const scanDocuments = 25
+
+func TestIterators(t *testing.T) {
+	client, err := getClient("http://127.0.0.1:9201")
+	require.Nil(t, err)
+
+	collection := client.I(testIndex).T(testType)
+	docs := make([][]byte, scanDocuments)
+
+	for i := range docs {
+		docs[i] = []byte(fmt.Sprintf(`{"rank": %d}`, i))
+	}
+
+	_, err = collection.BulkInsert(docs)
+	require.Nil(t, err)
+
+	// drain an iterator, asserting every document is visited exactly once
+	drain := func(t *testing.T, next func() bool, hit func() *elasticsearch.SearchHit) []string {
+		IDs := []string{}
+		seen := map[string]bool{}
+
+		for next() {
+			require.False(t, seen[hit().ID])
+			seen[hit().ID] = true
+			IDs = append(IDs, hit().ID)
+		}
+
+		require.Len(t, IDs, scanDocuments)
+		return IDs
+	}
+
+	t.Run("Scan visits every document across scroll pages", func(t *testing.T) {
+		scroller := collection.Scan(elasticsearch.NewSearchRequest().Size(10), time.Minute)
+		drain(t, scroller.Next, scroller.Hit)
+		require.Nil(t, scroller.Err())
+		require.Nil(t, scroller.Close())
+	})
+
+	t.Run("Index.Scan releases the scroll on Close", func(t *testing.T) {
+		scroller := client.I(testIndex).Scan(elasticsearch.NewSearchRequest().Size(5), time.Minute)
+		require.True(t, scroller.Next())
+		require.NotNil(t, scroller.Doc())
+		require.Nil(t, scroller.Close())
+		require.False(t, scroller.Next())
+		require.Nil(t, scroller.Close())
+	})
+
+	t.Run("SearchAfter visits every document in sort order", func(t *testing.T) {
+		search := elasticsearch.NewSearchRequest().Size(7).Sort(elasticsearch.NewSort("rank").Desc())
+		cursor := collection.SearchAfter(search)
+		IDs := drain(t, cursor.Next, cursor.Hit)
+		require.Nil(t, cursor.Err())
+		require.Nil(t, cursor.Close())
+
+		first, err := collection.FindById(IDs[0])
+		require.Nil(t, err)
+		require.Contains(t, string(first), fmt.Sprintf(`"rank":%d`, scanDocuments-1))
+	})
+
+	t.Run("Index.SearchAfter reads pages from a point in time", func(t *testing.T) {
+		cursor := client.I(testIndex).SearchAfter(elasticsearch.NewSearchRequest().Size(10), time.Minute)
+		drain(t, cursor.Next, cursor.Hit)
+		require.Nil(t, cursor.Err())
+		require.Nil(t, cursor.Close())
+	})
+
+	t.Run("SearchAfter without a point in time requires a sort", func(t *testing.T) {
+		for _, cursor := range []*elasticsearch.Cursor{
+			collection.SearchAfter(elasticsearch.NewSearchRequest()),
+			client.I(testIndex).SearchAfter(elasticsearch.NewSearchRequest(), 0),
+		} {
+			require.False(t, cursor.Next())
+			require.Error(t, cursor.Err())
+			require.Nil(t, cursor.Close())
+		}
+	})
+
+	t.Run("Errors stop the iteration", func(t *testing.T) {
+		scroller := client.I("hunter2").Scan(elasticsearch.NewSearchRequest(), time.Minute)
+		require.False(t, scroller.Next())
+		require.True(t, elasticsearch.IsIndexNotFound(scroller.Err()))
+		require.Nil(t, scroller.Close())
+	})
+
+	clean(client)
+}
@@ -0,0 +1,331 @@
+package elasticsearch
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/b3ntly/elasticsearch/aggs"
+	"github.com/b3ntly/elasticsearch/query"
+	"time"
+)
+
+type (
+	// SearchRequest describes the body of a call to the Search API. Create one
+	// with NewSearchRequest and chain its setters.
+	SearchRequest struct {
+		query          query.Query
+		from           *int
+		size           *int
+		sort           []*Sort
+		fetchSource    *bool
+		includes       []string
+		excludes       []string
+		storedFields   []string
+		minScore       *float64
+		timeout        time.Duration
+		trackTotalHits interface{}
+		aggregations   map[string]aggs.Aggregation
+		searchAfter    []interface{}
+		pointInTime    *pointInTime
+	}
+
+	pointInTime struct {
+		id        string
+		keepAlive time.Duration
+	}
+
+	// Sort orders the hits of a search by a single field
+	Sort struct {
+		field   string
+		desc    bool
+		missing interface{}
+		mode    string
+	}
+
+	// SearchResponse is the decoded result of a call to the Search API
+	SearchResponse struct {
+		// milliseconds elasticsearch took to execute the search
+		Took     int
+		TimedOut bool
+		// the number of documents matching the query, which is a lower bound
+		// if TotalHitsRelation is "gte"
+		TotalHits         int
+		TotalHitsRelation string
+		MaxScore          float64
+		Hits              []*SearchHit
+		Aggregations      aggs.Results
+		// set when the search opened or continued a scroll
+		ScrollID string
+		// set when the search was executed against a point in time
+		PitID string
+	}
+
+	// SearchHit is a single document matched by a search
+	SearchHit struct {
+		Index  string
+		Type   string
+		ID     string
+		Score  float64
+		Source json.RawMessage
+		// values of the stored fields requested by the search
+		Fields map[string]json.RawMessage
+		// values the hit was sorted on
+		Sort []interface{}
+	}
+)
+
+// NewSearchRequest creates a search request which matches every document.
+func NewSearchRequest() *SearchRequest {
+	return &SearchRequest{}
+}
+
+// Query sets the query hits must match.
+func (s *SearchRequest) Query(q query.Query) *SearchRequest {
+	s.query = q
+	return s
+}
+
+// From sets the offset of the first hit to return.
+func (s *SearchRequest) From(from int) *SearchRequest {
+	s.from = &from
+	return s
+}
+
+// Size sets the maximum number of hits to return, elasticsearch defaults to 10.
+func (s *SearchRequest) Size(size int) *SearchRequest {
+	s.size = &size
+	return s
+}
+
+// Sort appends sort criteria, hits are ordered by the first and ties are
+// broken by the following ones.
+func (s *SearchRequest) Sort(sorts ...*Sort) *SearchRequest {
+	s.sort = append(s.sort, sorts...)
+	return s
+}
+
+// FetchSource toggles whether the _source of each hit is returned.
+func (s *SearchRequest) FetchSource(fetch bool) *SearchRequest {
+	s.fetchSource = &fetch
+	return s
+}
+
+// Includes limits the returned _source to the given fields, wildcards are allowed.
+func (s *SearchRequest) Includes(fields ...string) *SearchRequest {
+	s.includes = append(s.includes, fields...)
+	return s
+}
+
+// Excludes removes the given fields from the returned _source, wildcards are allowed.
+func (s *SearchRequest) Excludes(fields ...string) *SearchRequest {
+	s.excludes = append(s.excludes, fields...)
+	return s
+}
+
+// StoredFields requests the values of fields which are stored in the mapping.
+func (s *SearchRequest) StoredFields(fields ...string) *SearchRequest {
+	s.storedFields = append(s.storedFields, fields...)
+	return s
+}
+
+// MinScore excludes hits scoring below min.
+func (s *SearchRequest) MinScore(min float64) *SearchRequest {
+	s.minScore = &min
+	return s
+}
+
+// Timeout bounds the time each shard may spend on the search, partial results
+// are returned with SearchResponse.TimedOut set once it elapses.
+func (s *SearchRequest) Timeout(timeout time.Duration) *SearchRequest {
+	s.timeout = timeout
+	return s
+}
+
+// TrackTotalHits toggles accurate counting of the total hits.
+func (s *SearchRequest) TrackTotalHits(track bool) *SearchRequest {
+	s.trackTotalHits = track
+	return s
+}
+
+// TrackTotalHitsUpTo counts the total hits accurately up to limit.
+func (s *SearchRequest) TrackTotalHitsUpTo(limit int) *SearchRequest {
+	s.trackTotalHits = limit
+	return s
+}
+
+// Aggregation adds a named aggregation computed over every matching document.
+func (s *SearchRequest) Aggregation(name string, aggregation aggs.Aggregation) *SearchRequest {
+	if s.aggregations == nil {
+		s.aggregations = map[string]aggs.Aggregation{}
+	}
+
+	s.aggregations[name] = aggregation
+	return s
+}
+
+// SearchAfter returns the hits following the passed sort values, which are
+// usually the SearchHit.Sort of the last hit of the previous page.
+func (s *SearchRequest) SearchAfter(values ...interface{}) *SearchRequest {
+	s.searchAfter = values
+	return s
+}
+
+// PointInTime executes the search against a point in time opened with the
+// given ID, keeping it alive for keepAlive after the search.
+func (s *SearchRequest) PointInTime(ID string, keepAlive time.Duration) *SearchRequest {
+	s.pointInTime = &pointInTime{id: ID, keepAlive: keepAlive}
+	return s
+}
+
+// a copy of the request which may be modified without affecting the original
+func (s *SearchRequest) clone() *SearchRequest {
+	if s == nil {
+		return NewSearchRequest()
+	}
+
+	clone := *s
+	clone.sort = append([]*Sort(nil), s.sort...)
+	return &clone
+}
+
+// Body returns the request in the form expected by the Search API. A nil
+// request is an empty search, which matches every document.
+func (s *SearchRequest) Body() map[string]interface{} {
+	body := map[string]interface{}{}
+
+	if s == nil {
+		return body
+	}
+
+	if s.query != nil {
+		body["query"] = s.query.Source()
+	}
+
+	if s.from != nil {
+		body["from"] = *s.from
+	}
+
+	if s.size != nil {
+		body["size"] = *s.size
+	}
+
+	if len(s.sort) > 0 {
+		sorts := make([]interface{}, len(s.sort))
+
+		for i, sort := range s.sort {
+			sorts[i] = sort.source()
+		}
+
+		body["sort"] = sorts
+	}
+
+	if s.fetchSource != nil && !*s.fetchSource {
+		body["_source"] = false
+	} else if len(s.includes) > 0 || len(s.excludes) > 0 {
+		filter := map[string]interface{}{}
+
+		if len(s.includes) > 0 {
+			filter["includes"] = s.includes
+		}
+
+		if len(s.excludes) > 0 {
+			filter["excludes"] = s.excludes
+		}
+
+		body["_source"] = filter
+	}
+
+	if len(s.storedFields) > 0 {
+		body["stored_fields"] = s.storedFields
+	}
+
+	if s.minScore != nil {
+		body["min_score"] = *s.minScore
+	}
+
+	if s.timeout > 0 {
+		body["timeout"] = formatDuration(s.timeout)
+	}
+
+	if s.trackTotalHits != nil {
+		body["track_total_hits"] = s.trackTotalHits
+	}
+
+	if len(s.aggregations) > 0 {
+		body["aggs"] = aggs.Sources(s.aggregations)
+	}
+
+	if len(s.searchAfter) > 0 {
+		body["search_after"] = s.searchAfter
+	}
+
+	if s.pointInTime != nil {
+		body["pit"] = map[string]interface{}{"id": s.pointInTime.id, "keep_alive": formatDuration(s.pointInTime.keepAlive)}
+	}
+
+	return body
+}
+
+// NewSort creates an ascending sort on field.
+func NewSort(field string) *Sort {
+	return &Sort{field: field}
+}
+
+// Asc sorts in ascending order.
+func (s *Sort) Asc() *Sort {
+	s.desc = false
+	return s
+}
+
+// Desc sorts in descending order.
+func (s *Sort) Desc() *Sort {
+	s.desc = true
+	return s
+}
+
+// Missing sets how documents without the field are sorted, either "_first",
+// "_last" or a custom value.
+func (s *Sort) Missing(missing interface{}) *Sort {
+	s.missing = missing
+	return s
+}
+
+// Mode sets which value of a multi-valued field is sorted on (min, max, sum, avg, median).
+func (s *Sort) Mode(mode string) *Sort {
+	s.mode = mode
+	return s
+}
+
+func (s *Sort) source() map[string]interface{} {
+	params := map[string]interface{}{"order": "asc"}
+
+	if s.desc {
+		params["order"] = "desc"
+	}
+
+	if s.missing != nil {
+		params["missing"] = s.missing
+	}
+
+	if s.mode != "" {
+		params["mode"] = s.mode
+	}
+
+	return map[string]interface{}{s.field: params}
+}
+
+// Documents returns the source of every hit with its _id injected, in the
+// same form as returned by Type.Search.
+func (r *SearchResponse) Documents() [][]byte {
+	documents := make([][]byte, len(r.Hits))
+
+	for i, hit := range r.Hits {
+		documents[i] = withID(hit.ID, hit.Source)
+	}
+
+	return documents
+}
+
+// format a duration in the time units understood by elasticsearch
+func formatDuration(d time.Duration) string {
+	return fmt.Sprintf("%dms", d/time.Millisecond)
+}
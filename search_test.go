@@ -0,0 +1,89 @@
+package elasticsearch
+
+import (
+	"encoding/json"
+	"github.com/b3ntly/elasticsearch/aggs"
+	"github.com/b3ntly/elasticsearch/query"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestSearchRequest_Body(t *testing.T) {
+	search := NewSearchRequest().
+		Query(query.Term("user", "kimchy")).
+		From(20).
+		Size(10).
+		Sort(NewSort("age").Desc().Missing("_last").Mode("avg"), NewSort("_id")).
+		Includes("user", "age").
+		Excludes("secret").
+		StoredFields("date").
+		MinScore(0.5).
+		Timeout(1500 * time.Millisecond).
+		TrackTotalHits(true)
+
+	js, err := json.Marshal(search.Body())
+	require.Nil(t, err)
+	require.JSONEq(t, `{
+		"query": {"term": {"user": "kimchy"}},
+		"from": 20,
+		"size": 10,
+		"sort": [{"age": {"order": "desc", "missing": "_last", "mode": "avg"}}, {"_id": {"order": "asc"}}],
+		"_source": {"includes": ["user", "age"], "excludes": ["secret"]},
+		"stored_fields": ["date"],
+		"min_score": 0.5,
+		"timeout": "1500ms",
+		"track_total_hits": true
+	}`, string(js))
+
+	js, err = json.Marshal(NewSearchRequest().Size(0).FetchSource(false).Aggregation("users", aggs.Terms("user")).Body())
+	require.Nil(t, err)
+	require.JSONEq(t, `{"size": 0, "_source": false, "aggs": {"users": {"terms": {"field": "user"}}}}`, string(js))
+
+	// nil requests are empty searches
+	var empty *SearchRequest
+	require.Equal(t, map[string]interface{}{}, empty.Body())
+	require.Equal(t, NewSearchRequest(), empty.clone())
+}
+
+func Test_searchResponseToResponse(t *testing.T) {
+	t.Run("Will decode the total hits of elasticsearch 7", func(t *testing.T) {
+		response, err := searchResponseToResponse(DefaultCodec, []byte(`{
+			"took": 5, "timed_out": true,
+			"hits": {
+				"total": {"value": 10000, "relation": "gte"},
+				"max_score": 1.5,
+				"hits": [{"_index": "test", "_id": "1", "_score": 1.5, "_source": {"a": 1}, "sort": [1]}]
+			},
+			"aggregations": {"users": {"buckets": [{"key": "kimchy", "doc_count": 10000}]}}
+		}`))
+
+		require.Nil(t, err)
+		require.Equal(t, 5, response.Took)
+		require.True(t, response.TimedOut)
+		require.Equal(t, 10000, response.TotalHits)
+		require.Equal(t, "gte", response.TotalHitsRelation)
+		require.Equal(t, 1.5, response.MaxScore)
+		require.Len(t, response.Hits, 1)
+		require.Equal(t, []interface{}{float64(1)}, response.Hits[0].Sort)
+		require.Equal(t, `{"_id":"1","a": 1}`, string(response.Documents()[0]))
+
+		users, ok := response.Aggregations.Buckets("users")
+		require.True(t, ok)
+		require.Equal(t, int64(10000), users.Buckets[0].DocCount)
+	})
+
+	t.Run("Will decode the total hits of earlier versions", func(t *testing.T) {
+		response, err := searchResponseToResponse(DefaultCodec, []byte(`{"hits": {"total": 3, "max_score": null, "hits": [{"_id": "1"}]}}`))
+
+		require.Nil(t, err)
+		require.Equal(t, 3, response.TotalHits)
+		require.Equal(t, "eq", response.TotalHitsRelation)
+		require.Equal(t, `{"_id":"1"}`, string(response.Documents()[0]))
+	})
+
+	t.Run("Will return an error for malformed JSON", func(t *testing.T) {
+		_, err := searchResponseToResponse(DefaultCodec, []byte("l23kej230"))
+		require.Error(t, err)
+	})
+}
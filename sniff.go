@@ -0,0 +1,132 @@
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"github.com/b3ntly/elasticsearch/mock"
+	"sort"
+	"strings"
+	"time"
+)
+
+// NodeInfo describes a node of the cluster discovered by sniffing
+type NodeInfo struct {
+	ID    string
+	Name  string
+	Roles []string
+	// host:port the node serves HTTP on
+	Address string
+}
+
+var errNoSniffedNodes = errors.New("Sniffing did not discover any node serving HTTP.")
+
+// DataNodes is the default Options.SnifferFilter, it accepts every node
+// except dedicated master nodes.
+func DataNodes(node NodeInfo) bool {
+	for _, role := range node.Roles {
+		if role != "master" && role != "voting_only" {
+			return true
+		}
+	}
+
+	// coordinating only nodes have no roles at all
+	return len(node.Roles) == 0
+}
+
+// Sniff replaces the nodes of the connection pool with the nodes of the
+// cluster accepted by Options.SnifferFilter. Nodes which were already known
+// keep their state.
+func (c *Client) Sniff() error {
+	return c.SniffContext(context.Background())
+}
+
+// SniffContext is like Sniff but the request is bound to ctx.
+func (c *Client) SniffContext(ctx context.Context) error {
+	nodes, err := c.REST.nodes(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	// sniffed nodes are served with the scheme of the configured ones
+	scheme := c.REST.base.Scheme
+	URIs := []string{}
+
+	for _, node := range nodes {
+		if c.Options.SnifferFilter(node) {
+			URIs = append(URIs, scheme+"://"+node.Address)
+		}
+	}
+
+	if len(URIs) == 0 {
+		return errNoSniffedNodes
+	}
+
+	return c.REST.pool.update(URIs)
+}
+
+// sniff periodically until the client is closed
+func (c *Client) sniffer(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), c.Options.SnifferTimeout)
+			// failures leave the known nodes in place until the next attempt
+			c.SniffContext(ctx)
+			cancel()
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// Call the elasticsearch Nodes Info API for the HTTP address of every node
+func (r *rest) nodes(ctx context.Context) ([]NodeInfo, error) {
+	URL, err := buildURI(r.BaseURI, map[string]string{"index": "_nodes", "type": "http"}, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := r.request(ctx, "GET", URL, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return nodesResponseToNodes(r.codec, body)
+}
+
+func nodesResponseToNodes(codec Codec, HTTPResponseBody []byte) ([]NodeInfo, error) {
+	response := &mock.NodesInfo{}
+	err := codec.Unmarshal(HTTPResponseBody, response)
+
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []NodeInfo{}
+
+	for ID, node := range response.Nodes {
+		// nodes with HTTP disabled do not report an address
+		if node.HTTP == nil || node.HTTP.PublishAddress == "" {
+			continue
+		}
+
+		address := node.HTTP.PublishAddress
+
+		// the address is reported as hostname/ip:port when the node is bound to a hostname
+		if i := strings.LastIndex(address, "/"); i >= 0 {
+			address = address[i+1:]
+		}
+
+		nodes = append(nodes, NodeInfo{ID: ID, Name: node.Name, Roles: node.Roles, Address: address})
+	}
+
+	// keep the order of the pool stable between sniffs
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Address < nodes[j].Address })
+	return nodes, nil
+}
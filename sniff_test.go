@@ -0,0 +1,87 @@
+package elasticsearch_test
+
+import (
+	"fmt"
+	"github.com/b3ntly/elasticsearch"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSniff(t *testing.T) {
+	urls := func(client *elasticsearch.Client) []string {
+		result := []string{}
+
+		for _, node := range client.Nodes() {
+			result = append(result, node.URL)
+		}
+
+		return result
+	}
+
+	t.Run("Discovers every node except dedicated masters", func(t *testing.T) {
+		client, err := elasticsearch.New(&elasticsearch.Options{URI: "http://127.0.0.1:9201", Sniff: true})
+		require.Nil(t, err)
+		defer client.Close()
+
+		require.Equal(t, []string{"http://127.0.0.1:9201", "http://127.0.0.1:9202"}, urls(client))
+	})
+
+	t.Run("SnifferFilter decides which nodes receive requests", func(t *testing.T) {
+		client, err := elasticsearch.New(&elasticsearch.Options{
+			URI:   "http://127.0.0.1:9201",
+			Sniff: true,
+			SnifferFilter: func(node elasticsearch.NodeInfo) bool {
+				return node.Name != "mock-2"
+			},
+		})
+
+		require.Nil(t, err)
+		defer client.Close()
+
+		require.Equal(t, []string{"http://127.0.0.1:9201", "http://127.0.0.1:9203"}, urls(client))
+	})
+
+	t.Run("Sniffs again every SnifferInterval", func(t *testing.T) {
+		var mu sync.Mutex
+		nodes := `"a": {"roles": ["data"], "http": {"publish_address": "%s"}}`
+
+		var server *httptest.Server
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			fmt.Fprintf(w, `{"nodes": {`+nodes+`}}`, strings.TrimPrefix(server.URL, "http://"))
+		}))
+		defer server.Close()
+
+		client, err := elasticsearch.New(&elasticsearch.Options{URI: server.URL, Sniff: true, SnifferInterval: 10 * time.Millisecond})
+		require.Nil(t, err)
+		defer client.Close()
+
+		require.Equal(t, []string{server.URL}, urls(client))
+
+		mu.Lock()
+		nodes += `, "b": {"roles": ["data"], "http": {"publish_address": "es-b/127.0.0.1:1"}}`
+		mu.Unlock()
+
+		for deadline := time.Now().Add(time.Second); len(client.Nodes()) < 2 && time.Now().Before(deadline); {
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		require.Equal(t, []string{"http://127.0.0.1:1", server.URL}, urls(client))
+	})
+
+	t.Run("Fails if no node serves HTTP", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Write([]byte(`{"nodes": {"a": {"roles": ["master"]}}}`))
+		}))
+		defer server.Close()
+
+		_, err := elasticsearch.New(&elasticsearch.Options{URI: server.URL, Sniff: true})
+		require.Error(t, err)
+	})
+}
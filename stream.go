@@ -0,0 +1,311 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/b3ntly/elasticsearch/mock"
+	"io"
+	"net/http"
+	"time"
+)
+
+type (
+	// the consumer of a streamed response body, carried by the context of the request
+	stream struct {
+		consume func(body io.Reader) error
+		// whether a response body reached consume
+		consumed bool
+	}
+
+	streamKey struct{}
+
+	// wraps the error of a streamed response. Hits may have been passed to
+	// the caller already, so the request is neither retried nor failed over.
+	streamError struct {
+		err error
+	}
+
+	// counts the bytes read from a response body
+	countingReader struct {
+		reader io.Reader
+		n      int64
+	}
+)
+
+func (e *streamError) Error() string {
+	return e.err.Error()
+}
+
+func (e *streamError) Unwrap() error {
+	return e.err
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Stream performs a search on a given index and calls fn with every hit as
+// it is decoded from the response, so that memory stays flat regardless of
+// the size of the page. Returns the rest of the response, without hits.
+// Decoding stops at the first error returned by fn, which is returned as is.
+func (idx *Index) Stream(search *SearchRequest, fn func(hit *SearchHit) error) (*SearchResponse, error) {
+	return idx.StreamContext(context.Background(), search, fn)
+}
+
+// StreamContext is like Stream but the request is bound to ctx.
+func (idx *Index) StreamContext(ctx context.Context, search *SearchRequest, fn func(hit *SearchHit) error) (*SearchResponse, error) {
+	return idx.Client.REST.streamSearch(ctx, idx.Name, "", search, fn)
+}
+
+// Stream performs a search on a given index-type and calls fn with every hit
+// as it is decoded from the response, see Index.Stream.
+func (t *Type) Stream(search *SearchRequest, fn func(hit *SearchHit) error) (*SearchResponse, error) {
+	return t.StreamContext(context.Background(), search, fn)
+}
+
+// StreamContext is like Stream but the request is bound to ctx.
+func (t *Type) StreamContext(ctx context.Context, search *SearchRequest, fn func(hit *SearchHit) error) (*SearchResponse, error) {
+	return t.Index.Client.REST.streamSearch(ctx, t.Index.Name, t.Name, search, fn)
+}
+
+// Stream performs a search of the collection and calls fn with every hit
+// decoded into T as it is read from the response, see Index.Stream.
+func (c *TypedCollection[T]) Stream(search *SearchRequest, fn func(hit *Hit[T]) error) (*SearchResponse, error) {
+	return c.StreamContext(context.Background(), search, fn)
+}
+
+// StreamContext is like Stream but the request is bound to ctx.
+func (c *TypedCollection[T]) StreamContext(ctx context.Context, search *SearchRequest, fn func(hit *Hit[T]) error) (*SearchResponse, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	return c.Type.StreamContext(ctx, search, func(hit *SearchHit) error {
+		doc, err := c.decode(hit.ID, hit.Source)
+
+		if err != nil {
+			return err
+		}
+
+		return fn(&Hit[T]{ID: hit.ID, Score: hit.Score, Sort: hit.Sort, Source: doc})
+	})
+}
+
+// Call the elasticsearch Search API, decoding hits as they are read from the response
+func (r *rest) streamSearch(ctx context.Context, index string, _type string, search *SearchRequest, fn func(hit *SearchHit) error) (*SearchResponse, error) {
+	URL, err := r.searchURI(index, _type, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := r.codec.Marshal(search.Body())
+
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := r.buildRequest(ctx, "POST", URL, payload)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var response *SearchResponse
+
+	s := &stream{consume: func(body io.Reader) error {
+		var err error
+		response, err = streamSearchResponse(r.codec, body, fn)
+		return err
+	}}
+
+	_, err = r.sendRequest(req.WithContext(context.WithValue(req.Context(), streamKey{}, s)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+func streamFromContext(ctx context.Context) *stream {
+	s, _ := ctx.Value(streamKey{}).(*stream)
+	return s
+}
+
+// pass the body of a successful response to the consumer of the stream
+// rather than reading it into memory
+func (r *rest) streamed(req *http.Request, response *http.Response, s *stream, start time.Time) (*Response, error) {
+	body, err := decompress(response)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer body.Close()
+
+	counter := &countingReader{reader: body}
+	s.consumed = true
+	err = s.consume(counter)
+	duration := time.Since(start)
+
+	r.metrics.attempt(operationFromContext(req.Context()), req.URL.Host, req.ContentLength, counter.n, duration, err != nil)
+
+	if err != nil {
+		return nil, &streamError{err: err}
+	}
+
+	return &Response{StatusCode: response.StatusCode, Header: response.Header, Duration: duration}, nil
+}
+
+// consume the body of a response which a middleware served without reaching
+// the transport
+func (s *stream) consumeResponse(response *Response) error {
+	if s.consumed {
+		return nil
+	}
+
+	s.consumed = true
+
+	if err := s.consume(bytes.NewReader(response.Body)); err != nil {
+		return &streamError{err: err}
+	}
+
+	return nil
+}
+
+func isStreamError(err error) bool {
+	var sErr *streamError
+	return errors.As(err, &sErr)
+}
+
+// decode a search response, calling fn with every hit as soon as it is
+// decoded. The returned response holds everything but the hits.
+func streamSearchResponse(codec Codec, body io.Reader, fn func(hit *SearchHit) error) (*SearchResponse, error) {
+	decoder := codec.NewDecoder(body)
+	response := &mock.Generic{}
+
+	err := decodeObject(decoder, func(key string) error {
+		switch key {
+		case "hits":
+			return streamHits(decoder, &response.Hits, fn)
+		case "took":
+			return decoder.Decode(&response.Took)
+		case "timed_out":
+			return decoder.Decode(&response.TimedOut)
+		case "aggregations":
+			return decoder.Decode(&response.Aggregations)
+		case "_scroll_id":
+			return decoder.Decode(&response.ScrollID)
+		case "pit_id":
+			return decoder.Decode(&response.PitID)
+		}
+
+		return skipValue(decoder)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return toSearchResponse(response), nil
+}
+
+// decode the hits object of a search response, hits are passed to fn
+// rather than kept in result
+func streamHits(decoder Decoder, result *mock.SearchResult, fn func(hit *SearchHit) error) error {
+	return decodeObject(decoder, func(key string) error {
+		switch key {
+		case "hits":
+			return decodeArray(decoder, func() error {
+				hit := &mock.SearchHit{}
+
+				if err := decoder.Decode(hit); err != nil {
+					return err
+				}
+
+				return fn(toSearchHit(hit))
+			})
+		case "total":
+			return decoder.Decode(&result.Total)
+		case "max_score":
+			// null unless the hits are sorted by score
+			var maxScore *float64
+			err := decoder.Decode(&maxScore)
+
+			if maxScore != nil {
+				result.MaxScore = *maxScore
+			}
+
+			return err
+		}
+
+		return skipValue(decoder)
+	})
+}
+
+// read an object, calling field for every key with the decoder positioned at its value
+func decodeObject(decoder Decoder, field func(key string) error) error {
+	if err := expectDelim(decoder, '{'); err != nil {
+		return err
+	}
+
+	for decoder.More() {
+		token, err := decoder.Token()
+
+		if err != nil {
+			return err
+		}
+
+		key, ok := token.(string)
+
+		if !ok {
+			return fmt.Errorf("Expected an object key but found %v.", token)
+		}
+
+		if err := field(key); err != nil {
+			return err
+		}
+	}
+
+	return expectDelim(decoder, '}')
+}
+
+// read an array, calling element with the decoder positioned at each element
+func decodeArray(decoder Decoder, element func() error) error {
+	if err := expectDelim(decoder, '['); err != nil {
+		return err
+	}
+
+	for decoder.More() {
+		if err := element(); err != nil {
+			return err
+		}
+	}
+
+	return expectDelim(decoder, ']')
+}
+
+func expectDelim(decoder Decoder, delim json.Delim) error {
+	token, err := decoder.Token()
+
+	if err != nil {
+		return err
+	}
+
+	if token != delim {
+		return fmt.Errorf("Expected %v but found %v.", delim, token)
+	}
+
+	return nil
+}
+
+func skipValue(decoder Decoder) error {
+	var skipped json.RawMessage
+	return decoder.Decode(&skipped)
+}
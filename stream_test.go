@@ -0,0 +1,197 @@
+package elasticsearch
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_streamSearchResponse(t *testing.T) {
+	body := `{
+		"took": 3,
+		"timed_out": false,
+		"_shards": {"total": 1, "successful": 1, "failed": 0},
+		"hits": {
+			"total": {"value": 2, "relation": "eq"},
+			"max_score": null,
+			"hits": [
+				{"_index": "test", "_id": "1", "_score": null, "_source": {"message": "a"}, "sort": [1]},
+				{"_index": "test", "_id": "2", "_score": null, "_source": {"message": "b"}, "sort": [2]}
+			]
+		},
+		"aggregations": {"count": {"value": 2}},
+		"_scroll_id": "scroll"
+	}`
+
+	t.Run("Passes every hit to fn and returns the rest of the response", func(t *testing.T) {
+		hits := []*SearchHit{}
+
+		response, err := streamSearchResponse(DefaultCodec, strings.NewReader(body), func(hit *SearchHit) error {
+			hits = append(hits, hit)
+			return nil
+		})
+
+		require.Nil(t, err)
+		require.Equal(t, 3, response.Took)
+		require.Equal(t, 2, response.TotalHits)
+		require.Equal(t, "eq", response.TotalHitsRelation)
+		require.Equal(t, "scroll", response.ScrollID)
+		require.Contains(t, response.Aggregations, "count")
+		require.Empty(t, response.Hits)
+
+		require.Len(t, hits, 2)
+		require.Equal(t, "1", hits[0].ID)
+		require.JSONEq(t, `{"message": "a"}`, string(hits[0].Source))
+		require.Equal(t, []interface{}{float64(2)}, hits[1].Sort)
+	})
+
+	t.Run("Stops at the first error of fn", func(t *testing.T) {
+		errStop := errors.New("stop")
+		calls := 0
+
+		_, err := streamSearchResponse(DefaultCodec, strings.NewReader(body), func(hit *SearchHit) error {
+			calls++
+			return errStop
+		})
+
+		require.Equal(t, errStop, err)
+		require.Equal(t, 1, calls)
+	})
+
+	t.Run("Fails on malformed responses", func(t *testing.T) {
+		for _, malformed := range []string{`[]`, `{"hits": {"hits": {}}}`, `{"hits": {"hits": [`, `l23kej230`} {
+			_, err := streamSearchResponse(DefaultCodec, strings.NewReader(malformed), func(hit *SearchHit) error { return nil })
+			require.Error(t, err, malformed)
+		}
+	})
+}
+
+func TestREST_Stream(t *testing.T) {
+	client, err := New(&Options{URI: "http://127.0.0.1:9201", Gzip: true})
+	require.Nil(t, err)
+
+	_ = client.I("stream").Drop()
+	defer client.I("stream").Drop()
+
+	docs := make([][]byte, 50)
+
+	for i := range docs {
+		docs[i] = []byte(fmt.Sprintf(`{"n": %v}`, i))
+	}
+
+	_, err = client.I("stream").T("stream").BulkInsert(docs)
+	require.Nil(t, err)
+	require.Nil(t, client.I("stream").Refresh())
+
+	t.Run("Streams every hit of a page", func(t *testing.T) {
+		seen := 0
+
+		response, err := client.I("stream").Stream(NewSearchRequest().Size(100), func(hit *SearchHit) error {
+			seen++
+			return nil
+		})
+
+		require.Nil(t, err)
+		require.Equal(t, 50, seen)
+		require.Equal(t, 50, response.TotalHits)
+
+		// the bytes of the streamed body are recorded
+		search := client.Stats().Operations["search"]
+		require.Equal(t, int64(1), search.Requests)
+		require.True(t, search.BytesReceived > 0)
+	})
+
+	t.Run("Typed collections decode streamed hits", func(t *testing.T) {
+		type doc struct {
+			ID string `json:"-" es:"_id"`
+			N  int    `json:"n"`
+		}
+
+		total := 0
+
+		_, err := Typed[doc](client.I("stream").T("stream")).Stream(NewSearchRequest().Size(100), func(hit *Hit[doc]) error {
+			require.Equal(t, hit.ID, hit.Source.ID)
+			total += hit.Source.N
+			return nil
+		})
+
+		require.Nil(t, err)
+		require.Equal(t, 49*50/2, total)
+	})
+
+	t.Run("Responses served by middleware are streamed too", func(t *testing.T) {
+		client, err := New(&Options{URI: "http://127.0.0.1:9201"})
+		require.Nil(t, err)
+
+		client.Use(func(next RoundTripFunc) RoundTripFunc {
+			return func(req *http.Request) (*Response, error) {
+				return &Response{StatusCode: http.StatusOK, Body: []byte(`{"hits": {"total": 1, "hits": [{"_id": "cached"}]}}`)}, nil
+			}
+		})
+
+		IDs := []string{}
+
+		_, err = client.I("stream").Stream(NewSearchRequest(), func(hit *SearchHit) error {
+			IDs = append(IDs, hit.ID)
+			return nil
+		})
+
+		require.Nil(t, err)
+		require.Equal(t, []string{"cached"}, IDs)
+	})
+}
+
+func TestREST_StreamRetry(t *testing.T) {
+	var mu sync.Mutex
+	attempts := 0
+
+	// overloaded for the first attempt
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		attempts++
+
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Write([]byte(`{"hits": {"total": 2, "hits": [{"_id": "1"}, {"_id": "2"}]}}`))
+	}))
+	defer server.Close()
+
+	client, err := New(&Options{URI: server.URL, Retrier: &BackoffRetrier{MaxRetries: 3, InitialInterval: time.Millisecond, MaxInterval: time.Millisecond}})
+	require.Nil(t, err)
+
+	t.Run("Errors before the body is streamed are retried", func(t *testing.T) {
+		seen := 0
+
+		_, err := client.I("test").Stream(NewSearchRequest(), func(hit *SearchHit) error {
+			seen++
+			return nil
+		})
+
+		require.Nil(t, err)
+		require.Equal(t, 2, attempts)
+		require.Equal(t, 2, seen)
+	})
+
+	t.Run("Errors once hits were passed to fn are not", func(t *testing.T) {
+		errStop := errors.New("stop")
+
+		_, err := client.I("test").Stream(NewSearchRequest(), func(hit *SearchHit) error {
+			return errStop
+		})
+
+		require.Equal(t, errStop, err)
+		require.Equal(t, 3, attempts)
+		require.True(t, client.Nodes()[0].Alive)
+	})
+}
@@ -0,0 +1,206 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"github.com/hashicorp/go-cleanhttp"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// TLSOptions configures the transport of the default HTTP client for
+// clusters served over HTTPS. PEM encoded contents take precedence over
+// the files they may be read from.
+type TLSOptions struct {
+	// certificate authorities the certificates of the nodes are verified
+	// against instead of the system roots
+	CACert     []byte
+	CACertFile string
+	// certificate presented to nodes which require client authentication
+	ClientCert     []byte
+	ClientCertFile string
+	ClientKey      []byte
+	ClientKeyFile  string
+	// verify the certificates of the nodes against this name rather than their host
+	ServerName string
+	// hex encoded SHA-256 fingerprint of a certificate, colons are ignored.
+	// If set, nodes are trusted if and only if they present this certificate
+	// in their chain, either as their own or as the CA their own chains to,
+	// and it is valid for ServerName or their host. Cannot be combined with
+	// CACert, the pinned certificate replaces the roots.
+	Fingerprint string
+}
+
+var (
+	errTLSWithHTTPClient = errors.New("TLS options cannot be combined with a custom HTTPClient.")
+	errInvalidCACert     = errors.New("The CA certificate does not contain any PEM encoded certificate.")
+	errClientKeyPair     = errors.New("A client certificate requires a client key and vice versa.")
+	errFingerprint       = errors.New("The node did not present a certificate matching the configured fingerprint.")
+	errFingerprintWithCA = errors.New("A fingerprint cannot be combined with a CA certificate.")
+)
+
+// build the default HTTP client with the TLS configuration applied
+func (t *TLSOptions) client() (*http.Client, error) {
+	config, err := t.config()
+
+	if err != nil {
+		return nil, err
+	}
+
+	client := cleanhttp.DefaultClient()
+	transport := client.Transport.(*http.Transport)
+	transport.TLSClientConfig = config
+
+	if t.Fingerprint != "" {
+		if t.CACert != nil || t.CACertFile != "" {
+			return nil, errFingerprintWithCA
+		}
+
+		fingerprint, err := hex.DecodeString(strings.Replace(t.Fingerprint, ":", "", -1))
+
+		if err != nil {
+			return nil, err
+		}
+
+		// the chain is verified against the pinned certificate by dialFingerprint instead
+		config.InsecureSkipVerify = true
+		transport.DialTLSContext = dialFingerprint(transport.DialContext, config, fingerprint)
+	}
+
+	return client, nil
+}
+
+func (t *TLSOptions) config() (*tls.Config, error) {
+	config := &tls.Config{ServerName: t.ServerName}
+
+	CACert, err := readPEM(t.CACert, t.CACertFile)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if CACert != nil {
+		config.RootCAs = x509.NewCertPool()
+
+		if !config.RootCAs.AppendCertsFromPEM(CACert) {
+			return nil, errInvalidCACert
+		}
+	}
+
+	clientCert, err := readPEM(t.ClientCert, t.ClientCertFile)
+
+	if err != nil {
+		return nil, err
+	}
+
+	clientKey, err := readPEM(t.ClientKey, t.ClientKeyFile)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if (clientCert == nil) != (clientKey == nil) {
+		return nil, errClientKeyPair
+	}
+
+	if clientCert != nil {
+		pair, err := tls.X509KeyPair(clientCert, clientKey)
+
+		if err != nil {
+			return nil, err
+		}
+
+		config.Certificates = []tls.Certificate{pair}
+	}
+
+	return config, nil
+}
+
+// dial nodes over TLS, verifying their certificates with verifyFingerprint
+// against ServerName or else the host dialed. The host is passed explicitly
+// as Go leaves it out of the connection state when dialing an IP.
+func dialFingerprint(dial func(ctx context.Context, network string, addr string) (net.Conn, error), config *tls.Config, fingerprint []byte) func(ctx context.Context, network string, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network string, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+
+		if err != nil {
+			return nil, err
+		}
+
+		config := config.Clone()
+
+		if config.ServerName == "" {
+			config.ServerName = host
+		}
+
+		config.VerifyConnection = verifyFingerprint(fingerprint, config.ServerName)
+
+		conn, err := dial(ctx, network, addr)
+
+		if err != nil {
+			return nil, err
+		}
+
+		tlsConn := tls.Client(conn, config)
+
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+
+		return tlsConn, nil
+	}
+}
+
+// verify that the certificate of the node chains to the certificate matching
+// fingerprint, which may be the certificate of the node itself, and that it
+// is valid for serverName
+func verifyFingerprint(fingerprint []byte, serverName string) func(tls.ConnectionState) error {
+	return func(state tls.ConnectionState) error {
+		if len(state.PeerCertificates) == 0 {
+			return errFingerprint
+		}
+
+		pinned := x509.NewCertPool()
+		intermediates := x509.NewCertPool()
+		found := false
+
+		for _, cert := range state.PeerCertificates {
+			sum := sha256.Sum256(cert.Raw)
+
+			if bytes.Equal(sum[:], fingerprint) {
+				pinned.AddCert(cert)
+				found = true
+			} else {
+				intermediates.AddCert(cert)
+			}
+		}
+
+		if !found {
+			return errFingerprint
+		}
+
+		_, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{
+			Roots:         pinned,
+			Intermediates: intermediates,
+			DNSName:       serverName,
+		})
+
+		return err
+	}
+}
+
+func readPEM(contents []byte, file string) ([]byte, error) {
+	if contents != nil || file == "" {
+		return contents, nil
+	}
+
+	return ioutil.ReadFile(file)
+}
@@ -0,0 +1,206 @@
+package elasticsearch_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"github.com/b3ntly/elasticsearch"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTLS(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`{"acknowledged": true}`))
+	})
+
+	server := httptest.NewTLSServer(handler)
+	defer server.Close()
+
+	CACert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	drop := func(options *elasticsearch.Options) error {
+		client, err := elasticsearch.New(options)
+
+		if err != nil {
+			return err
+		}
+
+		return client.I("test").Drop()
+	}
+
+	t.Run("Nodes are verified against the CA certificate", func(t *testing.T) {
+		require.Error(t, drop(&elasticsearch.Options{URI: server.URL, Retrier: elasticsearch.NoRetries}))
+		require.Nil(t, drop(&elasticsearch.Options{URI: server.URL, TLS: &elasticsearch.TLSOptions{CACert: CACert}}))
+
+		dir, err := ioutil.TempDir("", "tls")
+		require.Nil(t, err)
+		defer os.RemoveAll(dir)
+
+		file := filepath.Join(dir, "ca.pem")
+		require.Nil(t, ioutil.WriteFile(file, CACert, 0600))
+		require.Nil(t, drop(&elasticsearch.Options{URI: server.URL, TLS: &elasticsearch.TLSOptions{CACertFile: file}}))
+
+		_, err = elasticsearch.New(&elasticsearch.Options{TLS: &elasticsearch.TLSOptions{CACert: []byte("not a certificate")}})
+		require.Error(t, err)
+	})
+
+	t.Run("ServerName overrides the name verified", func(t *testing.T) {
+		TLS := &elasticsearch.TLSOptions{CACert: CACert, ServerName: "example.com"}
+		require.Nil(t, drop(&elasticsearch.Options{URI: server.URL, TLS: TLS}))
+
+		TLS = &elasticsearch.TLSOptions{CACert: CACert, ServerName: "elasticsearch.local"}
+		require.Error(t, drop(&elasticsearch.Options{URI: server.URL, TLS: TLS, Retrier: elasticsearch.NoRetries}))
+	})
+
+	t.Run("Fingerprints pin the certificate of the node", func(t *testing.T) {
+		sum := sha256.Sum256(server.Certificate().Raw)
+		require.Nil(t, drop(&elasticsearch.Options{URI: server.URL, TLS: &elasticsearch.TLSOptions{Fingerprint: hex.EncodeToString(sum[:])}}))
+
+		// the pinned certificate must be valid for the name of the node
+		TLS := &elasticsearch.TLSOptions{Fingerprint: hex.EncodeToString(sum[:]), ServerName: "elasticsearch.local"}
+		require.Error(t, drop(&elasticsearch.Options{URI: server.URL, TLS: TLS, Retrier: elasticsearch.NoRetries}))
+
+		TLS = &elasticsearch.TLSOptions{Fingerprint: hex.EncodeToString(sum[:]), CACert: CACert}
+		_, err := elasticsearch.New(&elasticsearch.Options{TLS: TLS})
+		require.Error(t, err)
+
+		sum[0]++
+		TLS = &elasticsearch.TLSOptions{Fingerprint: hex.EncodeToString(sum[:])}
+		require.Error(t, drop(&elasticsearch.Options{URI: server.URL, TLS: TLS, Retrier: elasticsearch.NoRetries}))
+	})
+
+	t.Run("Fingerprints may pin the CA of the node", func(t *testing.T) {
+		CA, CAKey := newServerCertificate(t, "127.0.0.1", nil, nil)
+		leaf, leafKey := newServerCertificate(t, "127.0.0.1", CA, CAKey)
+
+		issued := httptest.NewUnstartedServer(handler)
+		issued.TLS = &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{leaf.Raw, CA.Raw}, PrivateKey: leafKey}}}
+		issued.StartTLS()
+		defer issued.Close()
+
+		sum := sha256.Sum256(CA.Raw)
+		TLS := &elasticsearch.TLSOptions{Fingerprint: hex.EncodeToString(sum[:])}
+		require.Nil(t, drop(&elasticsearch.Options{URI: issued.URL, TLS: TLS}))
+
+		// a node presenting the pinned certificate without chaining to it
+		impostor, impostorKey := newServerCertificate(t, "127.0.0.1", nil, nil)
+
+		forged := httptest.NewUnstartedServer(handler)
+		forged.TLS = &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{impostor.Raw, CA.Raw}, PrivateKey: impostorKey}}}
+		forged.StartTLS()
+		defer forged.Close()
+
+		require.Error(t, drop(&elasticsearch.Options{URI: forged.URL, TLS: TLS, Retrier: elasticsearch.NoRetries}))
+
+		// a certificate issued by the pinned CA for another node
+		other, otherKey := newServerCertificate(t, "10.9.9.9", CA, CAKey)
+
+		misnamed := httptest.NewUnstartedServer(handler)
+		misnamed.TLS = &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{other.Raw, CA.Raw}, PrivateKey: otherKey}}}
+		misnamed.StartTLS()
+		defer misnamed.Close()
+
+		require.Error(t, drop(&elasticsearch.Options{URI: misnamed.URL, TLS: TLS, Retrier: elasticsearch.NoRetries}))
+	})
+
+	t.Run("Client certificates are presented to nodes which require them", func(t *testing.T) {
+		clientCert, clientKey := newCertificate(t)
+
+		clientCAs := x509.NewCertPool()
+		require.True(t, clientCAs.AppendCertsFromPEM(clientCert))
+
+		secured := httptest.NewUnstartedServer(handler)
+		secured.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+		secured.StartTLS()
+		defer secured.Close()
+
+		CACert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: secured.Certificate().Raw})
+
+		TLS := &elasticsearch.TLSOptions{CACert: CACert}
+		require.Error(t, drop(&elasticsearch.Options{URI: secured.URL, TLS: TLS, Retrier: elasticsearch.NoRetries}))
+
+		TLS = &elasticsearch.TLSOptions{CACert: CACert, ClientCert: clientCert, ClientKey: clientKey}
+		require.Nil(t, drop(&elasticsearch.Options{URI: secured.URL, TLS: TLS}))
+
+		_, err := elasticsearch.New(&elasticsearch.Options{TLS: &elasticsearch.TLSOptions{ClientCert: clientCert}})
+		require.Error(t, err)
+	})
+
+	t.Run("TLS options cannot be combined with a custom HTTPClient", func(t *testing.T) {
+		_, err := elasticsearch.New(&elasticsearch.Options{HTTPClient: http.DefaultClient, TLS: &elasticsearch.TLSOptions{}})
+		require.Error(t, err)
+	})
+}
+
+// a self-signed PEM encoded client certificate and its key
+func newCertificate(t *testing.T) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "client"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.Nil(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.Nil(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// a certificate for IP issued by parent, or a self-signed CA if parent is nil
+func newServerCertificate(t *testing.T, IP string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(2),
+		Subject:               pkix.Name{CommonName: "node"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IPAddresses:           []net.IP{net.ParseIP(IP)},
+	}
+
+	if parent == nil {
+		template.Subject.CommonName = "ca"
+		template.KeyUsage |= x509.KeyUsageCertSign
+		template.IsCA = true
+		parent, parentKey = template, key
+	}
+
+	raw, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.Nil(t, err)
+
+	cert, err := x509.ParseCertificate(raw)
+	require.Nil(t, err)
+
+	return cert, key
+}
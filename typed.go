@@ -0,0 +1,419 @@
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+type (
+	// TypedCollection wraps a Type to read and write Go values of type T, a
+	// struct or pointer to struct, instead of raw JSON. Values are encoded
+	// with the Codec of the client.
+	//
+	// The ID of a document is kept in the string field of T tagged es:"_id",
+	// or else the field named _id by its json tag. The ID is left out of the
+	// source of the document when it is written:
+	//
+	//	type User struct {
+	//		ID   string `json:"-" es:"_id"`
+	//		Name string `json:"name"`
+	//	}
+	//
+	// Documents without an ID field are assigned one by elasticsearch.
+	TypedCollection[T any] struct {
+		Type *Type
+		id   *idField
+		err  error
+	}
+
+	// Meta describes the document a value was read from.
+	Meta struct {
+		ID      string
+		Version DocumentVersion
+	}
+
+	// Hit is a single document matched by a search of a TypedCollection
+	Hit[T any] struct {
+		ID    string
+		Score float64
+		// values the hit was sorted on
+		Sort   []interface{}
+		Source T
+	}
+
+	// the field of a struct holding the ID of the document
+	idField struct {
+		index []int
+		// the name of the field in the encoded document, - if it is not encoded
+		name string
+	}
+)
+
+var (
+	errTypedNotStruct = errors.New("A TypedCollection requires a struct or pointer to struct type.")
+	errNoIDField      = errors.New("The documents of the collection have no ID field.")
+)
+
+// Typed wraps t to read and write values of type T.
+func Typed[T any](t *Type) *TypedCollection[T] {
+	id, err := findIDField(reflect.TypeOf((*T)(nil)).Elem())
+	return &TypedCollection[T]{Type: t, id: id, err: err}
+}
+
+// Insert indexes doc under the ID held by its ID field, replacing any
+// existing document, or under an ID assigned by elasticsearch if it has
+// none. Returns the ID of the document.
+func (c *TypedCollection[T]) Insert(doc T) (string, error) {
+	return c.InsertContext(context.Background(), doc)
+}
+
+// InsertContext is like Insert but the request is bound to ctx.
+func (c *TypedCollection[T]) InsertContext(ctx context.Context, doc T) (string, error) {
+	ID, source, err := c.encode(doc)
+
+	if err != nil {
+		return "", err
+	}
+
+	if ID == "" {
+		return c.Type.InsertContext(ctx, source)
+	}
+
+	_, err = c.Type.ReplaceContext(ctx, ID, source)
+
+	if err != nil {
+		return "", err
+	}
+
+	return ID, nil
+}
+
+// Get returns the document with the given ID, with its ID field set, along
+// with its metadata. If the document is not found it will return an error.
+func (c *TypedCollection[T]) Get(ID string) (T, *Meta, error) {
+	return c.GetContext(context.Background(), ID)
+}
+
+// GetContext is like Get but the request is bound to ctx.
+func (c *TypedCollection[T]) GetContext(ctx context.Context, ID string) (T, *Meta, error) {
+	var doc T
+
+	if c.err != nil {
+		return doc, nil, c.err
+	}
+
+	// the source is decoded as stored, the ID is only set through the ID field
+	response, err := c.Type.Index.Client.REST.getDocumentResponse(ctx, c.Type.Index.Name, c.Type.Name, ID)
+
+	if err != nil {
+		return doc, nil, err
+	}
+
+	doc, err = c.decode(response.ID, response.Source)
+
+	if err != nil {
+		return doc, nil, err
+	}
+
+	return doc, &Meta{ID: response.ID, Version: responseToVersion(response)}, nil
+}
+
+// Search returns the hits of a search of the collection decoded into T.
+func (c *TypedCollection[T]) Search(search *SearchRequest) ([]*Hit[T], error) {
+	return c.SearchContext(context.Background(), search)
+}
+
+// SearchContext is like Search but the request is bound to ctx.
+func (c *TypedCollection[T]) SearchContext(ctx context.Context, search *SearchRequest) ([]*Hit[T], error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	response, err := c.Type.ExecuteContext(ctx, search)
+
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]*Hit[T], len(response.Hits))
+
+	for i, hit := range response.Hits {
+		doc, err := c.decode(hit.ID, hit.Source)
+
+		if err != nil {
+			return nil, err
+		}
+
+		hits[i] = &Hit[T]{ID: hit.ID, Score: hit.Score, Sort: hit.Sort, Source: doc}
+	}
+
+	return hits, nil
+}
+
+// BulkInsert indexes every document as Insert does with a single call to the
+// Bulk API, not all documents may be inserted. The response lists the result
+// of every document in order, an error is only returned if the request itself
+// failed. The documents become visible to searches with the next refresh of
+// the index, see Index.Refresh.
+func (c *TypedCollection[T]) BulkInsert(docs []T) (*BulkResponse, error) {
+	return c.BulkInsertContext(context.Background(), docs)
+}
+
+// BulkInsertContext is like BulkInsert but the request is bound to ctx.
+func (c *TypedCollection[T]) BulkInsertContext(ctx context.Context, docs []T) (*BulkResponse, error) {
+	bulk := c.Type.Bulk()
+
+	for _, doc := range docs {
+		ID, source, err := c.encode(doc)
+
+		if err != nil {
+			return nil, err
+		}
+
+		request := NewBulkIndexRequest(source)
+
+		if ID != "" {
+			request.ID(ID)
+		}
+
+		bulk.Add(request)
+	}
+
+	return bulk.ExecuteContext(ctx)
+}
+
+// BulkDelete deletes every document by the ID held by its ID field, not all
+// documents may be deleted. The response lists the result of every deletion
+// in order, an error is only returned if the request itself failed.
+func (c *TypedCollection[T]) BulkDelete(docs []T) (*BulkResponse, error) {
+	return c.BulkDeleteContext(context.Background(), docs)
+}
+
+// BulkDeleteContext is like BulkDelete but the request is bound to ctx.
+func (c *TypedCollection[T]) BulkDeleteContext(ctx context.Context, docs []T) (*BulkResponse, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	if c.id == nil {
+		return nil, errNoIDField
+	}
+
+	IDs := make([]string, len(docs))
+
+	for i, doc := range docs {
+		IDs[i] = c.id.get(reflect.ValueOf(doc))
+
+		if IDs[i] == "" {
+			return nil, fmt.Errorf("Document %v of the bulk delete has no ID.", i)
+		}
+	}
+
+	return c.Type.BulkDeleteContext(ctx, IDs...)
+}
+
+func (c *TypedCollection[T]) codec() Codec {
+	return c.Type.Index.Client.REST.codec
+}
+
+// encode doc, returning its ID and its source without the ID
+func (c *TypedCollection[T]) encode(doc T) (string, []byte, error) {
+	if c.err != nil {
+		return "", nil, c.err
+	}
+
+	source, err := c.codec().Marshal(doc)
+
+	if err != nil || c.id == nil {
+		return "", source, err
+	}
+
+	ID := c.id.get(reflect.ValueOf(doc))
+
+	// elasticsearch rejects sources containing _id
+	if c.id.name == "-" {
+		return ID, source, nil
+	}
+
+	fields := map[string]json.RawMessage{}
+
+	if err := c.codec().Unmarshal(source, &fields); err != nil {
+		return "", nil, err
+	}
+
+	if _, ok := fields[c.id.name]; !ok {
+		return ID, source, nil
+	}
+
+	delete(fields, c.id.name)
+	source, err = c.codec().Marshal(fields)
+	return ID, source, err
+}
+
+// decode the source of the document with the given ID
+func (c *TypedCollection[T]) decode(ID string, source []byte) (T, error) {
+	var doc T
+
+	// the source is missing if the search did not fetch it
+	if len(source) > 0 {
+		if err := c.codec().Unmarshal(source, &doc); err != nil {
+			return doc, err
+		}
+	}
+
+	if c.id != nil {
+		c.id.set(reflect.ValueOf(&doc).Elem(), ID)
+	}
+
+	return doc, nil
+}
+
+// find the ID field of t, which is nil if t has none
+func findIDField(t reflect.Type) (*idField, error) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return nil, errTypedNotStruct
+	}
+
+	tagged, named, err := searchIDField(t, nil, map[reflect.Type]bool{})
+
+	if err != nil || tagged != nil {
+		return tagged, err
+	}
+
+	return named, nil
+}
+
+// search t for the field tagged es:"_id" and the first field named _id by its
+// json tag. The fields of embedded structs are promoted as they are by the
+// mapping package, after the fields of t itself.
+func searchIDField(t reflect.Type, index []int, seen map[reflect.Type]bool) (*idField, *idField, error) {
+	if seen[t] {
+		return nil, nil, nil
+	}
+
+	seen[t] = true
+	defer delete(seen, t)
+
+	var named *idField
+	embedded := []reflect.StructField{}
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		sf.Index = append(append([]int{}, index...), i)
+		name := strings.Split(sf.Tag.Get("json"), ",")[0]
+		tag := sf.Tag.Get("es")
+
+		if tag == "-" {
+			continue
+		}
+
+		if sf.Anonymous && name == "" && tag != "_id" && embeddedStruct(sf) != nil {
+			embedded = append(embedded, sf)
+			continue
+		}
+
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = sf.Name
+		}
+
+		if tag != "_id" && (name != "_id" || named != nil) {
+			continue
+		}
+
+		if sf.Type.Kind() != reflect.String {
+			return nil, nil, fmt.Errorf("The ID field %v must be a string.", sf.Name)
+		}
+
+		field := &idField{index: sf.Index, name: name}
+
+		if tag == "_id" {
+			return field, nil, nil
+		}
+
+		named = field
+	}
+
+	for _, sf := range embedded {
+		promotedTagged, promotedNamed, err := searchIDField(embeddedStruct(sf), sf.Index, seen)
+
+		if err != nil || promotedTagged != nil {
+			return promotedTagged, nil, err
+		}
+
+		if named == nil {
+			named = promotedNamed
+		}
+	}
+
+	return nil, named, nil
+}
+
+// the struct type embedded by sf, nil if its fields are not promoted
+func embeddedStruct(sf reflect.StructField) reflect.Type {
+	t := sf.Type
+
+	if t.Kind() == reflect.Ptr {
+		// unexported pointers cannot be allocated when decoding
+		if sf.PkgPath != "" {
+			return nil
+		}
+
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	return t
+}
+
+// the ID held by the document v, a struct or pointer to struct
+func (f *idField) get(v reflect.Value) string {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+
+		v = v.Elem()
+	}
+
+	// an embedded struct holding the field may be a nil pointer
+	field, err := v.FieldByIndexErr(f.index)
+
+	if err != nil {
+		return ""
+	}
+
+	return field.String()
+}
+
+// set the ID of the document v, which must be addressable, allocating the
+// embedded structs on the way to the field
+func (f *idField) set(v reflect.Value, ID string) {
+	for _, i := range f.index {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+
+			v = v.Elem()
+		}
+
+		v = v.Field(i)
+	}
+
+	v.SetString(ID)
+}
@@ -0,0 +1,152 @@
+package elasticsearch_test
+
+import (
+	"github.com/b3ntly/elasticsearch"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type (
+	typedUser struct {
+		ID   string `json:"-" es:"_id"`
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+
+	typedNote struct {
+		ID   string `json:"_id,omitempty"`
+		Text string `json:"text"`
+	}
+)
+
+func TestTypedCollection(t *testing.T) {
+	client, err := elasticsearch.New(&elasticsearch.Options{URI: "http://127.0.0.1:9201"})
+	require.Nil(t, err)
+
+	_ = client.I("typed").Drop()
+	defer client.I("typed").Drop()
+
+	users := elasticsearch.Typed[typedUser](client.I("typed").T("users"))
+
+	t.Run("Insert and Get round trip values through the ID field", func(t *testing.T) {
+		ID, err := users.Insert(typedUser{ID: "alice", Name: "alice", Age: 30})
+		require.Nil(t, err)
+		require.Equal(t, "alice", ID)
+
+		user, meta, err := users.Get("alice")
+		require.Nil(t, err)
+		require.Equal(t, typedUser{ID: "alice", Name: "alice", Age: 30}, user)
+		require.Equal(t, "alice", meta.ID)
+		require.Equal(t, int64(1), meta.Version.Version)
+
+		// the ID is not part of the source
+		source, err := client.I("typed").T("users").FindById("alice")
+		require.Nil(t, err)
+		require.JSONEq(t, `{"_id": "alice", "name": "alice", "age": 30}`, string(source))
+
+		// documents without an ID are assigned one
+		ID, err = users.Insert(typedUser{Name: "anonymous"})
+		require.Nil(t, err)
+		require.NotEmpty(t, ID)
+
+		_, _, err = users.Get("missing")
+		require.True(t, elasticsearch.IsNotFound(err))
+	})
+
+	t.Run("Bulk operations work on slices of values", func(t *testing.T) {
+		batch := []typedUser{{ID: "bob", Name: "bob", Age: 40}, {ID: "carol", Name: "carol", Age: 50}}
+
+		response, err := users.BulkInsert(batch)
+		require.Nil(t, err)
+		require.False(t, response.Errors)
+		require.Equal(t, []string{"bob", "carol"}, response.IDs())
+		require.Nil(t, client.I("typed").Refresh())
+
+		hits, err := users.Search(elasticsearch.NewSearchRequest().Sort(elasticsearch.NewSort("age").Desc()).Size(2))
+		require.Nil(t, err)
+		require.Len(t, hits, 2)
+		require.Equal(t, "carol", hits[0].ID)
+		require.Equal(t, []interface{}{float64(50)}, hits[0].Sort)
+		require.Equal(t, batch[1], hits[0].Source)
+		require.Equal(t, batch[0], hits[1].Source)
+
+		response, err = users.BulkDelete(batch)
+		require.Nil(t, err)
+		require.False(t, response.Errors)
+
+		_, err = users.BulkDelete([]typedUser{{Name: "no ID"}})
+		require.Error(t, err)
+	})
+
+	t.Run("Pointer types and IDs named by their json tag", func(t *testing.T) {
+		notes := elasticsearch.Typed[*typedNote](client.I("typed").T("notes"))
+
+		ID, err := notes.Insert(&typedNote{ID: "1", Text: "hello"})
+		require.Nil(t, err)
+		require.Equal(t, "1", ID)
+
+		note, _, err := notes.Get("1")
+		require.Nil(t, err)
+		require.Equal(t, &typedNote{ID: "1", Text: "hello"}, note)
+	})
+
+	t.Run("Documents with an empty source", func(t *testing.T) {
+		type empty struct {
+			ID string `json:"-" es:"_id"`
+		}
+
+		collection := elasticsearch.Typed[empty](client.I("typed").T("empty"))
+
+		ID, err := collection.Insert(empty{ID: "empty"})
+		require.Nil(t, err)
+
+		doc, _, err := collection.Get(ID)
+		require.Nil(t, err)
+		require.Equal(t, empty{ID: "empty"}, doc)
+	})
+
+	t.Run("IDs held by embedded structs", func(t *testing.T) {
+		type Base struct {
+			ID string `json:"-" es:"_id"`
+		}
+
+		type post struct {
+			Base
+			Title string `json:"title"`
+		}
+
+		type comment struct {
+			*Base
+			Text string `json:"text"`
+		}
+
+		posts := elasticsearch.Typed[post](client.I("typed").T("posts"))
+
+		ID, err := posts.Insert(post{Base: Base{ID: "p1"}, Title: "hello"})
+		require.Nil(t, err)
+		require.Equal(t, "p1", ID)
+
+		doc, _, err := posts.Get("p1")
+		require.Nil(t, err)
+		require.Equal(t, post{Base: Base{ID: "p1"}, Title: "hello"}, doc)
+
+		// the ID is left out of the source
+		source, err := client.I("typed").T("posts").FindById("p1")
+		require.Nil(t, err)
+		require.JSONEq(t, `{"_id": "p1", "title": "hello"}`, string(source))
+
+		comments := elasticsearch.Typed[comment](client.I("typed").T("comments"))
+
+		_, err = comments.Insert(comment{Base: &Base{ID: "c1"}, Text: "world"})
+		require.Nil(t, err)
+
+		reply, _, err := comments.Get("c1")
+		require.Nil(t, err)
+		require.Equal(t, comment{Base: &Base{ID: "c1"}, Text: "world"}, reply)
+	})
+
+	t.Run("Rejects types which are not structs", func(t *testing.T) {
+		_, err := elasticsearch.Typed[string](client.I("typed").T("strings")).Insert("hello")
+		require.Error(t, err)
+	})
+}
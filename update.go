@@ -0,0 +1,116 @@
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"github.com/b3ntly/elasticsearch/mock"
+	"strconv"
+)
+
+// UpdateResult describes the outcome of a write to a single document.
+type UpdateResult struct {
+	ID string
+	// "created", "updated" or "noop" if the update did not change the document
+	Result  string
+	Version DocumentVersion
+}
+
+var errNilScript = errors.New("An update by script requires a script.")
+
+// Replace indexes doc under ID, replacing the whole document if it exists
+// and creating it otherwise.
+func (t *Type) Replace(ID string, doc []byte) (*UpdateResult, error) {
+	return t.ReplaceContext(context.Background(), ID, doc)
+}
+
+// ReplaceContext is like Replace but the request is bound to ctx.
+func (t *Type) ReplaceContext(ctx context.Context, ID string, doc []byte) (*UpdateResult, error) {
+	return t.Index.Client.REST.replaceDocument(ctx, t.Index.Name, t.Name, ID, doc)
+}
+
+// Patch merges the fields of doc into the document with the given ID using
+// the update API. Fields missing from doc are left untouched. If the document
+// does not exist a not found error is returned.
+func (t *Type) Patch(ID string, doc []byte) (*UpdateResult, error) {
+	return t.PatchContext(context.Background(), ID, doc)
+}
+
+// PatchContext is like Patch but the request is bound to ctx.
+func (t *Type) PatchContext(ctx context.Context, ID string, doc []byte) (*UpdateResult, error) {
+	return t.Index.Client.REST.update(ctx, t.Index.Name, t.Name, ID, &mock.BulkUpdatePayload{Doc: doc}, 0)
+}
+
+// Upsert is like Patch but creates the document if it does not exist, from
+// upsert if given and from doc otherwise.
+func (t *Type) Upsert(ID string, doc []byte, upsert []byte) (*UpdateResult, error) {
+	return t.UpsertContext(context.Background(), ID, doc, upsert)
+}
+
+// UpsertContext is like Upsert but the request is bound to ctx.
+func (t *Type) UpsertContext(ctx context.Context, ID string, doc []byte, upsert []byte) (*UpdateResult, error) {
+	payload := &mock.BulkUpdatePayload{Doc: doc, Upsert: upsert, DocAsUpsert: upsert == nil}
+	return t.Index.Client.REST.update(ctx, t.Index.Name, t.Name, ID, payload, 0)
+}
+
+// UpdateScript updates the document with the given ID by running script
+// against it. If the document is changed concurrently the update is retried
+// up to retryOnConflict times by elasticsearch before a version conflict is
+// returned.
+func (t *Type) UpdateScript(ID string, script *Script, retryOnConflict int) (*UpdateResult, error) {
+	return t.UpdateScriptContext(context.Background(), ID, script, retryOnConflict)
+}
+
+// UpdateScriptContext is like UpdateScript but the request is bound to ctx.
+func (t *Type) UpdateScriptContext(ctx context.Context, ID string, script *Script, retryOnConflict int) (*UpdateResult, error) {
+	if script == nil {
+		return nil, errNilScript
+	}
+
+	return t.Index.Client.REST.update(ctx, t.Index.Name, t.Name, ID, &mock.BulkUpdatePayload{Script: script.body()}, retryOnConflict)
+}
+
+// Call the elasticsearch Index API to replace a document
+func (r *rest) replaceDocument(ctx context.Context, index string, _type string, ID string, doc []byte) (*UpdateResult, error) {
+	URL, err := buildURI(r.BaseURI, map[string]string{"index": index, "type": _type, "suffix": ID}, map[string]string{"refresh": "true"})
+
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := r.request(ctx, "PUT", URL, doc)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return updateResponseToResult(r.codec, body)
+}
+
+// Call the elasticsearch Update API
+func (r *rest) update(ctx context.Context, index string, _type string, ID string, payload *mock.BulkUpdatePayload, retryOnConflict int) (*UpdateResult, error) {
+	queryMap := map[string]string{"refresh": "true"}
+
+	if retryOnConflict > 0 {
+		queryMap["retry_on_conflict"] = strconv.Itoa(retryOnConflict)
+	}
+
+	URL, err := buildActionURI(r.BaseURI, map[string]string{"index": index, "type": _type, "suffix": ID}, "_update", queryMap)
+
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := r.codec.Marshal(payload)
+
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := r.request(ctx, "POST", URL, body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return updateResponseToResult(r.codec, response)
+}
@@ -0,0 +1,97 @@
+package elasticsearch_test
+
+import (
+	"github.com/b3ntly/elasticsearch"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestUpdate(t *testing.T) {
+	client, err := elasticsearch.New(&elasticsearch.Options{URI: "http://127.0.0.1:9201"})
+	require.Nil(t, err)
+
+	_ = client.I("update").Drop()
+	defer client.I("update").Drop()
+
+	collection := client.I("update").T("update")
+
+	source := func(t *testing.T, ID string) string {
+		doc, err := collection.FindById(ID)
+		require.Nil(t, err)
+		return string(doc)
+	}
+
+	t.Run("Replace overwrites the whole document", func(t *testing.T) {
+		result, err := collection.Replace("replace", []byte(`{"a": 1, "b": 1}`))
+		require.Nil(t, err)
+		require.Equal(t, "created", result.Result)
+		require.Equal(t, int64(1), result.Version.Version)
+
+		result, err = collection.Replace("replace", []byte(`{"a": 2}`))
+		require.Nil(t, err)
+		require.Equal(t, "updated", result.Result)
+		require.Equal(t, int64(2), result.Version.Version)
+		require.JSONEq(t, `{"_id": "replace", "a": 2}`, source(t, "replace"))
+	})
+
+	t.Run("Patch merges fields into an existing document", func(t *testing.T) {
+		_, err := collection.Replace("patch", []byte(`{"a": 1, "b": 1}`))
+		require.Nil(t, err)
+
+		result, err := collection.Patch("patch", []byte(`{"a": 2}`))
+		require.Nil(t, err)
+		require.Equal(t, "updated", result.Result)
+		require.JSONEq(t, `{"_id": "patch", "a": 2, "b": 1}`, source(t, "patch"))
+
+		// a patch which changes nothing does not create a new version
+		result, err = collection.Patch("patch", []byte(`{"a": 2}`))
+		require.Nil(t, err)
+		require.Equal(t, "noop", result.Result)
+		require.Equal(t, int64(2), result.Version.Version)
+
+		_, err = collection.Patch("missing", []byte(`{"a": 2}`))
+		require.True(t, elasticsearch.IsNotFound(err))
+	})
+
+	t.Run("Upsert creates missing documents", func(t *testing.T) {
+		result, err := collection.Upsert("upsert", []byte(`{"a": 1}`), nil)
+		require.Nil(t, err)
+		require.Equal(t, "created", result.Result)
+
+		result, err = collection.Upsert("upsert", []byte(`{"b": 1}`), nil)
+		require.Nil(t, err)
+		require.Equal(t, "updated", result.Result)
+		require.JSONEq(t, `{"_id": "upsert", "a": 1, "b": 1}`, source(t, "upsert"))
+
+		// the upsert body is only used if the document is missing
+		_, err = collection.Upsert("counter", []byte(`{"count": 5}`), []byte(`{"count": 0}`))
+		require.Nil(t, err)
+		require.JSONEq(t, `{"_id": "counter", "count": 0}`, source(t, "counter"))
+
+		_, err = collection.Upsert("counter", []byte(`{"count": 5}`), []byte(`{"count": 0}`))
+		require.Nil(t, err)
+		require.JSONEq(t, `{"_id": "counter", "count": 5}`, source(t, "counter"))
+	})
+
+	t.Run("UpdateScript runs a script against the document", func(t *testing.T) {
+		_, err := collection.Replace("script", []byte(`{"count": 1, "tmp": true}`))
+		require.Nil(t, err)
+
+		script := elasticsearch.NewScript("ctx._source.count += params.n; ctx._source.remove('tmp')").Param("n", 4)
+		result, err := collection.UpdateScript("script", script, 3)
+		require.Nil(t, err)
+		require.Equal(t, "updated", result.Result)
+		require.JSONEq(t, `{"_id": "script", "count": 5}`, source(t, "script"))
+
+		// a failing script leaves the document untouched
+		_, err = collection.UpdateScript("script", elasticsearch.NewScript("ctx._source.count++"), 0)
+		require.Error(t, err)
+		require.JSONEq(t, `{"_id": "script", "count": 5}`, source(t, "script"))
+
+		_, err = collection.UpdateScript("missing", script, 0)
+		require.True(t, elasticsearch.IsNotFound(err))
+
+		_, err = collection.UpdateScript("script", nil, 0)
+		require.Error(t, err)
+	})
+}
@@ -34,3 +34,21 @@ func buildURI(baseURI string, pathMap map[string]string, queryMap map[string]str
 
 	return uri, err
 }
+
+// like buildURI but appends an action such as _update to the path, after the
+// segments of pathMap
+func buildActionURI(baseURI string, pathMap map[string]string, action string, queryMap map[string]string) (string, error) {
+	uri, err := constructPath(baseURI, pathMap)
+
+	if err != nil {
+		return "", err
+	}
+
+	uri = uri + "/" + url.PathEscape(action)
+
+	if queryMap != nil {
+		return injectQuerystring(uri, queryMap), nil
+	}
+
+	return uri, nil
+}
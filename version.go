@@ -0,0 +1,129 @@
+package elasticsearch
+
+import (
+	"strconv"
+	"strings"
+)
+
+type (
+	// DocumentVersion identifies the write which last changed a document.
+	DocumentVersion struct {
+		SeqNo       int64
+		PrimaryTerm int64
+		Version     int64
+	}
+
+	// VersionedDocument is a document along with the version it was read at.
+	VersionedDocument struct {
+		ID string
+		// the source of the document with its _id, as returned by FindById
+		Source  []byte
+		Version DocumentVersion
+	}
+
+	// Precondition restricts a write to a known version of the document,
+	// failing it with a *VersionConflictError otherwise.
+	Precondition struct {
+		ifSeqNo       *int64
+		ifPrimaryTerm *int64
+		version       *int64
+		versionType   string
+	}
+
+	// VersionConflictError is returned when a write conflicts with the
+	// current version of a document, for example because a Precondition no
+	// longer holds. It unwraps to the underlying *ElasticsearchError.
+	VersionConflictError struct {
+		*ElasticsearchError
+		// the document the write conflicted on
+		ID string
+	}
+)
+
+// NewPrecondition creates a Precondition which holds for any version of the document.
+func NewPrecondition() *Precondition {
+	return &Precondition{}
+}
+
+// IfMatch creates a Precondition which holds only if the document was not
+// changed since it was read at version.
+func IfMatch(version DocumentVersion) *Precondition {
+	return NewPrecondition().IfSeqNo(version.SeqNo).IfPrimaryTerm(version.PrimaryTerm)
+}
+
+// IfSeqNo performs the write only if the document was last changed by the
+// operation with the given sequence number, see IfPrimaryTerm.
+func (p *Precondition) IfSeqNo(seqNo int64) *Precondition {
+	p.ifSeqNo = &seqNo
+	return p
+}
+
+// IfPrimaryTerm performs the write only if the document was last changed
+// under the given primary term, see IfSeqNo.
+func (p *Precondition) IfPrimaryTerm(primaryTerm int64) *Precondition {
+	p.ifPrimaryTerm = &primaryTerm
+	return p
+}
+
+// Version performs the write only if it matches the version of the document,
+// see VersionType.
+func (p *Precondition) Version(version int64) *Precondition {
+	p.version = &version
+	return p
+}
+
+// VersionType sets how Version is compared, either "internal", "external" or
+// "external_gte". External versions are maintained outside of elasticsearch and
+// must be higher than the current version, which they then replace.
+func (p *Precondition) VersionType(versionType string) *Precondition {
+	p.versionType = versionType
+	return p
+}
+
+// add the parameters of the precondition to queryMap
+func (p *Precondition) query(queryMap map[string]string) map[string]string {
+	if p == nil {
+		return queryMap
+	}
+
+	if p.ifSeqNo != nil {
+		queryMap["if_seq_no"] = strconv.FormatInt(*p.ifSeqNo, 10)
+	}
+
+	if p.ifPrimaryTerm != nil {
+		queryMap["if_primary_term"] = strconv.FormatInt(*p.ifPrimaryTerm, 10)
+	}
+
+	if p.version != nil {
+		queryMap["version"] = strconv.FormatInt(*p.version, 10)
+	}
+
+	if p.versionType != "" {
+		queryMap["version_type"] = p.versionType
+	}
+
+	return queryMap
+}
+
+// Unwrap returns the underlying *ElasticsearchError.
+func (e *VersionConflictError) Unwrap() error {
+	return e.ElasticsearchError
+}
+
+// wrap version conflicts of single document writes into a *VersionConflictError,
+// elasticsearch reports the ID as the prefix of the reason: "[ID]: version conflict, ..."
+func asVersionConflict(esErr *ElasticsearchError) error {
+	if esErr.Status != 409 || !esErr.hasType("version_conflict_engine_exception") {
+		return esErr
+	}
+
+	conflict := &VersionConflictError{ElasticsearchError: esErr}
+
+	if strings.HasPrefix(esErr.Reason, "[") {
+		if end := strings.Index(esErr.Reason, "]"); end > 0 {
+			conflict.ID = esErr.Reason[1:end]
+		}
+	}
+
+	return conflict
+}
@@ -0,0 +1,78 @@
+package elasticsearch_test
+
+import (
+	"errors"
+	"github.com/b3ntly/elasticsearch"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestOptimisticConcurrency(t *testing.T) {
+	client, err := elasticsearch.New(&elasticsearch.Options{URI: "http://127.0.0.1:9201"})
+	require.Nil(t, err)
+
+	_ = client.I("occ").Drop()
+	defer client.I("occ").Drop()
+
+	collection := client.I("occ").T("occ")
+
+	t.Run("Writes succeed as long as the document was not changed since it was read", func(t *testing.T) {
+		ID, err := collection.Insert([]byte(`{"count": 1}`))
+		require.Nil(t, err)
+
+		read, err := collection.FindByIdVersioned(ID)
+		require.Nil(t, err)
+		require.Equal(t, ID, read.ID)
+		require.Equal(t, int64(1), read.Version.Version)
+		require.Equal(t, int64(1), read.Version.PrimaryTerm)
+		require.JSONEq(t, `{"_id": "`+ID+`", "count": 1}`, string(read.Source))
+
+		written, err := collection.UpdateByIdIf(ID, []byte(`{"count": 2}`), elasticsearch.IfMatch(read.Version))
+		require.Nil(t, err)
+		require.Equal(t, int64(2), written.Version)
+		require.True(t, written.SeqNo > read.Version.SeqNo)
+
+		// a concurrent writer which read the same version loses
+		_, err = collection.UpdateByIdIf(ID, []byte(`{"count": 3}`), elasticsearch.IfMatch(read.Version))
+
+		var conflict *elasticsearch.VersionConflictError
+		require.True(t, errors.As(err, &conflict))
+		require.Equal(t, ID, conflict.ID)
+		require.True(t, elasticsearch.IsVersionConflict(err))
+
+		require.True(t, elasticsearch.IsVersionConflict(collection.DeleteByIdIf(ID, elasticsearch.IfMatch(read.Version))))
+		require.Nil(t, collection.DeleteByIdIf(ID, elasticsearch.IfMatch(written)))
+
+		_, err = collection.FindById(ID)
+		require.Error(t, err)
+	})
+
+	t.Run("External versions must increase", func(t *testing.T) {
+		ID, err := collection.Insert([]byte(`{"count": 1}`))
+		require.Nil(t, err)
+
+		external := func(version int64) *elasticsearch.Precondition {
+			return elasticsearch.NewPrecondition().Version(version).VersionType("external")
+		}
+
+		written, err := collection.UpdateByIdIf(ID, []byte(`{"count": 2}`), external(10))
+		require.Nil(t, err)
+		require.Equal(t, int64(10), written.Version)
+
+		_, err = collection.UpdateByIdIf(ID, []byte(`{"count": 3}`), external(10))
+		require.True(t, elasticsearch.IsVersionConflict(err))
+
+		require.Nil(t, collection.DeleteByIdIf(ID, external(11)))
+	})
+
+	t.Run("Internal versions must match exactly", func(t *testing.T) {
+		ID, err := collection.Insert([]byte(`{"count": 1}`))
+		require.Nil(t, err)
+
+		_, err = collection.UpdateByIdIf(ID, []byte(`{"count": 2}`), elasticsearch.NewPrecondition().Version(2))
+		require.True(t, elasticsearch.IsVersionConflict(err))
+
+		_, err = collection.UpdateByIdIf(ID, []byte(`{"count": 2}`), elasticsearch.NewPrecondition().Version(1))
+		require.Nil(t, err)
+	})
+}